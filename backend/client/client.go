@@ -0,0 +1,222 @@
+// Package client is the Go SDK for the menu REST API (/api/v1). It only
+// depends on the standard library, so services can import it without pulling
+// in the backend:
+//
+//	c, err := client.New("http://localhost:8080")
+//	tree, err := c.GetMenus(ctx)
+//	m, err := c.Create(ctx, client.CreateInput{Title: "Billing", ParentID: &settingsID})
+//
+// Failed calls return an *Error carrying the problem code, which also matches
+// the status sentinels (errors.Is(err, client.ErrNotFound)). Requests are
+// retried with exponential backoff on network errors, 429 and 502-504;
+// mutations send an Idempotency-Key that is kept across retries, so a retried
+// write is applied once.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for New; change them with the options.
+const (
+	DefaultRetries    = 3
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 2 * time.Second
+	DefaultTimeout    = 30 * time.Second
+)
+
+// idempotencyKeyHeader matches middleware.IdempotencyKeyHeader.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// Client calls the menu API. It is safe for concurrent use.
+type Client struct {
+	base       *url.URL
+	http       *http.Client
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	userAgent  string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests through hc, e.g. one with custom transport,
+// TLS or tracing. The default has a DefaultTimeout timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithRetries sets how many times a failed request is retried (0 disables
+// retries).
+func WithRetries(n int) Option {
+	return func(c *Client) { c.retries = max(n, 0) }
+}
+
+// WithBackoff sets the delay before the first retry; it doubles (with
+// jitter) per retry up to maxDelay. A Retry-After from the server takes
+// precedence.
+func WithBackoff(minDelay, maxDelay time.Duration) Option {
+	return func(c *Client) { c.minBackoff, c.maxBackoff = minDelay, max(minDelay, maxDelay) }
+}
+
+// WithUserAgent sets the User-Agent header.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a client for the API at baseURL (scheme and host, plus a path
+// prefix when the API is mounted below one).
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("client: base URL must be an absolute http(s) URL, got %q", baseURL)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	c := &Client{
+		base:       u,
+		http:       &http.Client{Timeout: DefaultTimeout},
+		retries:    DefaultRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+		userAgent:  "sotekre-go-client",
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c, nil
+}
+
+// newIdempotencyKey returns a random key for one logical write.
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// retryable reports whether a response status is worth retrying.
+func retryable(status int, code string) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		// the first attempt of this write is still running on the server
+		return code == CodeIdempotencyInFlight
+	}
+	return false
+}
+
+// backoff returns the delay before retry n (0-based): a random duration
+// between d/2 and d, where d is minBackoff * 2^n capped at maxBackoff.
+func (c *Client) backoff(n int) time.Duration {
+	d := c.minBackoff << min(n, 30)
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	return d/2 + mrand.N(d/2+1)
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(h http.Header) time.Duration {
+	secs, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// do sends a request with body marshalled as JSON (when not nil) and decodes
+// a successful response into out (when not nil). Mutating requests carry one
+// Idempotency-Key for all attempts.
+func (c *Client) do(ctx context.Context, method, path, contentType string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("client: encode request: %w", err)
+		}
+	}
+	var key string
+	if method != http.MethodGet {
+		key = newIdempotencyKey()
+	}
+	target := c.base.JoinPath(path).String()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("client: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.userAgent)
+		if body != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+
+		var wait time.Duration
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.retries {
+				return fmt.Errorf("client: %s %s: %w", method, path, err)
+			}
+		} else {
+			data, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			switch {
+			case readErr != nil:
+				if ctx.Err() != nil || attempt >= c.retries {
+					return fmt.Errorf("client: %s %s: read response: %w", method, path, readErr)
+				}
+			case resp.StatusCode < 300:
+				if out == nil {
+					return nil
+				}
+				if err := json.Unmarshal(data, out); err != nil {
+					return fmt.Errorf("client: %s %s: decode response: %w", method, path, err)
+				}
+				return nil
+			default:
+				apiErr := newError(resp, data)
+				if !retryable(resp.StatusCode, apiErr.Code) || attempt >= c.retries {
+					return apiErr
+				}
+				wait = retryAfter(resp.Header)
+			}
+		}
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return fmt.Errorf("client: %s %s: %w", method, path, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/galpt/sotekre/backend/client"
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/routes"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newServer serves routes.SetupRouter over httptest, backed by a fresh
+// sqlite database. wrap, when set, sits in front of the router.
+func newServer(t *testing.T, wrap func(next http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+	dsn := fmt.Sprintf("file:memtest_client_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	config.DB = db
+	require.NoError(t, db.AutoMigrate(&models.Menu{}, &models.MenuChange{}))
+	services.InvalidateMenuTree()
+	t.Cleanup(func() { config.CloseDB() })
+
+	var h http.Handler = routes.SetupRouter()
+	if wrap != nil {
+		h = wrap(h)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newClient(t *testing.T, srv *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+	opts = append([]client.Option{client.WithHTTPClient(srv.Client()), client.WithBackoff(time.Millisecond, 5*time.Millisecond)}, opts...)
+	c, err := client.New(srv.URL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_menus(t *testing.T) {
+	c := newClient(t, newServer(t, nil))
+	ctx := context.Background()
+
+	settings, err := c.Create(ctx, client.CreateInput{Title: "Settings", URL: client.String("/settings")})
+	require.NoError(t, err)
+	require.Equal(t, "settings", settings.Key)
+	billing, err := c.Create(ctx, client.CreateInput{Title: "Billing", ParentID: &settings.ID})
+	require.NoError(t, err)
+	help, err := c.Create(ctx, client.CreateInput{Title: "Help"})
+	require.NoError(t, err)
+
+	tree, err := c.GetMenus(ctx)
+	require.NoError(t, err)
+	require.Len(t, tree, 2)
+	require.Equal(t, billing.ID, tree[0].Children[0].ID)
+
+	m, err := c.Update(ctx, billing.ID, client.UpdateInput{Title: client.String("Invoices"), Icon: client.String("receipt")})
+	require.NoError(t, err)
+	require.Equal(t, "Invoices", m.Title)
+	require.Equal(t, "receipt", *m.Icon)
+	m, err = c.Update(ctx, billing.ID, client.UpdateInput{Icon: client.String("")})
+	require.NoError(t, err)
+	require.Nil(t, m.Icon)
+
+	m, err = c.Move(ctx, billing.ID, nil, client.Int(0))
+	require.NoError(t, err)
+	require.Nil(t, m.ParentID)
+	require.Equal(t, 0, m.Order)
+	m, err = c.Reorder(ctx, billing.ID, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, m.Order)
+	m, err = c.Move(ctx, help.ID, &settings.ID, nil)
+	require.NoError(t, err)
+	require.Equal(t, settings.ID, *m.ParentID)
+
+	require.NoError(t, c.Delete(ctx, settings.ID))
+	_, err = c.GetMenu(ctx, help.ID)
+	require.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestClient_errors(t *testing.T) {
+	c := newClient(t, newServer(t, nil))
+	ctx := context.Background()
+	root, err := c.Create(ctx, client.CreateInput{Title: "Root"})
+	require.NoError(t, err)
+	child, err := c.Create(ctx, client.CreateInput{Title: "Child", ParentID: &root.ID})
+	require.NoError(t, err)
+
+	_, err = c.Create(ctx, client.CreateInput{Title: " ", URL: client.String("javascript:alert(1)")})
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	require.ErrorIs(t, err, client.ErrBadRequest)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Equal(t, client.CodeValidationFailed, apiErr.Code)
+	require.Len(t, apiErr.Fields, 2)
+	require.NotEmpty(t, apiErr.RequestID)
+
+	_, err = c.Move(ctx, root.ID, &child.ID, nil)
+	require.ErrorIs(t, err, client.ErrConflict)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, client.CodeCycleDetected, apiErr.Code)
+
+	_, err = c.Update(ctx, child.ID, client.UpdateInput{Key: client.String("root")})
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, client.CodeKeyConflict, apiErr.Code)
+
+	_, err = c.Reorder(ctx, 999, 0)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, client.CodeMenuNotFound, apiErr.Code)
+	require.NotErrorIs(t, err, client.ErrConflict)
+}
+
+// TestClient_retries loses the response of the first attempt: the write is
+// retried with the same Idempotency-Key and the server replays its result
+// instead of creating a second item.
+func TestClient_retries(t *testing.T) {
+	var calls atomic.Int32
+	srv := newServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+			if n == 1 {
+				next.ServeHTTP(httptest.NewRecorder(), r)
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			if n == 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	c := newClient(t, srv)
+	ctx := context.Background()
+
+	m, err := c.Create(ctx, client.CreateInput{Title: "Once"})
+	require.NoError(t, err)
+	require.Equal(t, int32(3), calls.Load())
+	tree, err := c.GetMenus(ctx)
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	require.Equal(t, m.ID, tree[0].ID)
+
+	// without retries the first failure is returned
+	calls.Store(0)
+	_, err = newClient(t, srv, client.WithRetries(0)).Create(ctx, client.CreateInput{Title: "Twice"})
+	require.ErrorIs(t, err, client.ErrServer)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_context(t *testing.T) {
+	srv := newServer(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+	})
+	c := newClient(t, srv, client.WithBackoff(time.Hour, time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.GetMenus(ctx)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestNew_rejectsRelativeURL(t *testing.T) {
+	_, err := client.New("/api")
+	require.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Problem codes returned by the API (see the problem package of the backend).
+const (
+	CodeValidationFailed    = "validation_failed"
+	CodeMalformedBody       = "malformed_body"
+	CodeInvalidID           = "invalid_id"
+	CodeInvalidParameter    = "invalid_parameter"
+	CodeMenuNotFound        = "menu_not_found"
+	CodeParentNotFound      = "parent_not_found"
+	CodeCycleDetected       = "cycle_detected"
+	CodeChildSetMismatch    = "child_set_mismatch"
+	CodeKeyConflict         = "key_conflict"
+	CodeBodyTooLarge        = "body_too_large"
+	CodeRateLimited         = "rate_limited"
+	CodeIdempotencyReused   = "idempotency_key_reused"
+	CodeIdempotencyInFlight = "idempotency_key_in_flight"
+	CodeInternal            = "internal_error"
+)
+
+// Status sentinels; an *Error matches the one for its status code, e.g.
+// errors.Is(err, ErrNotFound) for a 404.
+var (
+	ErrBadRequest  = errors.New("bad request")          // 400
+	ErrNotFound    = errors.New("not found")            // 404
+	ErrConflict    = errors.New("conflict")             // 409
+	ErrTooLarge    = errors.New("request too large")    // 413
+	ErrUnprocessed = errors.New("unprocessable entity") // 422
+	ErrRateLimited = errors.New("rate limited")         // 429
+	ErrServer      = errors.New("server error")         // 5xx
+)
+
+// FieldError is one invalid input field of a validation_failed error.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error response of the API (an RFC 7807 problem).
+type Error struct {
+	StatusCode int          `json:"status"`
+	Code       string       `json:"code"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail"`
+	RequestID  string       `json:"request_id"`
+	Fields     []FieldError `json:"errors"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+	return fmt.Sprintf("client: %d %s: %s", e.StatusCode, e.Code, msg)
+}
+
+// Is matches the status sentinel for e's status code.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrTooLarge:
+		return e.StatusCode == http.StatusRequestEntityTooLarge
+	case ErrUnprocessed:
+		return e.StatusCode == http.StatusUnprocessableEntity
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// newError decodes an error response. Bodies that are not problems (e.g.
+// from a proxy) keep the status and use the status text.
+func newError(resp *http.Response, body []byte) *Error {
+	e := &Error{}
+	_ = json.Unmarshal(body, e)
+	e.StatusCode = resp.StatusCode
+	if e.Title == "" {
+		e.Title = http.StatusText(resp.StatusCode)
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	return e
+}
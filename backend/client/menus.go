@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Menu is a menu item. GetMenus fills Children (and leaves Icon and the
+// timestamps empty, like the tree endpoint); the other methods return single
+// items without children.
+type Menu struct {
+	ID        uint      `json:"id"`
+	Key       string    `json:"key"`
+	Title     string    `json:"title"`
+	URL       *string   `json:"url,omitempty"`
+	Icon      *string   `json:"icon,omitempty"`
+	ParentID  *uint     `json:"parent_id,omitempty"`
+	Order     int       `json:"order"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Children  []*Menu   `json:"children,omitempty"`
+}
+
+// CreateInput is a new menu item. Key is generated from the title when
+// empty; ParentID nil creates a root item and Order nil appends it.
+type CreateInput struct {
+	Title    string  `json:"title"`
+	Key      string  `json:"key,omitempty"`
+	URL      *string `json:"url,omitempty"`
+	Icon     *string `json:"icon,omitempty"`
+	ParentID *uint   `json:"parent_id,omitempty"`
+	Order    *int    `json:"order,omitempty"`
+}
+
+// UpdateInput changes the fields that are not nil; an empty URL or Icon
+// clears it. Use Move and Reorder to change the position.
+type UpdateInput struct {
+	Title *string `json:"title,omitempty"`
+	Key   *string `json:"key,omitempty"`
+	URL   *string `json:"url,omitempty"`
+	Icon  *string `json:"icon,omitempty"`
+}
+
+// String returns a pointer to s, for the optional fields of the inputs.
+func String(s string) *string { return &s }
+
+// Int returns a pointer to n.
+func Int(n int) *int { return &n }
+
+// Uint returns a pointer to n.
+func Uint(n uint) *uint { return &n }
+
+// menusPath is the menu collection of the API version the client speaks.
+const menusPath = "/api/v1/menus"
+
+func menuPath(id uint) string { return menusPath + "/" + strconv.FormatUint(uint64(id), 10) }
+
+// menuEnvelope is the {"data": ...} response of single-item routes.
+type menuEnvelope struct {
+	Data *Menu `json:"data"`
+}
+
+// GetMenus returns the whole tree, root items first.
+func (c *Client) GetMenus(ctx context.Context) ([]*Menu, error) {
+	var out struct {
+		Data []*Menu `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, menusPath, "", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Data, nil
+}
+
+// GetMenu returns one item.
+func (c *Client) GetMenu(ctx context.Context, id uint) (*Menu, error) {
+	var out menuEnvelope
+	if err := c.do(ctx, http.MethodGet, menuPath(id), "", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Data, nil
+}
+
+// Create adds an item and returns it.
+func (c *Client) Create(ctx context.Context, in CreateInput) (*Menu, error) {
+	var out menuEnvelope
+	if err := c.do(ctx, http.MethodPost, menusPath, "application/json", in, &out); err != nil {
+		return nil, err
+	}
+	return out.Data, nil
+}
+
+// Update changes the given fields (a JSON merge patch) and returns the item.
+func (c *Client) Update(ctx context.Context, id uint, in UpdateInput) (*Menu, error) {
+	var out menuEnvelope
+	if err := c.do(ctx, http.MethodPatch, menuPath(id), "application/merge-patch+json", in, &out); err != nil {
+		return nil, err
+	}
+	return out.Data, nil
+}
+
+// Move puts the item under parentID (nil: the root) at position order (nil:
+// last) and returns it.
+func (c *Client) Move(ctx context.Context, id uint, parentID *uint, order *int) (*Menu, error) {
+	in := struct {
+		NewParentID *uint `json:"new_parent_id"`
+		NewOrder    *int  `json:"new_order,omitempty"`
+	}{parentID, order}
+	var out menuEnvelope
+	if err := c.do(ctx, http.MethodPatch, menuPath(id)+"/move", "application/json", in, &out); err != nil {
+		return nil, err
+	}
+	return out.Data, nil
+}
+
+// Reorder moves the item to position order among its siblings and returns
+// it.
+func (c *Client) Reorder(ctx context.Context, id uint, order int) (*Menu, error) {
+	in := struct {
+		NewOrder int `json:"new_order"`
+	}{order}
+	var out menuEnvelope
+	if err := c.do(ctx, http.MethodPatch, menuPath(id)+"/reorder", "application/json", in, &out); err != nil {
+		return nil, err
+	}
+	return out.Data, nil
+}
+
+// Delete removes the item and all its descendants.
+func (c *Client) Delete(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, menuPath(id), "", nil, nil)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -94,7 +95,7 @@ func TestInitDB_pingFailure_retries_thenSucceeds(t *testing.T) {
 		return gorm.Open(sqlite.Open("file:memtest_ping?mode=memory&cache=shared"), opts...)
 	}
 	calls := 0
-	PingFn = func(db *sql.DB) error {
+	PingFn = func(_ context.Context, db *sql.DB) error {
 		calls++
 		if calls == 1 {
 			return fmt.Errorf("transient ping")
@@ -128,7 +129,7 @@ func TestInitDB_pingPermanentFailure_returnsError(t *testing.T) {
 	OpenGorm = func(dialector gorm.Dialector, opts ...gorm.Option) (*gorm.DB, error) {
 		return gorm.Open(sqlite.Open("file:memtest_ping2?mode=memory&cache=shared"), opts...)
 	}
-	PingFn = func(db *sql.DB) error { return fmt.Errorf("unreachable") }
+	PingFn = func(_ context.Context, db *sql.DB) error { return fmt.Errorf("unreachable") }
 	SleepFn = func(d time.Duration) {}
 	// set retries to 1 to fail fast
 	os.Setenv("DB_CONNECT_RETRIES", "1")
@@ -6,9 +6,10 @@ package config
 // - go-sql-driver DSN options: https://github.com/go-sql-driver/mysql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
@@ -27,7 +28,7 @@ var (
 	OpenGorm = gorm.Open
 	SleepFn  = time.Sleep
 	// test hook: allow ping behavior to be overridden in tests
-	PingFn = func(db *sql.DB) error { return db.Ping() }
+	PingFn = func(ctx context.Context, db *sql.DB) error { return db.PingContext(ctx) }
 )
 
 func envOr(key, def string) string {
@@ -84,7 +85,7 @@ func InitDB() error {
 
 	// Retry loop for transient DB startup (useful with docker-compose)
 	for i := 0; i < retries; i++ {
-		DB, err = OpenGorm(mysql.Open(dsn), &gorm.Config{})
+		DB, err = OpenGorm(mysql.Open(dsn), &gorm.Config{Logger: NewGormLogger()})
 		if err == nil {
 			// configure underlying sql.DB
 			sqlDB, derr := DB.DB()
@@ -95,19 +96,19 @@ func InitDB() error {
 			sqlDB.SetMaxIdleConns(5)
 			sqlDB.SetConnMaxLifetime(5 * time.Minute)
 			// quick ping (testable via PingFn)
-			if pingErr := PingFn(sqlDB); pingErr != nil {
+			if pingErr := PingFn(context.Background(), sqlDB); pingErr != nil {
 				err = pingErr
 			} else {
-				log.Printf("connected to database %s@%s:%s", user, host, port)
+				slog.Info("connected to database", "user", user, "host", host, "port", port)
 				return nil
 			}
 		}
 
 		if i < retries-1 {
-			log.Printf("db connect attempt %d failed: %v — retrying in %s", i+1, err, delay)
+			slog.Warn("db connect attempt failed, retrying", "attempt", i+1, "error", err, "delay", delay)
 			SleepFn(delay)
 		} else {
-			log.Printf("db connect attempt %d failed: %v — giving up", i+1, err)
+			slog.Error("db connect attempt failed, giving up", "attempt", i+1, "error", err)
 		}
 	}
 
@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+// Exported env helpers so other packages (services, routes) read their tunables
+// with the same fallback rules as InitDB: unset or invalid values use the default.
+
+// EnvOr returns the env var value or def when unset.
+func EnvOr(key, def string) string { return envOr(key, def) }
+
+// EnvIntOr parses a non-negative integer env var or returns def.
+func EnvIntOr(key string, def int) int { return envIntOr(key, def) }
+
+// EnvDurationMSOr parses an env var in milliseconds or returns def.
+func EnvDurationMSOr(key string, def time.Duration) time.Duration {
+	return envDurationMSOr(key, def)
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// NewLogger builds a slog logger. level is debug, info (default), warn or
+// error; format is json (default) or text.
+func NewLogger(w io.Writer, level, format string) *slog.Logger {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		lvl = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	if strings.EqualFold(format, "text") {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// InitLogger configures the process-wide logger from LOG_LEVEL and LOG_FORMAT
+// and installs it as slog's default (which also redirects the std log package).
+func InitLogger() *slog.Logger {
+	l := NewLogger(os.Stderr, EnvOr("LOG_LEVEL", "info"), EnvOr("LOG_FORMAT", "json"))
+	slog.SetDefault(l)
+	return l
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying l (e.g. a logger with request_id).
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// Logger returns the logger carried by ctx, or slog's default.
+func Logger(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+			return l
+		}
+	}
+	return slog.Default()
+}
+
+// slowQueryThreshold marks queries logged at warn level. Override with
+// DB_SLOW_QUERY_MS.
+const slowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger routes GORM's logging through the context logger, so SQL
+// errors carry the request_id of the request that issued them (queries must
+// run with DB.WithContext(ctx)).
+type gormLogger struct {
+	slow time.Duration
+}
+
+// NewGormLogger returns a GORM logger backed by slog. Failed queries log at
+// error, slow ones at warn and everything else at debug.
+func NewGormLogger() gormlogger.Interface {
+	return gormLogger{slow: EnvDurationMSOr("DB_SLOW_QUERY_MS", slowQueryThreshold)}
+}
+
+// LogMode is a no-op: levels are controlled by the slog handler.
+func (g gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface { return g }
+
+func (g gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	Logger(ctx).InfoContext(ctx, "gorm: "+msg, "args", args)
+}
+
+func (g gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	Logger(ctx).WarnContext(ctx, "gorm: "+msg, "args", args)
+}
+
+func (g gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	Logger(ctx).ErrorContext(ctx, "gorm: "+msg, "args", args)
+}
+
+func (g gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l := Logger(ctx)
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.ErrorContext(ctx, "query failed", "sql", sql, "rows", rows, "duration", elapsed, "error", err)
+	case g.slow > 0 && elapsed > g.slow:
+		sql, rows := fc()
+		l.WarnContext(ctx, "slow query", "sql", sql, "rows", rows, "duration", elapsed)
+	case l.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		l.DebugContext(ctx, "query", "sql", sql, "rows", rows, "duration", elapsed)
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestNewLogger_levelAndFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "warn", "json")
+	l.Info("dropped")
+	l.Warn("kept", "k", "v")
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "kept", line["msg"])
+	require.Equal(t, "v", line["k"])
+
+	buf.Reset()
+	NewLogger(&buf, "bogus", "text").Info("hello")
+	require.Contains(t, buf.String(), "msg=hello", "unknown level falls back to info")
+}
+
+func TestLogger_fromContext(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "info", "json").With("request_id", "abc")
+	ctx := WithLogger(context.Background(), l)
+	require.Same(t, l, Logger(ctx))
+	require.NotNil(t, Logger(context.Background()))
+
+	// GORM errors are logged through the request's logger
+	NewGormLogger().Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 0 }, errors.New("boom"))
+	require.Contains(t, buf.String(), `"request_id":"abc"`)
+	require.Contains(t, buf.String(), `"sql":"SELECT 1"`)
+
+	buf.Reset()
+	NewGormLogger().Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 0 }, gorm.ErrRecordNotFound)
+	require.Empty(t, buf.String(), "not-found is not an error worth logging")
+}
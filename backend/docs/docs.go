@@ -19,7 +19,7 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/menus": {
+        "/api/v1/menus": {
             "get": {
                 "produces": [
                     "application/json"
@@ -38,7 +38,7 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
@@ -63,95 +63,331 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handlers.createMenuInput"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "retry-safe key; a retry with the same key and body replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Menu"
+                            "$ref": "#/definitions/handlers.menuResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "parent_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "key_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/menus/{id}": {
-            "put": {
-                "consumes": [
+        "/api/v1/menus/changes": {
+            "get": {
+                "description": "Returns the current state of every item created/updated/moved after ` + "`" + `since` + "`" + `, the ids deleted\nsince then, and the ` + "`" + `cursor` + "`" + ` to pass next time. Start with ` + "`" + `since=0` + "`" + `. A 410 means the cursor\nis older than the retention window: refetch ` + "`" + `/api/v1/menus` + "`" + ` and continue from the returned ` + "`" + `cursor` + "`" + `.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Incremental sync: menu changes since a sequence",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "last cursor seen (default 0)",
+                        "name": "since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.changeSetResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "changes_expired; carries the ` + "`" + `cursor` + "`" + ` to resume from",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/menus/events": {
+            "get": {
+                "description": "Emits ` + "`" + `created` + "`" + `, ` + "`" + `updated` + "`" + `, ` + "`" + `moved` + "`" + `, ` + "`" + `reordered` + "`" + ` and ` + "`" + `deleted` + "`" + ` events whose ` + "`" + `id` + "`" + ` is the revision.\nReconnect with ` + "`" + `Last-Event-ID` + "`" + ` (or ` + "`" + `?last_event_id=` + "`" + `) to resume; a ` + "`" + `reset` + "`" + ` event means the\nrevision is no longer in history and the client should refetch ` + "`" + `/api/v1/menus` + "`" + `.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Stream menu changes (Server-Sent Events)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "last revision seen",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.ChangeEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/menus/flat": {
+            "get": {
+                "description": "Pass ` + "`" + `next_cursor` + "`" + ` from the previous page as ` + "`" + `cursor` + "`" + ` (with the same sort) to continue.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "menus"
                 ],
-                "summary": "Update menu (partial)",
+                "summary": "List menu rows (flat) with filters and cursor pagination",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "parent id, or ` + "`" + `null` + "`" + ` for root items",
+                        "name": "parent_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "title starts with",
+                        "name": "title_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 timestamp; updated_at \u003e= value",
+                        "name": "updated_since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only items with (true) or without (false) a url",
+                        "name": "has_url",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "order (default), title or updated_at; prefix with - for descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "menu id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
                     },
                     {
-                        "description": "fields to update",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "include the total number of matches",
+                        "name": "include_total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.updateMenuInput"
+                            "$ref": "#/definitions/handlers.flatMenusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
+                }
+            }
+        },
+        "/api/v1/menus/render": {
+            "get": {
+                "description": "` + "`" + `html` + "`" + ` is a fragment of nested ` + "`" + `\u003cul\u003e/\u003cli\u003e/\u003ca\u003e` + "`" + ` (items without a URL are ` + "`" + `\u003cspan\u003e` + "`" + `); ` + "`" + `md` + "`" + ` is a nested\nbullet list of links. The current item — ` + "`" + `current` + "`" + ` (id or ` + "`" + `key:\u003ckey\u003e` + "`" + `), or the item ` + "`" + `path` + "`" + ` resolves\nto (see /api/v1/menus/resolve) — gets ` + "`" + `aria-current` + "`" + ` and ` + "`" + `active_class` + "`" + `, its ancestors ` + "`" + `ancestor_class` + "`" + `\n(in Markdown it is bold). The same renderers are available to Go code as package ` + "`" + `render` + "`" + `.",
+                "produces": [
+                    "text/html",
+                    "text/markdown"
+                ],
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Render the menu tree as HTML or Markdown",
+                "parameters": [
+                    {
+                        "enum": [
+                            "html",
+                            "md"
+                        ],
+                        "type": "string",
+                        "description": "html (default) or md",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "current item: menu id or key:\u003cmenu key\u003e",
+                        "name": "current",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "page path whose item is current",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "class of every \u003cul\u003e",
+                        "name": "list_class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "class of nested \u003cul\u003e (list_class when omitted)",
+                        "name": "sublist_class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "class of every \u003cli\u003e",
+                        "name": "item_class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "class of every \u003ca\u003e",
+                        "name": "link_class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "extra \u003cli\u003e class of the current item",
+                        "name": "active_class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "extra \u003cli\u003e class of the current item's ancestors",
+                        "name": "ancestor_class",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "aria-current value (default page)",
+                        "name": "aria_current",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found: unknown current key",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/menus/resolve": {
+            "get": {
+                "description": "Returns the item whose ` + "`" + `url` + "`" + ` best matches ` + "`" + `path` + "`" + ` — an exact match, otherwise the longest\nsegment-wise prefix (` + "`" + `/settings` + "`" + ` matches ` + "`" + `/settings/billing/cards` + "`" + `) — with its ancestors from\nthe root down, so clients can highlight it and expand the tree. Item URLs may contain\n` + "`" + `:name` + "`" + ` segments (` + "`" + `/orders/:id` + "`" + `); their values are returned in ` + "`" + `params` + "`" + `.",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
                     "menus"
                 ],
-                "summary": "Delete menu item (recursive)",
+                "summary": "Find the menu item for a page path",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "menu id",
-                        "name": "id",
-                        "in": "path",
+                        "type": "string",
+                        "description": "page path, e.g. /settings/billing",
+                        "name": "path",
+                        "in": "query",
                         "required": true
                     }
                 ],
@@ -159,29 +395,33 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.resolveMenuResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "invalid_parameter",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found: no item matches",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/menus/{id}/move": {
-            "patch": {
+        "/api/v1/menus/root/children/order": {
+            "put": {
+                "description": "` + "`" + `ids` + "`" + ` must be exactly the current root items, in the desired order; otherwise 409.",
                 "consumes": [
                     "application/json"
                 ],
@@ -191,52 +431,93 @@ const docTemplate = `{
                 "tags": [
                     "menus"
                 ],
-                "summary": "Move menu item to different parent and position",
+                "summary": "Set the complete order of the root items",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "menu id",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "new parent and/or order",
+                        "description": "ordered root ids",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.moveInput"
+                            "$ref": "#/definitions/handlers.childOrderInput"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "the root items in their new order",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.menuNodesResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/api/menus/{id}/reorder": {
-            "patch": {
+        "/api/v1/menus/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Get one menu item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "menu id, or key:\u003cmenu key\u003e",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.menuResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Full replacement: ` + "`" + `title` + "`" + ` and ` + "`" + `parent_id` + "`" + ` (null for the root) are required, and ` + "`" + `url` + "`" + ` and\n` + "`" + `icon` + "`" + ` are reset to null when omitted. A new ` + "`" + `parent_id` + "`" + ` or an ` + "`" + `order` + "`" + ` moves the item like\nthe move endpoint, renumbering its old and new siblings; ` + "`" + `order` + "`" + ` keeps the current position\nwhen omitted (a new parent appends). Use PATCH for partial updates.",
                 "consumes": [
                     "application/json"
                 ],
@@ -246,91 +527,1041 @@ const docTemplate = `{
                 "tags": [
                     "menus"
                 ],
-                "summary": "Reorder menu item within same parent",
+                "summary": "Replace a menu item",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "menu id",
+                        "type": "string",
+                        "description": "menu id, or key:\u003cmenu key\u003e",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "new order",
+                        "description": "the whole menu item",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.reorderInput"
+                            "$ref": "#/definitions/handlers.replaceMenuInput"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "the updated item and the siblings a parent or order change renumbered",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.menuMutationResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found or parent_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "cycle_detected or key_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/handlers.errorResponse"
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "handlers.createMenuInput": {
-            "type": "object",
-            "required": [
-                "title"
-            ],
-            "properties": {
-                "order": {
-                    "type": "integer"
-                },
-                "parent_id": {
-                    "type": "integer"
-                },
-                "title": {
-                    "type": "string"
-                },
-                "url": {
-                    "type": "string"
-                }
-            }
-        },
-        "handlers.errorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                }
-            }
-        },
-        "handlers.getMenusResponse": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.MenuNode"
-                    }
-                }
-            }
-        },
+            },
+            "delete": {
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Delete menu item (recursive)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "menu id, or key:\u003cmenu key\u003e",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Send ` + "`" + `application/merge-patch+json` + "`" + ` (RFC 7396; plain ` + "`" + `application/json` + "`" + ` is treated the same) or\n` + "`" + `application/json-patch+json` + "`" + ` (RFC 6902). The patch applies to\n` + "`" + `{\"title\",\"key\",\"url\",\"icon\",\"parent_id\",\"order\"}` + "`" + `: ` + "`" + `null` + "`" + ` (merge patch) or ` + "`" + `remove` + "`" + ` (JSON Patch)\nclears ` + "`" + `url` + "`" + ` and ` + "`" + `icon` + "`" + ` and moves the item to the root for ` + "`" + `parent_id` + "`" + `; ` + "`" + `title` + "`" + `, ` + "`" + `key` + "`" + ` and\n` + "`" + `order` + "`" + ` cannot be cleared. A failed JSON Patch ` + "`" + `test` + "`" + ` answers 409.",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Patch a menu item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "menu id, or key:\u003cmenu key\u003e",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "merge patch (or an array of jsonPatchOp)",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.updateMenuInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "the updated item and the siblings a parent or order change renumbered",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.menuMutationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found or parent_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "cycle_detected, key_conflict or patch_test_failed",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "patch_not_applicable",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/menus/{id}/breadcrumbs.jsonld": {
+            "get": {
+                "description": "The item's ancestor chain, root first, ending with the item. Entries are resolved against\n` + "`" + `SITE_BASE_URL` + "`" + ` (503 ` + "`" + `site_url_not_configured` + "`" + ` while it is unset); items without a URL, external\nlinks and pattern URLs are left out.",
+                "produces": [
+                    "application/ld+json"
+                ],
+                "tags": [
+                    "seo"
+                ],
+                "summary": "schema.org BreadcrumbList (JSON-LD) for a menu item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "menu id, or key:\u003cmenu key\u003e",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/render.BreadcrumbList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "site_url_not_configured",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/menus/{id}/children/order": {
+            "put": {
+                "description": "` + "`" + `ids` + "`" + ` must be exactly the parent's current children, in the desired order; otherwise 409.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Set the complete child order of a parent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "parent menu id, or key:\u003cmenu key\u003e",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "ordered child ids",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.childOrderInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "the children in their new order",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.menuNodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "child_set_mismatch",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/menus/{id}/duplicate": {
+            "post": {
+                "description": "Copies get new ids and keep their relative order. Omit ` + "`" + `new_parent_id` + "`" + ` to place the copy\nright after the original; ` + "`" + `null` + "`" + ` places it at the root. ` + "`" + `new_order` + "`" + ` is the index among\nthe destination's children (appends when omitted).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Deep-copy a menu item and its descendants",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "menu id, or key:\u003cmenu key\u003e",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "destination and title suffix",
+                        "name": "input",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.duplicateInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "retry-safe key; a retry with the same key and body replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.menuResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found or parent_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/menus/{id}/move": {
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Move menu item to different parent and position",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "menu id, or key:\u003cmenu key\u003e",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new parent and/or order",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.moveInput"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "retry-safe key; a retry with the same key and body replays the first response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "the moved item and the renumbered siblings (source and destination parent)",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.menuMutationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "cycle_detected",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/menus/{id}/reorder": {
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "menus"
+                ],
+                "summary": "Reorder menu item within same parent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "menu id, or key:\u003cmenu key\u003e",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "new order",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.reorderInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "the reordered item and the renumbered siblings",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.menuMutationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "menu_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.webhookListResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Deliveries are POSTed with ` + "`" + `X-Sotekre-Signature: sha256=\u003chex HMAC-SHA256 of body\u003e` + "`" + `.\n` + "`" + `events` + "`" + ` filters by type (created, updated, moved, reordered, deleted); empty means all.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Subscribe a URL to menu change events",
+                "parameters": [
+                    {
+                        "description": "subscription",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.createWebhookInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.webhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/deliveries/{id}/redeliver": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Queue a past delivery again",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "delivery id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.webhookDeliveryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}": {
+            "delete": {
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook subscription and its delivery log",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "subscription id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}/deliveries": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delivery log of a subscription (newest first)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "subscription id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "max rows (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.webhookDeliveryListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/graphql": {
+            "post": {
+                "description": "Queries ` + "`" + `menu(id)` + "`" + `, ` + "`" + `menus(root, depth)` + "`" + ` and ` + "`" + `search(q, limit)` + "`" + `, with ` + "`" + `children` + "`" + ` and ` + "`" + `parent` + "`" + ` on every\n` + "`" + `Menu` + "`" + `; mutations ` + "`" + `createMenu` + "`" + `, ` + "`" + `updateMenu` + "`" + `, ` + "`" + `moveMenu` + "`" + `, ` + "`" + `reorderMenu` + "`" + ` and ` + "`" + `deleteMenu` + "`" + ` run through\nthe same services as REST. Errors carry the REST problem ` + "`" + `code` + "`" + ` and ` + "`" + `status` + "`" + ` in ` + "`" + `extensions` + "`" + `.\nDocuments deeper than ` + "`" + `GRAPHQL_MAX_DEPTH` + "`" + ` (10) or more complex than ` + "`" + `GRAPHQL_MAX_COMPLEXITY` + "`" + ` (5000;\none per field, list selections count 5 times) are rejected with ` + "`" + `query_too_deep` + "`" + ` /\n` + "`" + `query_too_complex` + "`" + `. GET runs queries only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "GraphQL endpoint for menu queries and mutations",
+                "parameters": [
+                    {
+                        "description": "GraphQL request",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.graphQLRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.graphQLResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Always 200 while the process is serving HTTP; it does not touch the database.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.healthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "200 when the database answers a ping and every table and column is migrated; 503 otherwise and while\nthe server drains during shutdown. The ping is bounded by ` + "`" + `READYZ_TIMEOUT_MS` + "`" + `. ` + "`" + `checks` + "`" + ` holds\n` + "`" + `ok` + "`" + ` or a generic failure (` + "`" + `unavailable` + "`" + `, ` + "`" + `pending` + "`" + `); details are logged, not returned.\n` + "`" + `pool` + "`" + ` reports connection pool usage and saturation.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/services.Readiness"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/services.Readiness"
+                        }
+                    }
+                }
+            }
+        },
+        "/sitemap.xml": {
+            "get": {
+                "description": "One ` + "`" + `\u003curl\u003e` + "`" + ` per site page a menu item links to, resolved against ` + "`" + `SITE_BASE_URL` + "`" + `, with ` + "`" + `lastmod` + "`" + `\nfrom the item's ` + "`" + `updated_at` + "`" + `. Items without a URL, external links (other hosts, ` + "`" + `mailto:` + "`" + `, ...)\nand pattern URLs such as ` + "`" + `/orders/:id` + "`" + ` are left out. Answers 503 ` + "`" + `site_url_not_configured` + "`" + ` until\n` + "`" + `SITE_BASE_URL` + "`" + ` is set; the request's Host header is never used.",
+                "produces": [
+                    "text/xml"
+                ],
+                "tags": [
+                    "seo"
+                ],
+                "summary": "sitemap.xml generated from the menu",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "site_url_not_configured",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.changeSetResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/services.ChangeSet"
+                }
+            }
+        },
+        "handlers.childOrderInput": {
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        3,
+                        1,
+                        2
+                    ]
+                }
+            }
+        },
+        "handlers.createMenuInput": {
+            "type": "object",
+            "properties": {
+                "icon": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "orders"
+                },
+                "order": {
+                    "type": "integer"
+                },
+                "parent_id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Orders"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "/orders"
+                }
+            }
+        },
+        "handlers.createWebhookInput": {
+            "type": "object",
+            "required": [
+                "secret",
+                "url"
+            ],
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "moved",
+                        "deleted"
+                    ]
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://builder.example.com/hooks/menus"
+                }
+            }
+        },
+        "handlers.duplicateInput": {
+            "type": "object",
+            "properties": {
+                "new_order": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "new_parent_id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "title_suffix": {
+                    "type": "string",
+                    "example": " (copy)"
+                }
+            }
+        },
+        "handlers.flatMenusMeta": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.flatMenusResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Menu"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/handlers.flatMenusMeta"
+                }
+            }
+        },
+        "handlers.getMenusResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MenuNode"
+                    }
+                }
+            }
+        },
+        "handlers.graphQLError": {
+            "type": "object",
+            "properties": {
+                "extensions": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "message": {
+                    "type": "string",
+                    "example": "menu item does not exist"
+                },
+                "path": {
+                    "type": "array",
+                    "items": {}
+                }
+            }
+        },
+        "handlers.graphQLRequest": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string",
+                    "example": "{ menus(depth: 2) { id title children { id title } } }"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "handlers.graphQLResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.graphQLError"
+                    }
+                }
+            }
+        },
+        "handlers.healthResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "handlers.menuMutationMeta": {
+            "type": "object",
+            "properties": {
+                "siblings": {
+                    "description": "Siblings are the items in the source and destination parents that were\nrenumbered, with their new order (empty when none moved).",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SiblingOrder"
+                    }
+                }
+            }
+        },
+        "handlers.menuMutationResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Menu"
+                },
+                "meta": {
+                    "$ref": "#/definitions/handlers.menuMutationMeta"
+                }
+            }
+        },
+        "handlers.menuNodesResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MenuNode"
+                    }
+                }
+            }
+        },
+        "handlers.menuResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.Menu"
+                }
+            }
+        },
         "handlers.moveInput": {
             "type": "object",
             "properties": {
@@ -351,9 +1582,52 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.replaceMenuInput": {
+            "type": "object",
+            "required": [
+                "parent_id",
+                "title"
+            ],
+            "properties": {
+                "icon": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "order": {
+                    "type": "integer"
+                },
+                "parent_id": {
+                    "type": "integer",
+                    "x-nullable": true
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Orders"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.resolveMenuResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/services.MenuMatch"
+                }
+            }
+        },
         "handlers.updateMenuInput": {
             "type": "object",
             "properties": {
+                "icon": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
                 "order": {
                     "type": "integer"
                 },
@@ -368,6 +1642,44 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.webhookDeliveryListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookDelivery"
+                    }
+                }
+            }
+        },
+        "handlers.webhookDeliveryResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.WebhookDelivery"
+                }
+            }
+        },
+        "handlers.webhookListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookSubscription"
+                    }
+                }
+            }
+        },
+        "handlers.webhookResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/models.WebhookSubscription"
+                }
+            }
+        },
         "models.Menu": {
             "type": "object",
             "properties": {
@@ -380,6 +1692,9 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "key": {
+                    "type": "string"
+                },
                 "order": {
                     "type": "integer"
                 },
@@ -409,6 +1724,9 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "key": {
+                    "type": "string"
+                },
                 "order": {
                     "type": "integer"
                 },
@@ -422,6 +1740,301 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "redelivery_of": {
+                    "type": "integer"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookSubscription": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "problem.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "url"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "scheme \"javascript\" is not allowed"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "validation_failed"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "request failed validation"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/problem.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/menus"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "4bf92f3577b34da6a3ce929d0e0e4736"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Bad Request"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "render.BreadcrumbItem": {
+            "type": "object",
+            "properties": {
+                "@type": {
+                    "type": "string"
+                },
+                "item": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer"
+                }
+            }
+        },
+        "render.BreadcrumbList": {
+            "type": "object",
+            "properties": {
+                "@context": {
+                    "type": "string"
+                },
+                "@type": {
+                    "type": "string"
+                },
+                "itemListElement": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/render.BreadcrumbItem"
+                    }
+                }
+            }
+        },
+        "services.ChangeEvent": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "type": {
+                    "$ref": "#/definitions/services.EventType"
+                }
+            }
+        },
+        "services.ChangeSet": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "integer"
+                },
+                "deleted": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "upserted": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Menu"
+                    }
+                }
+            }
+        },
+        "services.EventType": {
+            "type": "string",
+            "enum": [
+                "created",
+                "updated",
+                "moved",
+                "reordered",
+                "deleted"
+            ],
+            "x-enum-varnames": [
+                "EventCreated",
+                "EventUpdated",
+                "EventMoved",
+                "EventReordered",
+                "EventDeleted"
+            ]
+        },
+        "services.MenuMatch": {
+            "type": "object",
+            "properties": {
+                "ancestors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MenuNode"
+                    }
+                },
+                "exact": {
+                    "description": "Exact is false when the item URL is only a prefix of the path.",
+                    "type": "boolean"
+                },
+                "item": {
+                    "$ref": "#/definitions/models.MenuNode"
+                },
+                "params": {
+                    "description": "Params holds the values of \":name\" segments of a pattern URL.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "services.PoolStats": {
+            "type": "object",
+            "properties": {
+                "idle": {
+                    "type": "integer"
+                },
+                "in_use": {
+                    "type": "integer"
+                },
+                "max_open": {
+                    "type": "integer"
+                },
+                "open": {
+                    "type": "integer"
+                },
+                "saturation": {
+                    "type": "number"
+                },
+                "wait_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "services.Readiness": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "pool": {
+                    "$ref": "#/definitions/services.PoolStats"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.SiblingOrder": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "order": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "parent_id": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
         }
     }
 }`
@@ -433,7 +2046,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/api",
 	Schemes:          []string{},
 	Title:            "Sotekre — Menu Tree API",
-	Description:      "Minimal OpenAPI for the Menu Tree MVP",
+	Description:      "Minimal OpenAPI for the Menu Tree MVP. Routes are documented under /api/v1, where every\nJSON response is `{\"data\": ...}` and mutations return the changed resource. The unversioned\n/api alias is deprecated (`Deprecation`/`Sunset` headers) and keeps the old shapes:\n`{\"message\": ...}` for mutations and deletes, and bare objects for /menus/flat and /menus/changes.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 }
@@ -0,0 +1,129 @@
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/problem"
+	menuv1 "github.com/galpt/sotekre/backend/proto/menu/v1"
+	"github.com/galpt/sotekre/backend/services"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrorDomain is the domain of the google.rpc.ErrorInfo detail on every
+// error; its reason is the problem code.
+const ErrorDomain = "sotekre"
+
+// grpcCode maps a problem to a status code.
+func grpcCode(p problem.Problem) codes.Code {
+	switch {
+	case p.Code == problem.CodeKeyConflict:
+		return codes.AlreadyExists
+	case p.Status == http.StatusBadRequest:
+		return codes.InvalidArgument
+	case p.Status == http.StatusNotFound:
+		return codes.NotFound
+	case p.Status == http.StatusConflict:
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
+
+// toStatus maps a service error with services.ErrorProblem, like the REST and
+// GraphQL APIs; unexpected errors are logged and reported as internal_error.
+func toStatus(ctx context.Context, err error) error {
+	p, ok := services.ErrorProblem(err)
+	if !ok {
+		config.Logger(ctx).ErrorContext(ctx, "grpc call failed", "error", err)
+		p = problem.New(http.StatusInternalServerError, problem.CodeInternal, "internal server error")
+	}
+	st := status.New(grpcCode(p), p.Detail)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: p.Code, Domain: ErrorDomain}}
+	if len(p.Errors) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, f := range p.Errors {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+		}
+		details = append(details, br)
+	}
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+func optionalUint64(p *uint) *uint64 {
+	if p == nil {
+		return nil
+	}
+	v := uint64(*p)
+	return &v
+}
+
+// toProto converts a stored item.
+func toProto(m *models.Menu) *menuv1.Menu {
+	return &menuv1.Menu{
+		Id:        uint64(m.ID),
+		Key:       m.Key,
+		Title:     m.Title,
+		Url:       m.URL,
+		Icon:      m.Icon,
+		ParentId:  optionalUint64(m.ParentID),
+		Order:     int32(m.Order),
+		CreatedAt: timestamppb.New(m.CreatedAt),
+		UpdatedAt: timestamppb.New(m.UpdatedAt),
+	}
+}
+
+// nodesToProto converts tree nodes with up to depth levels (0: all).
+func nodesToProto(nodes []*models.MenuNode, depth int) []*menuv1.Menu {
+	out := make([]*menuv1.Menu, len(nodes))
+	for i, n := range nodes {
+		out[i] = &menuv1.Menu{
+			Id:       uint64(n.ID),
+			Key:      n.Key,
+			Title:    n.Title,
+			Url:      n.URL,
+			ParentId: optionalUint64(n.ParentID),
+			Order:    int32(n.Order),
+		}
+		if depth != 1 {
+			out[i].Children = nodesToProto(n.Children, max(depth-1, 0))
+		}
+	}
+	return out
+}
+
+func findNode(nodes []*models.MenuNode, id uint) *models.MenuNode {
+	for _, n := range nodes {
+		if n.ID == id {
+			return n
+		}
+		if found := findNode(n.Children, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+var changeTypes = map[services.EventType]menuv1.ChangeType{
+	services.EventCreated:   menuv1.ChangeType_CHANGE_TYPE_CREATED,
+	services.EventUpdated:   menuv1.ChangeType_CHANGE_TYPE_UPDATED,
+	services.EventMoved:     menuv1.ChangeType_CHANGE_TYPE_MOVED,
+	services.EventReordered: menuv1.ChangeType_CHANGE_TYPE_REORDERED,
+	services.EventDeleted:   menuv1.ChangeType_CHANGE_TYPE_DELETED,
+}
+
+func eventToProto(ev services.ChangeEvent) *menuv1.ChangeEvent {
+	ids := make([]uint64, len(ev.IDs))
+	for i, id := range ev.IDs {
+		ids[i] = uint64(id)
+	}
+	return &menuv1.ChangeEvent{Revision: ev.Revision, Type: changeTypes[ev.Type], Ids: ids, At: timestamppb.New(ev.At)}
+}
@@ -0,0 +1,254 @@
+// Package grpcapi serves the menu API over gRPC (proto/menu/v1) for internal
+// services. It calls the same services and reports the same problem codes as
+// the REST handlers; main runs it beside the Gin router on GRPC_ADDR, which
+// defaults to loopback: the API has no authentication or TLS of its own.
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/middleware"
+	"github.com/galpt/sotekre/backend/models"
+	menuv1 "github.com/galpt/sotekre/backend/proto/menu/v1"
+	"github.com/galpt/sotekre/backend/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// Options configures NewServer.
+type Options struct {
+	// Reflection registers server reflection (for grpcurl and similar tools).
+	Reflection bool
+	// MaxRecvMsgSize caps request messages in bytes; 0 keeps gRPC's default.
+	MaxRecvMsgSize int
+}
+
+// OptionsFromEnv enables reflection with GRPC_REFLECTION=true and caps
+// requests at MAX_BODY_BYTES, like the REST API.
+func OptionsFromEnv() Options {
+	return Options{
+		Reflection:     config.EnvOr("GRPC_REFLECTION", "") == "true",
+		MaxRecvMsgSize: int(middleware.MaxBodyBytesFromEnv()),
+	}
+}
+
+// NewServer returns a gRPC server with MenuService registered. Every call
+// gets a request id (x-request-id metadata, echoed back) and an access log
+// line; reflection is only registered when o.Reflection is set.
+func NewServer(o Options, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptor),
+		grpc.ChainStreamInterceptor(streamInterceptor),
+	}, opts...)
+	if o.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(o.MaxRecvMsgSize))
+	}
+	s := grpc.NewServer(opts...)
+	menuv1.RegisterMenuServiceServer(s, &menuServer{})
+	if o.Reflection {
+		reflection.Register(s)
+	}
+	return s
+}
+
+type menuServer struct {
+	menuv1.UnimplementedMenuServiceServer
+}
+
+// menuID resolves a numeric id or "key:<menu key>".
+func menuID(ctx context.Context, raw string) (uint, error) {
+	id, err := services.LookupMenuID(ctx, raw)
+	if err != nil {
+		return 0, toStatus(ctx, err)
+	}
+	return id, nil
+}
+
+// optionalMenuID resolves raw, or returns nil when it is empty.
+func optionalMenuID(ctx context.Context, raw string) (*uint, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := menuID(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// checkOrder rejects negative positions like the REST handlers do.
+func checkOrder(ctx context.Context, order *int32) error {
+	if order != nil && *order < 0 {
+		return toStatus(ctx, &services.ValidationError{Fields: []services.FieldError{{Field: "order", Message: "must be >= 0"}}})
+	}
+	return nil
+}
+
+// current returns the item as it is after a write.
+func current(ctx context.Context, id uint) (*menuv1.Menu, error) {
+	m, err := services.GetMenuFn(ctx, id)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return toProto(m), nil
+}
+
+func (menuServer) GetTree(ctx context.Context, req *menuv1.GetTreeRequest) (*menuv1.GetTreeResponse, error) {
+	if req.Depth < 0 {
+		return nil, toStatus(ctx, &services.ValidationError{Fields: []services.FieldError{{Field: "depth", Message: "must be >= 0"}}})
+	}
+	tree, err := services.GetMenuTreeFn(ctx)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	if req.Root != "" {
+		id, err := menuID(ctx, req.Root)
+		if err != nil {
+			return nil, err
+		}
+		root := findNode(tree, id)
+		if root == nil {
+			return nil, toStatus(ctx, gorm.ErrRecordNotFound)
+		}
+		tree = root.Children
+	}
+	return &menuv1.GetTreeResponse{Items: nodesToProto(tree, int(req.Depth))}, nil
+}
+
+func (menuServer) GetMenu(ctx context.Context, req *menuv1.GetMenuRequest) (*menuv1.Menu, error) {
+	id, err := menuID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return current(ctx, id)
+}
+
+func (menuServer) Create(ctx context.Context, req *menuv1.CreateRequest) (*menuv1.Menu, error) {
+	if err := checkOrder(ctx, req.Order); err != nil {
+		return nil, err
+	}
+	parentID, err := optionalMenuID(ctx, req.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	m := &models.Menu{Title: req.Title, Key: req.Key, URL: req.Url, Icon: req.Icon, ParentID: parentID}
+	if req.Order != nil {
+		m.Order = int(*req.Order)
+	}
+	if err := services.CreateMenuFn(ctx, m); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return toProto(m), nil
+}
+
+func (menuServer) Update(ctx context.Context, req *menuv1.UpdateRequest) (*menuv1.Menu, error) {
+	id, err := menuID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	upd := map[string]interface{}{}
+	if req.Title != nil {
+		upd["title"] = *req.Title
+	}
+	if req.Key != nil {
+		upd["key"] = *req.Key
+	}
+	if req.Url != nil {
+		upd["url"] = req.Url
+	}
+	if req.Icon != nil {
+		upd["icon"] = req.Icon
+	}
+	if len(upd) > 0 {
+		if _, err := services.UpdateMenuFn(ctx, id, upd); err != nil {
+			return nil, toStatus(ctx, err)
+		}
+	}
+	return current(ctx, id)
+}
+
+func (menuServer) Move(ctx context.Context, req *menuv1.MoveRequest) (*menuv1.Menu, error) {
+	if err := checkOrder(ctx, req.Order); err != nil {
+		return nil, err
+	}
+	id, err := menuID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	parentID, err := optionalMenuID(ctx, req.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	var order *int
+	if req.Order != nil {
+		n := int(*req.Order)
+		order = &n
+	}
+	if _, err := services.MoveMenuFn(ctx, id, parentID, order); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return current(ctx, id)
+}
+
+func (menuServer) Reorder(ctx context.Context, req *menuv1.ReorderRequest) (*menuv1.Menu, error) {
+	if err := checkOrder(ctx, &req.Order); err != nil {
+		return nil, err
+	}
+	id, err := menuID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := services.ReorderMenuFn(ctx, id, int(req.Order)); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return current(ctx, id)
+}
+
+func (menuServer) Delete(ctx context.Context, req *menuv1.DeleteRequest) (*menuv1.DeleteResponse, error) {
+	id, err := menuID(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := services.DeleteMenuRecursiveFn(ctx, id); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &menuv1.DeleteResponse{}, nil
+}
+
+// WatchChanges streams change events until the client goes away. A client
+// that falls too far behind is disconnected with UNAVAILABLE and should
+// reconnect with last_revision.
+func (menuServer) WatchChanges(req *menuv1.WatchChangesRequest, stream menuv1.MenuService_WatchChangesServer) error {
+	sub, backlog, ok := services.SubscribeEvents(req.GetLastRevision(), req.LastRevision != nil)
+	defer sub.Close()
+
+	if !ok {
+		reset := &menuv1.ChangeEvent{Revision: services.CurrentRevision(), Type: menuv1.ChangeType_CHANGE_TYPE_RESET}
+		if err := stream.Send(reset); err != nil {
+			return err
+		}
+	}
+	for _, ev := range backlog {
+		if err := stream.Send(eventToProto(ev)); err != nil {
+			return err
+		}
+	}
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case ev, open := <-sub.C:
+			if !open {
+				return status.Error(codes.Unavailable, "subscriber fell behind; reconnect with last_revision")
+			}
+			if err := stream.Send(eventToProto(ev)); err != nil {
+				return err
+			}
+		}
+	}
+}
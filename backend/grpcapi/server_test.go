@@ -0,0 +1,244 @@
+package grpcapi_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/grpcapi"
+	"github.com/galpt/sotekre/backend/models"
+	menuv1 "github.com/galpt/sotekre/backend/proto/menu/v1"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newClient serves grpcapi over an in-memory listener backed by a fresh
+// sqlite database.
+func newClient(t *testing.T) menuv1.MenuServiceClient {
+	t.Helper()
+	return newClientWith(t, grpcapi.Options{})
+}
+
+// newClientWith is newClient with server options.
+func newClientWith(t *testing.T, opts grpcapi.Options) menuv1.MenuServiceClient {
+	t.Helper()
+	dsn := fmt.Sprintf("file:memtest_grpc_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	config.DB = db
+	require.NoError(t, db.AutoMigrate(&models.Menu{}, &models.MenuChange{}))
+	services.InvalidateMenuTree()
+	t.Cleanup(func() { config.CloseDB() })
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpcapi.NewServer(opts)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return menuv1.NewMenuServiceClient(conn)
+}
+
+// problemCode returns the status code and the problem code of err.
+func problemCode(t *testing.T, err error) (codes.Code, string) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok, err)
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			require.Equal(t, grpcapi.ErrorDomain, info.Domain)
+			return st.Code(), info.Reason
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", err)
+	return 0, ""
+}
+
+func TestMenuService(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+
+	settings, err := c.Create(ctx, &menuv1.CreateRequest{Title: "Settings", Url: proto.String("/settings")})
+	require.NoError(t, err)
+	require.Equal(t, "settings", settings.Key)
+	require.NotNil(t, settings.CreatedAt)
+	billing, err := c.Create(ctx, &menuv1.CreateRequest{Title: "Billing", ParentId: "key:settings"})
+	require.NoError(t, err)
+	require.Equal(t, settings.Id, billing.GetParentId())
+	_, err = c.Create(ctx, &menuv1.CreateRequest{Title: "Help"})
+	require.NoError(t, err)
+
+	tree, err := c.GetTree(ctx, &menuv1.GetTreeRequest{})
+	require.NoError(t, err)
+	require.Len(t, tree.Items, 2)
+	require.Equal(t, "Billing", tree.Items[0].Children[0].Title)
+	tree, err = c.GetTree(ctx, &menuv1.GetTreeRequest{Depth: 1})
+	require.NoError(t, err)
+	require.Empty(t, tree.Items[0].Children)
+	tree, err = c.GetTree(ctx, &menuv1.GetTreeRequest{Root: "key:settings"})
+	require.NoError(t, err)
+	require.Len(t, tree.Items, 1)
+
+	m, err := c.Update(ctx, &menuv1.UpdateRequest{Id: "key:billing", Title: proto.String("Invoices"), Icon: proto.String("receipt")})
+	require.NoError(t, err)
+	require.Equal(t, "Invoices", m.Title)
+	require.Equal(t, "receipt", m.GetIcon())
+	m, err = c.Update(ctx, &menuv1.UpdateRequest{Id: "key:billing", Icon: proto.String("")})
+	require.NoError(t, err)
+	require.Nil(t, m.Icon, "an empty icon clears it")
+
+	m, err = c.Move(ctx, &menuv1.MoveRequest{Id: "key:billing", Order: proto.Int32(0)})
+	require.NoError(t, err)
+	require.Nil(t, m.ParentId)
+	require.Equal(t, int32(0), m.Order)
+	m, err = c.Reorder(ctx, &menuv1.ReorderRequest{Id: "key:billing", Order: 2})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), m.Order)
+
+	got, err := c.GetMenu(ctx, &menuv1.GetMenuRequest{Id: "key:settings"})
+	require.NoError(t, err)
+	require.Equal(t, "/settings", got.GetUrl())
+
+	_, err = c.Delete(ctx, &menuv1.DeleteRequest{Id: "key:settings"})
+	require.NoError(t, err)
+	_, err = c.GetMenu(ctx, &menuv1.GetMenuRequest{Id: fmt.Sprint(settings.Id)})
+	code, reason := problemCode(t, err)
+	require.Equal(t, codes.NotFound, code)
+	require.Equal(t, "menu_not_found", reason)
+}
+
+func TestMenuService_errors(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	root, err := c.Create(ctx, &menuv1.CreateRequest{Title: "Root"})
+	require.NoError(t, err)
+	child, err := c.Create(ctx, &menuv1.CreateRequest{Title: "Child", ParentId: fmt.Sprint(root.Id)})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		call   func() error
+		code   codes.Code
+		reason string
+	}{
+		{"invalid id", func() error { _, err := c.GetMenu(ctx, &menuv1.GetMenuRequest{Id: "abc"}); return err },
+			codes.InvalidArgument, "invalid_id"},
+		{"validation", func() error {
+			_, err := c.Create(ctx, &menuv1.CreateRequest{Title: " ", Url: proto.String("javascript:x")})
+			return err
+		}, codes.InvalidArgument, "validation_failed"},
+		{"key taken", func() error {
+			_, err := c.Update(ctx, &menuv1.UpdateRequest{Id: fmt.Sprint(child.Id), Key: proto.String("root")})
+			return err
+		}, codes.AlreadyExists, "key_conflict"},
+		{"cycle", func() error {
+			_, err := c.Move(ctx, &menuv1.MoveRequest{Id: fmt.Sprint(root.Id), ParentId: fmt.Sprint(child.Id)})
+			return err
+		}, codes.FailedPrecondition, "cycle_detected"},
+		{"negative order", func() error {
+			_, err := c.Reorder(ctx, &menuv1.ReorderRequest{Id: fmt.Sprint(child.Id), Order: -1})
+			return err
+		}, codes.InvalidArgument, "validation_failed"},
+		{"unknown key", func() error { _, err := c.Delete(ctx, &menuv1.DeleteRequest{Id: "key:nope"}); return err },
+			codes.NotFound, "menu_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, reason := problemCode(t, tt.call())
+			require.Equal(t, tt.code, code)
+			require.Equal(t, tt.reason, reason)
+		})
+	}
+
+	_, err = c.Create(ctx, &menuv1.CreateRequest{Title: " ", Url: proto.String("javascript:x")})
+	var fields []string
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.FieldViolations {
+				fields = append(fields, v.Field)
+			}
+		}
+	}
+	require.ElementsMatch(t, []string{"title", "url"}, fields)
+}
+
+func TestMenuService_WatchChanges(t *testing.T) {
+	c := newClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// resume from the current revision so nothing is missed while the server
+	// sets up the subscription
+	stream, err := c.WatchChanges(ctx, &menuv1.WatchChangesRequest{LastRevision: proto.Uint64(services.CurrentRevision())})
+	require.NoError(t, err)
+	m, err := c.Create(ctx, &menuv1.CreateRequest{Title: "Live"})
+	require.NoError(t, err)
+	ev, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, menuv1.ChangeType_CHANGE_TYPE_CREATED, ev.Type)
+	require.Equal(t, []uint64{m.Id}, ev.Ids)
+
+	// resuming from the last revision replays what happened since
+	_, err = c.Update(ctx, &menuv1.UpdateRequest{Id: fmt.Sprint(m.Id), Title: proto.String("Live!")})
+	require.NoError(t, err)
+	resumed, err := c.WatchChanges(ctx, &menuv1.WatchChangesRequest{LastRevision: proto.Uint64(ev.Revision)})
+	require.NoError(t, err)
+	next, err := resumed.Recv()
+	require.NoError(t, err)
+	require.Equal(t, ev.Revision+1, next.Revision)
+	require.Equal(t, menuv1.ChangeType_CHANGE_TYPE_UPDATED, next.Type)
+
+	// a revision from the future cannot be resumed
+	reset, err := c.WatchChanges(ctx, &menuv1.WatchChangesRequest{LastRevision: proto.Uint64(next.Revision + 100)})
+	require.NoError(t, err)
+	first, err := reset.Recv()
+	require.NoError(t, err)
+	require.Equal(t, menuv1.ChangeType_CHANGE_TYPE_RESET, first.Type)
+}
+
+func TestMenuService_requestID(t *testing.T) {
+	c := newClient(t)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-42")
+	_, err := c.GetTree(ctx, &menuv1.GetTreeRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{"req-42"}, header.Get("x-request-id"))
+
+	header = nil
+	_, err = c.GetMenu(context.Background(), &menuv1.GetMenuRequest{Id: "999"}, grpc.Header(&header))
+	require.Error(t, err)
+	require.Len(t, header.Get("x-request-id"), 1)
+	require.Len(t, header.Get("x-request-id")[0], 32, "a generated id")
+}
+
+func TestNewServer_reflectionIsOptIn(t *testing.T) {
+	_, ok := grpcapi.NewServer(grpcapi.Options{}).GetServiceInfo()["grpc.reflection.v1.ServerReflection"]
+	require.False(t, ok)
+	_, ok = grpcapi.NewServer(grpcapi.Options{Reflection: true}).GetServiceInfo()["grpc.reflection.v1.ServerReflection"]
+	require.True(t, ok)
+}
+
+func TestNewServer_capsRequestSize(t *testing.T) {
+	c := newClientWith(t, grpcapi.Options{MaxRecvMsgSize: 64})
+	_, err := c.Create(context.Background(), &menuv1.CreateRequest{Title: strings.Repeat("x", 100)})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,139 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/routes"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIv1_envelopes(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	r := routes.SetupRouter()
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	data := func(rec *httptest.ResponseRecorder) map[string]interface{} {
+		t.Helper()
+		var out struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out), rec.Body.String())
+		require.NotNil(t, out.Data, rec.Body.String())
+		return out.Data
+	}
+
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/menus", `{"title":"Settings"}`).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/menus", `{"title":"Billing","parent_id":1}`).Code)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/menus", `{"title":"Help"}`).Code)
+
+	rec := do(http.MethodPut, "/api/v1/menus/2", `{"title":"Invoices","parent_id":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "Invoices", data(rec)["title"])
+
+	rec = do(http.MethodPatch, "/api/v1/menus/2", `{"url":"/invoices"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "/invoices", data(rec)["url"])
+	require.Contains(t, rec.Body.String(), `"meta":{"siblings":[]}`)
+
+	rec = do(http.MethodPatch, "/api/v1/menus/2/move", `{"new_parent_id":null,"new_order":0}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	m := data(rec)
+	require.Nil(t, m["parent_id"])
+	require.EqualValues(t, 0, m["order"])
+
+	require.Contains(t, rec.Body.String(), `"meta":{"siblings":[{"id":1,"parent_id":null,"order":1},{"id":3,"parent_id":null,"order":2}]}`)
+
+	rec = do(http.MethodPatch, "/api/v1/menus/2/reorder", `{"new_order":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.EqualValues(t, 2, data(rec)["order"])
+	require.Contains(t, rec.Body.String(), `"meta":{"siblings":[{"id":1,"parent_id":null,"order":0},{"id":3,"parent_id":null,"order":1}]}`)
+
+	rec = do(http.MethodPut, "/api/v1/menus/root/children/order", `{"ids":[3,1,2]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"data":[{"id":3,`)
+
+	rec = do(http.MethodGet, "/api/v1/menus/flat?limit=2", "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"meta":{"next_cursor":`)
+
+	rec = do(http.MethodDelete, "/api/v1/menus/3", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Empty(t, rec.Body.String())
+	require.Empty(t, rec.Header().Get("Deprecation"))
+}
+
+func TestAPIv1_updateReturnsSiblings(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	r := routes.SetupRouter()
+	do := func(method, contentType, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	for i, title := range []string{"Home", "Blog", "About"} {
+		body := `{"title":"` + title + `","order":` + strconv.Itoa(i) + `}`
+		require.Equal(t, http.StatusCreated, do(http.MethodPost, "application/json", "/api/v1/menus", body).Code)
+	}
+	var out struct {
+		Data struct {
+			Order int `json:"order"`
+		} `json:"data"`
+		Meta struct {
+			Siblings []services.SiblingOrder `json:"siblings"`
+		} `json:"meta"`
+	}
+
+	// About moves to the front: Home and Blog both shift
+	rec := do(http.MethodPut, "application/json", "/api/v1/menus/3", `{"title":"About","parent_id":null,"order":0}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Equal(t, 0, out.Data.Order)
+	require.Equal(t, []services.SiblingOrder{{ID: 1, Order: 1}, {ID: 2, Order: 2}}, out.Meta.Siblings)
+
+	rec = do(http.MethodPatch, "application/merge-patch+json", "/api/v1/menus/3", `{"order":2}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	require.Equal(t, 2, out.Data.Order)
+	require.Equal(t, []services.SiblingOrder{{ID: 1, Order: 0}, {ID: 2, Order: 1}}, out.Meta.Siblings)
+}
+
+func TestLegacyAPI_deprecated(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	r := routes.SetupRouter()
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/menus", `{"title":"Settings"}`).Code)
+	rec := do(http.MethodPatch, "/api/menus/1/reorder", `{"new_order":0}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.JSONEq(t, `{"message":"reordered"}`, rec.Body.String())
+	require.NotEmpty(t, rec.Header().Get("Deprecation"))
+	require.NotEmpty(t, rec.Header().Get("Sunset"))
+	require.Equal(t, `</api/v1/menus/1/reorder>; rel="successor-version"`, rec.Header().Get("Link"))
+
+	rec = do(http.MethodDelete, "/api/menus/1", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"message":"deleted"}`, rec.Body.String())
+}
@@ -23,7 +23,7 @@ func setupInMemoryDBForDocs(t *testing.T) {
 		t.Fatalf("failed to open sqlite in-memory: %v", err)
 	}
 	config.DB = db
-	if err := config.DB.AutoMigrate(&models.Menu{}); err != nil {
+	if err := config.DB.AutoMigrate(&models.Menu{}, &models.MenuChange{}); err != nil {
 		t.Fatalf("migrate failed: %v", err)
 	}
 }
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// validationFailed writes the 400 shared by every validated input.
+func validationFailed(c *gin.Context, fields []services.FieldError) {
+	problem.Write(c, services.ValidationProblem(fields))
+}
+
+// invalidParam rejects a malformed path or query parameter.
+func invalidParam(c *gin.Context, detail string) {
+	problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidParameter, detail)
+}
+
+func invalidID(c *gin.Context) {
+	problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidID, "id must be a positive integer")
+}
+
+// bindFailed reports a request body that could not be decoded; a value of the
+// wrong type becomes a field error like any other validation failure.
+func bindFailed(c *gin.Context, err error) {
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) && te.Field != "" {
+		validationFailed(c, []services.FieldError{{Field: te.Field, Message: "must be " + jsonTypeName(te.Type.Kind().String())}})
+		return
+	}
+	problem.Abort(c, http.StatusBadRequest, problem.CodeMalformedBody, "request body must be a JSON object")
+}
+
+func jsonTypeName(kind string) string {
+	switch {
+	case kind == "string":
+		return "a string"
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"):
+		return "an integer"
+	case kind == "slice":
+		return "an array"
+	}
+	return "of type " + kind
+}
+
+// serviceError writes the problem for a menu service error. Anything
+// unexpected is logged and answered with a generic 500, so database messages
+// never reach clients.
+func serviceError(c *gin.Context, err error) {
+	if p, ok := services.ErrorProblem(err); ok {
+		problem.Write(c, p)
+		return
+	}
+	internalError(c, err)
+}
+
+func internalError(c *gin.Context, err error) {
+	ctx := c.Request.Context()
+	config.Logger(ctx).ErrorContext(ctx, "request failed", "error", err)
+	problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "internal server error")
+}
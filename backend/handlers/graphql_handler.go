@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// GraphQL query limits; override with GRAPHQL_MAX_DEPTH and
+// GRAPHQL_MAX_COMPLEXITY.
+const (
+	defaultGraphQLMaxDepth      = 10
+	defaultGraphQLMaxComplexity = 5000
+)
+
+// gqlListFactor is the assumed size of a list field when estimating
+// complexity: everything selected below it counts this many times.
+const gqlListFactor = 5
+
+// gqlListFields return lists of menus.
+var gqlListFields = map[string]bool{"menus": true, "search": true, "children": true}
+
+// queryCost returns the depth and estimated complexity of sel: one per field,
+// with the selection below a list field counted gqlListFactor times.
+// Introspection fields (__schema, __type, ...) are free.
+func queryCost(sel *ast.SelectionSet, fragments map[string]*ast.FragmentDefinition, visiting map[string]bool) (depth, complexity int) {
+	if sel == nil {
+		return 0, 0
+	}
+	for _, s := range sel.Selections {
+		var d, c int
+		switch s := s.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			d, c = queryCost(s.SelectionSet, fragments, visiting)
+			if gqlListFields[s.Name.Value] {
+				c *= gqlListFactor
+			}
+			d, c = d+1, c+1
+		case *ast.InlineFragment:
+			d, c = queryCost(s.SelectionSet, fragments, visiting)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			f, ok := fragments[name]
+			if !ok || visiting[name] { // validation reports these
+				continue
+			}
+			visiting[name] = true
+			d, c = queryCost(f.SelectionSet, fragments, visiting)
+			delete(visiting, name)
+		}
+		depth = max(depth, d)
+		complexity += c
+	}
+	return depth, complexity
+}
+
+// checkGraphQLLimits rejects documents whose operations nest deeper or are
+// estimated to be more complex than allowed.
+func checkGraphQLLimits(doc *ast.Document) *gqlError {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[f.Name.Value] = f
+		}
+	}
+	maxDepth := config.EnvIntOr("GRAPHQL_MAX_DEPTH", defaultGraphQLMaxDepth)
+	maxComplexity := config.EnvIntOr("GRAPHQL_MAX_COMPLEXITY", defaultGraphQLMaxComplexity)
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		depth, complexity := queryCost(op.SelectionSet, fragments, map[string]bool{})
+		if depth > maxDepth {
+			return &gqlError{problem.New(http.StatusBadRequest, problem.CodeQueryTooDeep,
+				fmt.Sprintf("query depth %d exceeds the limit of %d", depth, maxDepth))}
+		}
+		if complexity > maxComplexity {
+			return &gqlError{problem.New(http.StatusBadRequest, problem.CodeQueryTooComplex,
+				fmt.Sprintf("query complexity %d exceeds the limit of %d", complexity, maxComplexity))}
+		}
+	}
+	return nil
+}
+
+// isMutation reports whether the operation that will run (operationName, or
+// the only one) is a mutation.
+func isMutation(doc *ast.Document, operationName string) bool {
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (op.Name != nil && op.Name.Value == operationName) {
+			if op.Operation == ast.OperationTypeMutation {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query" example:"{ menus(depth: 2) { id title children { id title } } }"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// graphQLResponse and graphQLError document the response shape for swag.
+type graphQLResponse struct {
+	Data   interface{}    `json:"data,omitempty"`
+	Errors []graphQLError `json:"errors,omitempty"`
+}
+
+type graphQLError struct {
+	Message    string                 `json:"message" example:"menu item does not exist"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQL godoc
+// @Summary GraphQL endpoint for menu queries and mutations
+// @Description Queries `menu(id)`, `menus(root, depth)` and `search(q, limit)`, with `children` and `parent` on every
+// @Description `Menu`; mutations `createMenu`, `updateMenu`, `moveMenu`, `reorderMenu` and `deleteMenu` run through
+// @Description the same services as REST. Errors carry the REST problem `code` and `status` in `extensions`.
+// @Description Documents deeper than `GRAPHQL_MAX_DEPTH` (10) or more complex than `GRAPHQL_MAX_COMPLEXITY` (5000;
+// @Description one per field, list selections count 5 times) are rejected with `query_too_deep` /
+// @Description `query_too_complex`. GET runs queries only.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param input body graphQLRequest true "GraphQL request"
+// @Success 200 {object} graphQLResponse
+// @Failure 400 {object} problem.Problem
+// @Router /graphql [post]
+func GraphQL(c *gin.Context) {
+	var req graphQLRequest
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if v := c.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				invalidParam(c, "variables must be a JSON object")
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		invalidParam(c, "query is required")
+		return
+	}
+	// syntax errors are left to the executor, which reports them as usual
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query)})})
+	if err == nil {
+		if c.Request.Method == http.MethodGet && isMutation(doc, req.OperationName) {
+			problem.Abort(c, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "mutations must be sent with POST")
+			return
+		}
+		if e := checkGraphQLLimits(doc); e != nil {
+			c.JSON(http.StatusOK, &graphql.Result{Errors: []gqlerrors.FormattedError{
+				gqlerrors.FormatError(&gqlerrors.Error{Message: e.Error(), OriginalError: e}),
+			}})
+			return
+		}
+	}
+
+	res := graphql.Do(graphql.Params{
+		Schema:         gqlSchema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        withGQLTree(c.Request.Context()),
+	})
+	c.JSON(http.StatusOK, res)
+}
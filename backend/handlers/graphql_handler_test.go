@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/routes"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/stretchr/testify/require"
+)
+
+type gqlResult struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func TestGraphQL_queriesAndMutations(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	services.InvalidateMenuTree()
+	r := routes.SetupRouter()
+	gql := func(query string, vars map[string]interface{}) gqlResult {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var res gqlResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res), rec.Body.String())
+		return res
+	}
+
+	res := gql(`mutation { createMenu(input: {title: "Settings", url: "/settings"}) { id key } }`, nil)
+	require.Empty(t, res.Errors)
+	require.JSONEq(t, `{"id":"1","key":"settings"}`, string(res.Data["createMenu"]))
+	res = gql(`mutation($p: ID) { createMenu(input: {title: "Billing", parentId: $p}) { id parent { title } } }`,
+		map[string]interface{}{"p": "key:settings"})
+	require.Empty(t, res.Errors)
+	require.JSONEq(t, `{"id":"2","parent":{"title":"Settings"}}`, string(res.Data["createMenu"]))
+	require.Empty(t, gql(`mutation { createMenu(input: {title: "Help"}) { id } }`, nil).Errors)
+
+	res = gql(`{ menus(depth: 1) { title children { title } } }`, nil)
+	require.JSONEq(t, `[{"title":"Settings","children":[]},{"title":"Help","children":[]}]`, string(res.Data["menus"]))
+	res = gql(`{ menus(root: "1") { title } search(q: "bill") { key } menu(id: "key:nope") { id } }`, nil)
+	require.Empty(t, res.Errors)
+	require.JSONEq(t, `[{"title":"Billing"}]`, string(res.Data["menus"]))
+	require.JSONEq(t, `[{"key":"billing"}]`, string(res.Data["search"]))
+	require.JSONEq(t, `null`, string(res.Data["menu"]))
+
+	res = gql(`mutation { updateMenu(id: "2", input: {title: "Invoices", url: "/invoices"}) { title url } }`, nil)
+	require.Empty(t, res.Errors)
+	require.JSONEq(t, `{"title":"Invoices","url":"/invoices"}`, string(res.Data["updateMenu"]))
+	res = gql(`mutation { moveMenu(id: "2") { parentId } }`, nil)
+	require.Empty(t, res.Errors)
+	require.JSONEq(t, `{"parentId":null}`, string(res.Data["moveMenu"]))
+	res = gql(`mutation { reorderMenu(id: "2", order: 0) { order } }`, nil)
+	require.Empty(t, res.Errors)
+	require.JSONEq(t, `{"order":0}`, string(res.Data["reorderMenu"]))
+
+	// service errors carry the REST problem codes
+	res = gql(`mutation { moveMenu(id: "1", parentId: "1") { id } }`, nil)
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, "cycle_detected", res.Errors[0].Extensions["code"])
+	res = gql(`mutation { updateMenu(id: "1", input: {url: "javascript:x"}) { id } }`, nil)
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, "validation_failed", res.Errors[0].Extensions["code"])
+	require.Contains(t, res.Errors[0].Extensions["errors"], map[string]interface{}{"field": "url", "message": `scheme "javascript" is not allowed`})
+	res = gql(`{ menus(root: "99") { id } }`, nil)
+	require.Equal(t, "menu_not_found", res.Errors[0].Extensions["code"])
+
+	res = gql(`mutation { deleteMenu(id: "key:settings") }`, nil)
+	require.Empty(t, res.Errors)
+	res = gql(`{ menus { title } }`, nil)
+	require.JSONEq(t, `[{"title":"Invoices"},{"title":"Help"}]`, string(res.Data["menus"]))
+}
+
+func TestGraphQL_limits(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	t.Setenv("GRAPHQL_MAX_DEPTH", "3")
+	t.Setenv("GRAPHQL_MAX_COMPLEXITY", "40")
+	r := routes.SetupRouter()
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(query), nil))
+		return rec
+	}
+	code := func(rec *httptest.ResponseRecorder) interface{} {
+		var res gqlResult
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res), rec.Body.String())
+		if len(res.Errors) == 0 {
+			return nil
+		}
+		return res.Errors[0].Extensions["code"]
+	}
+
+	require.Nil(t, code(get(`{ menus { id children { id } } }`)))
+	require.Equal(t, "query_too_deep", code(get(`{ menus { children { children { id } } } }`)))
+	require.Equal(t, "query_too_deep", code(get(`{ menus { ...f } } fragment f on Menu { children { children { id } } }`)))
+	require.Equal(t, "query_too_complex", code(get(`{ menus { id key title url order children { id } } }`)))
+	require.Nil(t, code(get(`{ __schema { types { name fields { name type { name ofType { name } } } } } }`)), "introspection is free")
+
+	rec := get(`mutation { deleteMenu(id: "1") }`)
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"method_not_allowed"`)
+}
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+)
+
+// gqlError carries a problem into the GraphQL response: Detail becomes the
+// message and code, status and field errors the extensions, so clients see
+// the same codes as over REST.
+type gqlError struct{ p problem.Problem }
+
+func (e *gqlError) Error() string { return e.p.Detail }
+
+func (e *gqlError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.p.Code, "status": e.p.Status}
+	if len(e.p.Errors) > 0 {
+		ext["errors"] = e.p.Errors
+	}
+	return ext
+}
+
+// toGQLError maps a service error like serviceError does; unexpected errors
+// are logged and reported as internal_error.
+func toGQLError(ctx context.Context, err error) error {
+	if p, ok := services.ErrorProblem(err); ok {
+		return &gqlError{p}
+	}
+	config.Logger(ctx).ErrorContext(ctx, "graphql resolver failed", "error", err)
+	return &gqlError{problem.New(http.StatusInternalServerError, problem.CodeInternal, "internal server error")}
+}
+
+// gqlTree indexes the (cached) menu tree for one request, so children and
+// parent resolvers do not query per item. Mutations drop it.
+type gqlTree struct {
+	roots []*models.MenuNode
+	byID  map[uint]*models.MenuNode
+}
+
+type gqlTreeKey struct{}
+
+// gqlTreeHolder is stored in the request context by the GraphQL handler.
+type gqlTreeHolder struct{ tree *gqlTree }
+
+func withGQLTree(ctx context.Context) context.Context {
+	return context.WithValue(ctx, gqlTreeKey{}, &gqlTreeHolder{})
+}
+
+func loadGQLTree(ctx context.Context) (*gqlTree, error) {
+	h, _ := ctx.Value(gqlTreeKey{}).(*gqlTreeHolder)
+	if h != nil && h.tree != nil {
+		return h.tree, nil
+	}
+	roots, err := services.GetMenuTreeFn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t := &gqlTree{roots: roots, byID: map[uint]*models.MenuNode{}}
+	var index func(level []*models.MenuNode)
+	index = func(level []*models.MenuNode) {
+		for _, n := range level {
+			t.byID[n.ID] = n
+			index(n.Children)
+		}
+	}
+	index(roots)
+	if h != nil {
+		h.tree = t
+	}
+	return t, nil
+}
+
+func dropGQLTree(ctx context.Context) {
+	if h, _ := ctx.Value(gqlTreeKey{}).(*gqlTreeHolder); h != nil {
+		h.tree = nil
+	}
+}
+
+// gqlMenu is the source value of the Menu type. depth is how many more levels
+// of children may be returned (negative: unlimited).
+type gqlMenu struct {
+	node  *models.MenuNode
+	depth int
+}
+
+func gqlMenus(nodes []*models.MenuNode, depth int) []gqlMenu {
+	out := make([]gqlMenu, len(nodes))
+	for i, n := range nodes {
+		out[i] = gqlMenu{node: n, depth: depth}
+	}
+	return out
+}
+
+// menuByID returns the item from the tree (freshly loaded after mutations).
+func menuByID(ctx context.Context, id uint) (interface{}, error) {
+	t, err := loadGQLTree(ctx)
+	if err != nil {
+		return nil, toGQLError(ctx, err)
+	}
+	n, ok := t.byID[id]
+	if !ok {
+		return nil, toGQLError(ctx, gorm.ErrRecordNotFound)
+	}
+	return gqlMenu{node: n, depth: -1}, nil
+}
+
+func idString(id uint) string { return strconv.FormatUint(uint64(id), 10) }
+
+// menuIDArg resolves an ID argument.
+func menuIDArg(p graphql.ResolveParams, name string) (uint, error) {
+	raw, _ := p.Args[name].(string)
+	id, err := services.LookupMenuID(p.Context, raw)
+	if err != nil {
+		return 0, toGQLError(p.Context, err)
+	}
+	return id, nil
+}
+
+var gqlMenuType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Menu",
+	Description: "A menu item.",
+	Fields: func() graphql.Fields {
+		field := func(t graphql.Output, get func(n *models.MenuNode) interface{}) *graphql.Field {
+			return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return get(p.Source.(gqlMenu).node), nil
+			}}
+		}
+		return graphql.Fields{
+			"id":    field(graphql.NewNonNull(graphql.ID), func(n *models.MenuNode) interface{} { return idString(n.ID) }),
+			"key":   field(graphql.NewNonNull(graphql.String), func(n *models.MenuNode) interface{} { return n.Key }),
+			"title": field(graphql.NewNonNull(graphql.String), func(n *models.MenuNode) interface{} { return n.Title }),
+			"url": field(graphql.String, func(n *models.MenuNode) interface{} {
+				if n.URL == nil {
+					return nil
+				}
+				return *n.URL
+			}),
+			"parentId": field(graphql.ID, func(n *models.MenuNode) interface{} {
+				if n.ParentID == nil {
+					return nil
+				}
+				return idString(*n.ParentID)
+			}),
+			"order": field(graphql.NewNonNull(graphql.Int), func(n *models.MenuNode) interface{} { return n.Order }),
+		}
+	}(),
+})
+
+var gqlQueryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"menu": {
+			Type:        gqlMenuType,
+			Description: "One item by id or key:<menu key>; null when it does not exist.",
+			Args: graphql.FieldConfigArgument{
+				"id": {Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				raw, _ := p.Args["id"].(string)
+				id, err := services.LookupMenuID(p.Context, raw)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, nil
+				} else if err != nil {
+					return nil, toGQLError(p.Context, err)
+				}
+				t, err := loadGQLTree(p.Context)
+				if err != nil {
+					return nil, toGQLError(p.Context, err)
+				}
+				if n, ok := t.byID[id]; ok {
+					return gqlMenu{node: n, depth: -1}, nil
+				}
+				return nil, nil
+			},
+		},
+		"menus": {
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(gqlMenuType))),
+			Description: "The root items, or the children of root; depth limits how many levels children returns (1: none).",
+			Args: graphql.FieldConfigArgument{
+				"root":  {Type: graphql.ID},
+				"depth": {Type: graphql.Int},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				depth := -1
+				if d, ok := p.Args["depth"].(int); ok {
+					if d < 1 {
+						return nil, &gqlError{problem.New(http.StatusBadRequest, problem.CodeInvalidParameter, "depth must be >= 1")}
+					}
+					depth = d - 1
+				}
+				t, err := loadGQLTree(p.Context)
+				if err != nil {
+					return nil, toGQLError(p.Context, err)
+				}
+				level := t.roots
+				if _, ok := p.Args["root"]; ok {
+					id, err := menuIDArg(p, "root")
+					if err != nil {
+						return nil, err
+					}
+					n, ok := t.byID[id]
+					if !ok {
+						return nil, toGQLError(p.Context, gorm.ErrRecordNotFound)
+					}
+					level = n.Children
+				}
+				return gqlMenus(level, depth), nil
+			},
+		},
+		"search": {
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(gqlMenuType))),
+			Description: "Items whose title, key or URL contains q, by title (limit: default 20, max 100).",
+			Args: graphql.FieldConfigArgument{
+				"q":     {Type: graphql.NewNonNull(graphql.String)},
+				"limit": {Type: graphql.Int},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				q, _ := p.Args["q"].(string)
+				limit, _ := p.Args["limit"].(int)
+				rows, err := services.SearchMenusFn(p.Context, q, limit)
+				if err != nil {
+					return nil, toGQLError(p.Context, err)
+				}
+				t, err := loadGQLTree(p.Context)
+				if err != nil {
+					return nil, toGQLError(p.Context, err)
+				}
+				out := make([]gqlMenu, 0, len(rows))
+				for _, r := range rows {
+					if n, ok := t.byID[r.ID]; ok {
+						out = append(out, gqlMenu{node: n, depth: -1})
+					}
+				}
+				return out, nil
+			},
+		},
+	},
+})
+
+var gqlCreateMenuInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateMenuInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title":    {Type: graphql.NewNonNull(graphql.String)},
+		"key":      {Type: graphql.String, Description: "generated from the title when omitted"},
+		"url":      {Type: graphql.String},
+		"icon":     {Type: graphql.String},
+		"parentId": {Type: graphql.ID},
+		"order":    {Type: graphql.Int},
+	},
+})
+
+var gqlUpdateMenuInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "UpdateMenuInput",
+	Description: "Only the given fields change; an empty url or icon clears it. Use moveMenu to change the parent.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title": {Type: graphql.String},
+		"key":   {Type: graphql.String},
+		"url":   {Type: graphql.String},
+		"icon":  {Type: graphql.String},
+		"order": {Type: graphql.Int},
+	},
+})
+
+// mutated drops the request's tree after a successful write and returns the
+// item as it is now.
+func mutated(ctx context.Context, id uint) (interface{}, error) {
+	dropGQLTree(ctx)
+	return menuByID(ctx, id)
+}
+
+func optString(in map[string]interface{}, k string) *string {
+	if s, ok := in[k].(string); ok {
+		return &s
+	}
+	return nil
+}
+
+var gqlMutationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Mutation",
+	Fields: graphql.Fields{
+		"createMenu": {
+			Type: graphql.NewNonNull(gqlMenuType),
+			Args: graphql.FieldConfigArgument{
+				"input": {Type: graphql.NewNonNull(gqlCreateMenuInput)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				in, _ := p.Args["input"].(map[string]interface{})
+				m := &models.Menu{URL: optString(in, "url"), Icon: optString(in, "icon")}
+				m.Title, _ = in["title"].(string)
+				m.Key, _ = in["key"].(string)
+				m.Order, _ = in["order"].(int)
+				if raw, ok := in["parentId"].(string); ok {
+					id, err := services.LookupMenuID(p.Context, raw)
+					if err != nil {
+						return nil, toGQLError(p.Context, err)
+					}
+					m.ParentID = &id
+				}
+				if err := services.CreateMenuFn(p.Context, m); err != nil {
+					return nil, toGQLError(p.Context, err)
+				}
+				return mutated(p.Context, m.ID)
+			},
+		},
+		"updateMenu": {
+			Type: graphql.NewNonNull(gqlMenuType),
+			Args: graphql.FieldConfigArgument{
+				"id":    {Type: graphql.NewNonNull(graphql.ID)},
+				"input": {Type: graphql.NewNonNull(gqlUpdateMenuInput)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, err := menuIDArg(p, "id")
+				if err != nil {
+					return nil, err
+				}
+				in, _ := p.Args["input"].(map[string]interface{})
+				upd := map[string]interface{}{}
+				for k, v := range in {
+					upd[k] = v
+				}
+				if len(upd) > 0 {
+					if _, err := services.UpdateMenuFn(p.Context, id, upd); err != nil {
+						return nil, toGQLError(p.Context, err)
+					}
+				}
+				return mutated(p.Context, id)
+			},
+		},
+		"moveMenu": {
+			Type:        graphql.NewNonNull(gqlMenuType),
+			Description: "Moves the item under parentId (the root when omitted), at order or last.",
+			Args: graphql.FieldConfigArgument{
+				"id":       {Type: graphql.NewNonNull(graphql.ID)},
+				"parentId": {Type: graphql.ID},
+				"order":    {Type: graphql.Int},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, err := menuIDArg(p, "id")
+				if err != nil {
+					return nil, err
+				}
+				var parentID *uint
+				if _, ok := p.Args["parentId"]; ok {
+					pid, err := menuIDArg(p, "parentId")
+					if err != nil {
+						return nil, err
+					}
+					parentID = &pid
+				}
+				var order *int
+				if n, ok := p.Args["order"].(int); ok {
+					if n < 0 {
+						return nil, toGQLError(p.Context, &services.ValidationError{Fields: []services.FieldError{{Field: "order", Message: "must be >= 0"}}})
+					}
+					order = &n
+				}
+				if _, err := services.MoveMenuFn(p.Context, id, parentID, order); err != nil {
+					return nil, toGQLError(p.Context, err)
+				}
+				return mutated(p.Context, id)
+			},
+		},
+		"reorderMenu": {
+			Type: graphql.NewNonNull(gqlMenuType),
+			Args: graphql.FieldConfigArgument{
+				"id":    {Type: graphql.NewNonNull(graphql.ID)},
+				"order": {Type: graphql.NewNonNull(graphql.Int)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, err := menuIDArg(p, "id")
+				if err != nil {
+					return nil, err
+				}
+				n, _ := p.Args["order"].(int)
+				if n < 0 {
+					return nil, toGQLError(p.Context, &services.ValidationError{Fields: []services.FieldError{{Field: "order", Message: "must be >= 0"}}})
+				}
+				if _, err := services.ReorderMenuFn(p.Context, id, n); err != nil {
+					return nil, toGQLError(p.Context, err)
+				}
+				return mutated(p.Context, id)
+			},
+		},
+		"deleteMenu": {
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "Deletes the item and all its descendants.",
+			Args: graphql.FieldConfigArgument{
+				"id": {Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, err := menuIDArg(p, "id")
+				if err != nil {
+					return nil, err
+				}
+				if err := services.DeleteMenuRecursiveFn(p.Context, id); err != nil {
+					return nil, toGQLError(p.Context, err)
+				}
+				dropGQLTree(p.Context)
+				return true, nil
+			},
+		},
+	},
+})
+
+// gqlSchema is the /graphql schema.
+var gqlSchema graphql.Schema
+
+func init() {
+	// Menu refers to itself, so these are added after it exists
+	gqlMenuType.AddFieldConfig("children", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(gqlMenuType))),
+		Description: "Child items in order (empty below the depth requested from menus).",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			m := p.Source.(gqlMenu)
+			if m.depth == 0 {
+				return []gqlMenu{}, nil
+			}
+			return gqlMenus(m.node.Children, m.depth-1), nil
+		},
+	})
+	gqlMenuType.AddFieldConfig("parent", &graphql.Field{
+		Type: gqlMenuType,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			n := p.Source.(gqlMenu).node
+			if n.ParentID == nil {
+				return nil, nil
+			}
+			t, err := loadGQLTree(p.Context)
+			if err != nil {
+				return nil, toGQLError(p.Context, err)
+			}
+			parent, ok := t.byID[*n.ParentID]
+			if !ok {
+				return nil, nil
+			}
+			return gqlMenu{node: parent, depth: -1}, nil
+		},
+	})
+
+	var err error
+	gqlSchema, err = graphql.NewSchema(graphql.SchemaConfig{Query: gqlQueryType, Mutation: gqlMutationType})
+	if err != nil {
+		panic("graphql schema: " + err.Error())
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+type healthResponse struct {
+	Status string `json:"status" example:"ok"`
+}
+
+var _ = (*healthResponse)(nil)
+
+// Healthz godoc
+// @Summary Liveness probe
+// @Description Always 200 while the process is serving HTTP; it does not touch the database.
+// @Tags health
+// @Produce json
+// @Success 200 {object} healthResponse
+// @Router /healthz [get]
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Description 200 when the database answers a ping and every table and column is migrated; 503 otherwise and while
+// @Description the server drains during shutdown. The ping is bounded by `READYZ_TIMEOUT_MS`. `checks` holds
+// @Description `ok` or a generic failure (`unavailable`, `pending`); details are logged, not returned.
+// @Description `pool` reports connection pool usage and saturation.
+// @Tags health
+// @Produce json
+// @Success 200 {object} services.Readiness
+// @Failure 503 {object} services.Readiness
+// @Router /readyz [get]
+func Readyz(c *gin.Context) {
+	r := services.CheckReadinessFn(c.Request.Context())
+	status := http.StatusOK
+	if !r.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, r)
+}
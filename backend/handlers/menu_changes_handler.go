@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// MenuChanges godoc
+// @Summary Incremental sync: menu changes since a sequence
+// @Description Returns the current state of every item created/updated/moved after `since`, the ids deleted
+// @Description since then, and the `cursor` to pass next time. Start with `since=0`. A 410 means the cursor
+// @Description is older than the retention window: refetch `/api/v1/menus` and continue from the returned `cursor`.
+// @Tags menus
+// @Produce json
+// @Param since query int false "last cursor seen (default 0)"
+// @Success 200 {object} changeSetResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 410 {object} problem.Problem "changes_expired; carries the `cursor` to resume from"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/changes [get]
+func MenuChanges(c *gin.Context) {
+	var since uint64
+	if s := c.Query("since"); s != "" {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			invalidParam(c, "since must be a non-negative integer")
+			return
+		}
+		since = v
+	}
+
+	set, err := services.GetChangesSinceFn(c.Request.Context(), since)
+	if err != nil {
+		if errors.Is(err, services.ErrChangesExpired) {
+			// resync with GET /api/menus, then continue from cursor
+			p := problem.New(http.StatusGone, problem.CodeChangesExpired, err.Error())
+			p.Extensions = map[string]interface{}{"cursor": set.Cursor}
+			problem.Write(c, p)
+			return
+		}
+		internalError(c, err)
+		return
+	}
+	if legacyAPI(c) {
+		c.JSON(http.StatusOK, set)
+		return
+	}
+	respondData(c, http.StatusOK, set)
+}
+
+type changeSetResponse struct {
+	Data services.ChangeSet `json:"data"`
+}
+
+var _ = (*changeSetResponse)(nil)
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// EventsHeartbeat is how often an idle stream sends a comment line so proxies
+// keep the connection open (overridable in tests).
+var EventsHeartbeat = 15 * time.Second
+
+// resetEvent tells the client its Last-Event-ID could not be resumed and it
+// should refetch the tree.
+type resetEvent struct {
+	Revision uint64 `json:"revision"`
+}
+
+// MenuEvents godoc
+// @Summary Stream menu changes (Server-Sent Events)
+// @Description Emits `created`, `updated`, `moved`, `reordered` and `deleted` events whose `id` is the revision.
+// @Description Reconnect with `Last-Event-ID` (or `?last_event_id=`) to resume; a `reset` event means the
+// @Description revision is no longer in history and the client should refetch `/api/v1/menus`.
+// @Tags menus
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "last revision seen"
+// @Success 200 {object} services.ChangeEvent
+// @Failure 400 {object} problem.Problem
+// @Router /api/v1/menus/events [get]
+func MenuEvents(c *gin.Context) {
+	lastStr := c.GetHeader("Last-Event-ID")
+	if lastStr == "" {
+		lastStr = c.Query("last_event_id")
+	}
+	var last uint64
+	resume := lastStr != ""
+	if resume {
+		v, err := strconv.ParseUint(lastStr, 10, 64)
+		if err != nil {
+			invalidParam(c, "Last-Event-ID must be a non-negative integer")
+			return
+		}
+		last = v
+	}
+
+	sub, backlog, ok := services.SubscribeEvents(last, resume)
+	defer sub.Close()
+
+	// the stream outlives the server's WriteTimeout; lift it for this response
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if !ok {
+		c.Render(-1, sse.Event{Event: "reset", Data: resetEvent{Revision: services.CurrentRevision()}})
+	}
+	for _, ev := range backlog {
+		renderChangeEvent(c, ev)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(EventsHeartbeat)
+	defer heartbeat.Stop()
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case ev, open := <-sub.C:
+			if !open {
+				return false
+			}
+			renderChangeEvent(c, ev)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+func renderChangeEvent(c *gin.Context, ev services.ChangeEvent) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(ev.Revision, 10),
+		Event: string(ev.Type),
+		Data:  ev,
+	})
+}
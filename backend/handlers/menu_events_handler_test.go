@@ -0,0 +1,98 @@
+package handlers_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/routes"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/stretchr/testify/require"
+)
+
+type sseFrame struct {
+	id, event, data string
+}
+
+// readFrame reads one SSE frame, skipping heartbeat comments.
+func readFrame(t *testing.T, rd *bufio.Reader) sseFrame {
+	t.Helper()
+	var f sseFrame
+	for {
+		line, err := rd.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if f.event != "" || f.data != "" {
+				return f
+			}
+		case strings.HasPrefix(line, "id:"):
+			f.id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"):
+			f.event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			f.data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+}
+
+func openStream(t *testing.T, ctx context.Context, url, lastEventID string) *bufio.Reader {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	return bufio.NewReader(res.Body)
+}
+
+func TestMenuEvents_streamsMutationsAndResumes(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	srv := httptest.NewServer(routes.SetupRouter())
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rd := openStream(t, ctx, srv.URL+"/api/menus/events", "")
+
+	res, err := http.Post(srv.URL+"/api/menus", "application/json", bytes.NewReader([]byte(`{"title":"live"}`)))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	f := readFrame(t, rd)
+	require.Equal(t, "created", f.event)
+	require.Equal(t, fmt.Sprint(services.CurrentRevision()), f.id)
+	require.Contains(t, f.data, `"type":"created"`)
+
+	// a reconnect from the previous revision replays the missed event
+	prev := services.CurrentRevision() - 1
+	rd2 := openStream(t, ctx, srv.URL+"/api/menus/events", fmt.Sprint(prev))
+	require.Equal(t, f.id, readFrame(t, rd2).id)
+
+	// a revision from the future cannot be resumed
+	rd3 := openStream(t, ctx, srv.URL+"/api/menus/events", fmt.Sprint(prev+1000))
+	require.Equal(t, "reset", readFrame(t, rd3).event)
+}
+
+func TestMenuEvents_invalidLastEventID_returns400(t *testing.T) {
+	r := routes.SetupRouter()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/menus/events?last_event_id=abc", nil)
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
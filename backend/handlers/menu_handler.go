@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/galpt/sotekre/backend/models"
 	"github.com/galpt/sotekre/backend/services"
@@ -10,8 +15,10 @@ import (
 )
 
 type createMenuInput struct {
-	Title    string  `json:"title" binding:"required"`
-	URL      *string `json:"url"`
+	Title    string  `json:"title" example:"Orders"`
+	Key      string  `json:"key,omitempty" example:"orders"`
+	URL      *string `json:"url" example:"/orders"`
+	Icon     *string `json:"icon"`
 	ParentID *uint   `json:"parent_id"`
 	Order    *int    `json:"order"`
 }
@@ -21,9 +28,22 @@ type getMenusResponse struct {
 	Data []*models.MenuNode `json:"data"`
 }
 
+// replaceMenuInput is the PUT body: title and parent_id (null for the root)
+// must be present.
+type replaceMenuInput struct {
+	Title    string  `json:"title" binding:"required" example:"Orders"`
+	Key      *string `json:"key,omitempty"`
+	URL      *string `json:"url,omitempty"`
+	Icon     *string `json:"icon,omitempty"`
+	ParentID *uint   `json:"parent_id" binding:"required" extensions:"x-nullable"`
+	Order    *int    `json:"order,omitempty"`
+}
+
 type updateMenuInput struct {
 	Title    *string `json:"title,omitempty"`
+	Key      *string `json:"key,omitempty"`
 	URL      *string `json:"url,omitempty"`
+	Icon     *string `json:"icon,omitempty"`
 	ParentID *uint   `json:"parent_id,omitempty"`
 	Order    *int    `json:"order,omitempty"`
 }
@@ -37,20 +57,126 @@ type moveInput struct {
 	NewOrder    *int  `json:"new_order,omitempty"`
 }
 
-type errorResponse struct {
-	Error string `json:"error"`
+type duplicateInput struct {
+	NewParentID *uint  `json:"new_parent_id" example:"3"`
+	NewOrder    *int   `json:"new_order,omitempty" example:"0"`
+	TitleSuffix string `json:"title_suffix,omitempty" example:" (copy)"`
+}
+
+type childOrderInput struct {
+	IDs []uint `json:"ids" binding:"required" example:"3,1,2"`
+}
+
+type resolveMenuResponse struct {
+	Data services.MenuMatch `json:"data"`
+}
+
+type menuResponse struct {
+	Data models.Menu `json:"data"`
+}
+
+type menuNodesResponse struct {
+	Data []*models.MenuNode `json:"data"`
+}
+
+type flatMenusMeta struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+type flatMenusResponse struct {
+	Data []models.Menu `json:"data"`
+	Meta flatMenusMeta `json:"meta"`
 }
 
 // Ensure these doc-only types are referenced so gopls / static analysis do not
 // report them as unused (they're consumed by swag via reflection only).
 var (
 	_ = (*getMenusResponse)(nil)
+	_ = (*replaceMenuInput)(nil)
 	_ = (*updateMenuInput)(nil)
 	_ = (*reorderInput)(nil)
 	_ = (*moveInput)(nil)
-	_ = (*errorResponse)(nil)
+	_ = (*duplicateInput)(nil)
+	_ = (*childOrderInput)(nil)
+	_ = (*flatMenusResponse)(nil)
+	_ = (*menuResponse)(nil)
+	_ = (*menuNodesResponse)(nil)
+	_ = (*resolveMenuResponse)(nil)
 )
 
+// decodeMenuUpdate strictly decodes the updatable fields of body: title and
+// key are strings, url and icon a string or null (clears), parent_id an id or null
+// (root) and order an integer. Other keys are ignored so clients can send a
+// whole menu object back.
+func decodeMenuUpdate(body map[string]json.RawMessage) (map[string]interface{}, []services.FieldError) {
+	upd := map[string]interface{}{}
+	var fields []services.FieldError
+	for _, k := range menuFields {
+		raw, present := body[k]
+		if !present {
+			continue
+		}
+		isNull := string(raw) == "null"
+		var (
+			v  interface{}
+			ok bool
+		)
+		switch k {
+		case "title", "key":
+			var s string
+			ok = !isNull && json.Unmarshal(raw, &s) == nil
+			v = s
+		case "url", "icon":
+			var s *string
+			ok = json.Unmarshal(raw, &s) == nil
+			v = s
+		case "parent_id":
+			var p *uint
+			ok = json.Unmarshal(raw, &p) == nil
+			v = p
+		case "order":
+			var n int
+			ok = !isNull && json.Unmarshal(raw, &n) == nil
+			v = n
+		}
+		if !ok {
+			fields = append(fields, services.FieldError{Field: k, Message: updateFieldTypes[k]})
+			continue
+		}
+		upd[k] = v
+	}
+	return upd, fields
+}
+
+// menuID resolves the :id path parameter, a numeric id or "key:<menu key>".
+// It writes the problem and returns false when there is no such item.
+func menuID(c *gin.Context) (uint, bool) {
+	id, err := services.LookupMenuID(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, services.ErrInvalidMenuID):
+		invalidID(c)
+		return 0, false
+	case err != nil:
+		serviceError(c, err)
+		return 0, false
+	}
+	return id, true
+}
+
+// menuFields are the writable fields of a menu item, in response order.
+var menuFields = []string{"title", "key", "url", "icon", "parent_id", "order"}
+
+// updateFieldTypes is the type error reported for each updatable field.
+var updateFieldTypes = map[string]string{
+	"title":     "must be a string",
+	"key":       "must be a string",
+	"url":       "must be a string or null",
+	"icon":      "must be a string or null",
+	"parent_id": "must be a menu id or null",
+	"order":     "must be an integer",
+}
+
 // -----------------------------------------------
 
 // GetMenus godoc
@@ -58,42 +184,95 @@ var (
 // @Tags menus
 // @Produce json
 // @Success 200 {object} getMenusResponse
-// @Failure 500 {object} errorResponse
-// @Router /api/menus [get]
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus [get]
 func GetMenus(c *gin.Context) {
-	flat, err := services.GetAllMenusFn(c.Request.Context())
+	tree, err := services.GetMenuTreeFn(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serviceError(c, err)
 		return
 	}
-	tree, _ := services.BuildTree(flat)
 	if tree == nil {
 		tree = []*models.MenuNode{}
 	}
 	c.JSON(http.StatusOK, gin.H{"data": tree})
 }
 
+// ResolveMenu godoc
+// @Summary Find the menu item for a page path
+// @Description Returns the item whose `url` best matches `path` — an exact match, otherwise the longest
+// @Description segment-wise prefix (`/settings` matches `/settings/billing/cards`) — with its ancestors from
+// @Description the root down, so clients can highlight it and expand the tree. Item URLs may contain
+// @Description `:name` segments (`/orders/:id`); their values are returned in `params`.
+// @Tags menus
+// @Produce json
+// @Param path query string true "page path, e.g. /settings/billing"
+// @Success 200 {object} resolveMenuResponse
+// @Failure 400 {object} problem.Problem "invalid_parameter"
+// @Failure 404 {object} problem.Problem "menu_not_found: no item matches"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/resolve [get]
+func ResolveMenu(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		invalidParam(c, "path is required")
+		return
+	}
+	m, err := services.ResolveMenuPathFn(c.Request.Context(), path)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": m})
+}
+
+// GetMenu godoc
+// @Summary Get one menu item
+// @Tags menus
+// @Produce json
+// @Param id path string true "menu id, or key:<menu key>"
+// @Success 200 {object} menuResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "menu_not_found"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/{id} [get]
+func GetMenu(c *gin.Context) {
+	id, ok := menuID(c)
+	if !ok {
+		return
+	}
+	m, err := services.GetMenuFn(c.Request.Context(), id)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": m})
+}
+
 // CreateMenu godoc
 // @Summary Create a menu item
 // @Tags menus
 // @Accept json
 // @Produce json
 // @Param input body createMenuInput true "create menu"
-// @Success 201 {object} models.Menu
-// @Failure 400 {object} errorResponse
-// @Failure 500 {object} errorResponse
-// @Router /api/menus [post]
+// @Param Idempotency-Key header string false "retry-safe key; a retry with the same key and body replays the first response"
+// @Success 201 {object} menuResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "parent_not_found"
+// @Failure 409 {object} problem.Problem "key_conflict"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus [post]
 func CreateMenu(c *gin.Context) {
 	var in createMenuInput
 	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindFailed(c, err)
 		return
 	}
 	m := &models.Menu{
 		Title: in.Title,
-	}
-	if in.URL != nil {
-		m.URL = in.URL
+		Key:   in.Key,
+		URL:   in.URL,
+		Icon:  in.Icon,
 	}
 	if in.ParentID != nil {
 		m.ParentID = in.ParentID
@@ -102,52 +281,60 @@ func CreateMenu(c *gin.Context) {
 		m.Order = *in.Order
 	}
 	if err := services.CreateMenuFn(c.Request.Context(), m); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serviceError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"data": m})
 }
 
 // UpdateMenu godoc
-// @Summary Update menu (partial)
+// @Summary Replace a menu item
+// @Description Full replacement: `title` and `parent_id` (null for the root) are required, and `url` and
+// @Description `icon` are reset to null when omitted. A new `parent_id` or an `order` moves the item like
+// @Description the move endpoint, renumbering its old and new siblings; `order` keeps the current position
+// @Description when omitted (a new parent appends). Use PATCH for partial updates.
 // @Tags menus
 // @Accept json
 // @Produce json
-// @Param id path int true "menu id"
-// @Param input body updateMenuInput true "fields to update"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} errorResponse
-// @Failure 500 {object} errorResponse
-// @Router /api/menus/{id} [put]
+// @Param id path string true "menu id, or key:<menu key>"
+// @Param input body replaceMenuInput true "the whole menu item"
+// @Success 200 {object} menuMutationResponse "the updated item and the siblings a parent or order change renumbered"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "menu_not_found or parent_not_found"
+// @Failure 409 {object} problem.Problem "cycle_detected or key_conflict"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/{id} [put]
 func UpdateMenu(c *gin.Context) {
-	idStr := c.Param("id")
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+	id, ok := menuID(c)
+	if !ok {
 		return
 	}
-	var in map[string]interface{}
+	var in map[string]json.RawMessage
 	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindFailed(c, err)
 		return
 	}
-	// sanitize allowed fields
-	allowed := map[string]bool{"title": true, "url": true, "parent_id": true, "order": true}
-	upd := map[string]interface{}{}
-	for k, v := range in {
-		if allowed[k] {
-			upd[k] = v
+	upd, fields := decodeMenuUpdate(in)
+	for _, k := range []string{"title", "parent_id"} {
+		if _, ok := in[k]; !ok {
+			fields = append(fields, services.FieldError{Field: k, Message: "is required"})
 		}
 	}
-	if len(upd) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no updatable fields provided"})
+	if len(fields) > 0 {
+		validationFailed(c, fields)
 		return
 	}
-	if err := services.UpdateMenuFn(c.Request.Context(), uint(id64), upd); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	for _, k := range []string{"url", "icon"} {
+		if _, ok := upd[k]; !ok {
+			upd[k] = nil
+		}
+	}
+	siblings, err := services.UpdateMenuFn(c.Request.Context(), id, upd)
+	if err != nil {
+		serviceError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "updated"})
+	respondMenu(c, id, "updated", siblings)
 }
 
 // ReorderMenu godoc
@@ -155,35 +342,35 @@ func UpdateMenu(c *gin.Context) {
 // @Tags menus
 // @Accept json
 // @Produce json
-// @Param id path int true "menu id"
+// @Param id path string true "menu id, or key:<menu key>"
 // @Param input body reorderInput true "new order"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} errorResponse
-// @Failure 500 {object} errorResponse
-// @Router /api/menus/{id}/reorder [patch]
+// @Success 200 {object} menuMutationResponse "the reordered item and the renumbered siblings"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "menu_not_found"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/{id}/reorder [patch]
 func ReorderMenu(c *gin.Context) {
-	idStr := c.Param("id")
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+	id, ok := menuID(c)
+	if !ok {
 		return
 	}
 	var in struct {
 		NewOrder *int `json:"new_order"`
 	}
 	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindFailed(c, err)
 		return
 	}
 	if in.NewOrder == nil || *in.NewOrder < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "new_order is required and must be >= 0"})
+		validationFailed(c, []services.FieldError{{Field: "new_order", Message: "is required and must be >= 0"}})
 		return
 	}
-	if err := services.ReorderMenuFn(c.Request.Context(), uint(id64), *in.NewOrder); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	siblings, err := services.ReorderMenuFn(c.Request.Context(), id, *in.NewOrder)
+	if err != nil {
+		serviceError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "reordered"})
+	respondMenu(c, id, "reordered", siblings)
 }
 
 // MoveMenu godoc
@@ -191,17 +378,18 @@ func ReorderMenu(c *gin.Context) {
 // @Tags menus
 // @Accept json
 // @Produce json
-// @Param id path int true "menu id"
+// @Param id path string true "menu id, or key:<menu key>"
 // @Param input body moveInput true "new parent and/or order"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} errorResponse
-// @Failure 500 {object} errorResponse
-// @Router /api/menus/{id}/move [patch]
+// @Param Idempotency-Key header string false "retry-safe key; a retry with the same key and body replays the first response"
+// @Success 200 {object} menuMutationResponse "the moved item and the renumbered siblings (source and destination parent)"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "menu_not_found"
+// @Failure 409 {object} problem.Problem "cycle_detected"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/{id}/move [patch]
 func MoveMenu(c *gin.Context) {
-	idStr := c.Param("id")
-	id64, err := strconv.ParseUint(idStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+	id, ok := menuID(c)
+	if !ok {
 		return
 	}
 	var in struct {
@@ -209,38 +397,226 @@ func MoveMenu(c *gin.Context) {
 		NewOrder    *int  `json:"new_order"`
 	}
 	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		bindFailed(c, err)
 		return
 	}
 	if in.NewOrder != nil && *in.NewOrder < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "new_order must be >= 0"})
+		validationFailed(c, []services.FieldError{{Field: "new_order", Message: "must be >= 0"}})
 		return
 	}
-	if err := services.MoveMenuFn(c.Request.Context(), uint(id64), in.NewParentID, in.NewOrder); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	siblings, err := services.MoveMenuFn(c.Request.Context(), id, in.NewParentID, in.NewOrder)
+	if err != nil {
+		serviceError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "moved"})
+	respondMenu(c, id, "moved", siblings)
 }
 
 // DeleteMenu godoc
 // @Summary Delete menu item (recursive)
 // @Tags menus
-// @Param id path int true "menu id"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} errorResponse
-// @Failure 500 {object} errorResponse
-// @Router /api/menus/{id} [delete]
+// @Param id path string true "menu id, or key:<menu key>"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "menu_not_found"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/{id} [delete]
 func DeleteMenu(c *gin.Context) {
-	idStr := c.Param("id")
-	id64, err := strconv.ParseUint(idStr, 10, 64)
+	id, ok := menuID(c)
+	if !ok {
+		return
+	}
+	if err := services.DeleteMenuRecursiveFn(c.Request.Context(), id); err != nil {
+		serviceError(c, err)
+		return
+	}
+	respondDeleted(c)
+}
+
+// DuplicateMenu godoc
+// @Summary Deep-copy a menu item and its descendants
+// @Description Copies get new ids and keep their relative order. Omit `new_parent_id` to place the copy
+// @Description right after the original; `null` places it at the root. `new_order` is the index among
+// @Description the destination's children (appends when omitted).
+// @Tags menus
+// @Accept json
+// @Produce json
+// @Param id path string true "menu id, or key:<menu key>"
+// @Param input body duplicateInput false "destination and title suffix"
+// @Param Idempotency-Key header string false "retry-safe key; a retry with the same key and body replays the first response"
+// @Success 201 {object} menuResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "menu_not_found or parent_not_found"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/{id}/duplicate [post]
+func DuplicateMenu(c *gin.Context) {
+	id, ok := menuID(c)
+	if !ok {
+		return
+	}
+	// new_parent_id stays raw so "absent" (next to the source) and null (root) differ
+	var in struct {
+		NewParentID json.RawMessage `json:"new_parent_id"`
+		NewOrder    *int            `json:"new_order"`
+		TitleSuffix string          `json:"title_suffix"`
+	}
+	if err := c.ShouldBindJSON(&in); err != nil && !errors.Is(err, io.EOF) {
+		bindFailed(c, err)
+		return
+	}
+	if in.NewOrder != nil && *in.NewOrder < 0 {
+		validationFailed(c, []services.FieldError{{Field: "new_order", Message: "must be >= 0"}})
+		return
+	}
+	opts := services.DuplicateOptions{Position: in.NewOrder, TitleSuffix: in.TitleSuffix}
+	if len(in.NewParentID) == 0 {
+		opts.NextToSource = true
+	} else if err := json.Unmarshal(in.NewParentID, &opts.ParentID); err != nil {
+		validationFailed(c, []services.FieldError{{Field: "new_parent_id", Message: "must be a menu id or null"}})
+		return
+	}
+	m, err := services.DuplicateMenuFn(c.Request.Context(), id, opts)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": m})
+}
+
+// SetChildOrder godoc
+// @Summary Set the complete child order of a parent
+// @Description `ids` must be exactly the parent's current children, in the desired order; otherwise 409.
+// @Tags menus
+// @Accept json
+// @Produce json
+// @Param id path string true "parent menu id, or key:<menu key>"
+// @Param input body childOrderInput true "ordered child ids"
+// @Success 200 {object} menuNodesResponse "the children in their new order"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 409 {object} problem.Problem "child_set_mismatch"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/{id}/children/order [put]
+func SetChildOrder(c *gin.Context) {
+	id, ok := menuID(c)
+	if !ok {
+		return
+	}
+	setChildOrder(c, &id)
+}
+
+// SetRootOrder godoc
+// @Summary Set the complete order of the root items
+// @Description `ids` must be exactly the current root items, in the desired order; otherwise 409.
+// @Tags menus
+// @Accept json
+// @Produce json
+// @Param input body childOrderInput true "ordered root ids"
+// @Success 200 {object} menuNodesResponse "the root items in their new order"
+// @Failure 400 {object} problem.Problem
+// @Failure 409 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/root/children/order [put]
+func SetRootOrder(c *gin.Context) {
+	setChildOrder(c, nil)
+}
+
+func setChildOrder(c *gin.Context, parentID *uint) {
+	var in childOrderInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	if err := services.SetChildOrderFn(c.Request.Context(), parentID, in.IDs); err != nil {
+		serviceError(c, err)
+		return
+	}
+	if legacyAPI(c) {
+		c.JSON(http.StatusOK, gin.H{"message": "reordered"})
+		return
+	}
+	children, err := childrenOf(c, parentID)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	if children == nil {
+		children = []*models.MenuNode{}
+	}
+	respondData(c, http.StatusOK, children)
+}
+
+// ListMenusFlat godoc
+// @Summary List menu rows (flat) with filters and cursor pagination
+// @Description Pass `next_cursor` from the previous page as `cursor` (with the same sort) to continue.
+// @Tags menus
+// @Produce json
+// @Param parent_id query string false "parent id, or `null` for root items"
+// @Param title_prefix query string false "title starts with"
+// @Param updated_since query string false "RFC 3339 timestamp; updated_at >= value"
+// @Param has_url query bool false "only items with (true) or without (false) a url"
+// @Param sort query string false "order (default), title or updated_at; prefix with - for descending"
+// @Param limit query int false "page size (default 50, max 500)"
+// @Param cursor query string false "cursor from the previous page"
+// @Param include_total query bool false "include the total number of matches"
+// @Success 200 {object} flatMenusResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/flat [get]
+func ListMenusFlat(c *gin.Context) {
+	q := services.FlatQuery{
+		TitlePrefix: c.Query("title_prefix"),
+		Cursor:      c.Query("cursor"),
+		Sort:        strings.TrimPrefix(c.Query("sort"), "-"),
+		Desc:        strings.HasPrefix(c.Query("sort"), "-"),
+	}
+	if v := c.Query("parent_id"); v == "null" {
+		q.RootsOnly = true
+	} else if v != "" {
+		id64, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			invalidParam(c, "parent_id must be an id or null")
+			return
+		}
+		pid := uint(id64)
+		q.ParentID = &pid
+	}
+	if v := c.Query("updated_since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			invalidParam(c, "updated_since must be an RFC 3339 timestamp")
+			return
+		}
+		q.UpdatedSince = &t
+	}
+	if v := c.Query("has_url"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			invalidParam(c, "has_url must be true or false")
+			return
+		}
+		q.HasURL = &b
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			invalidParam(c, "limit must be a positive integer")
+			return
+		}
+		q.Limit = n
+	}
+	if v := c.Query("include_total"); v != "" {
+		q.IncludeTotal, _ = strconv.ParseBool(v)
+	}
+
+	page, err := services.ListMenusFlatFn(c.Request.Context(), q)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		serviceError(c, err)
 		return
 	}
-	if err := services.DeleteMenuRecursiveFn(c.Request.Context(), uint(id64)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if legacyAPI(c) {
+		c.JSON(http.StatusOK, page)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+	c.JSON(http.StatusOK, flatMenusResponse{Data: page.Items, Meta: flatMenusMeta{NextCursor: page.NextCursor, Total: page.Total}})
 }
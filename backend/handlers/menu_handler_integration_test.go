@@ -31,7 +31,7 @@ func setupInMemoryDB(t *testing.T) {
 		t.Fatalf("failed to open sqlite in-memory: %v", err)
 	}
 	config.DB = db
-	if err := config.DB.AutoMigrate(&models.Menu{}); err != nil {
+	if err := config.DB.AutoMigrate(&models.Menu{}, &models.MenuChange{}); err != nil {
 		t.Fatalf("migrate failed: %v", err)
 	}
 }
@@ -297,7 +297,7 @@ func TestUpdateMenu_Success_viaHTTP(t *testing.T) {
 	id := int(res["data"].(map[string]any)["id"].(float64))
 
 	// update
-	update := map[string]interface{}{"title": "updated"}
+	update := map[string]interface{}{"title": "updated", "parent_id": nil}
 	b, _ = json.Marshal(update)
 	rec = httptest.NewRecorder()
 	req = httptest.NewRequest(http.MethodPut, "/api/menus/"+strconv.Itoa(id), bytes.NewReader(b))
@@ -334,7 +334,7 @@ func TestGetMenus_EmptyList_returns200_withEmptyArray(t *testing.T) {
 	require.Len(t, data, 0)
 }
 
-func TestMoveMenu_Handler_returns500_whenMovingIntoDescendant(t *testing.T) {
+func TestMoveMenu_Handler_returns409_whenMovingIntoDescendant(t *testing.T) {
 	setupInMemoryDB(t)
 	defer config.CloseDB()
 
@@ -361,7 +361,8 @@ func TestMoveMenu_Handler_returns500_whenMovingIntoDescendant(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPatch, "/api/menus/"+strconv.Itoa(int(a.ID))+"/move", body)
 	req.Header.Set("Content-Type", "application/json")
 	r.ServeHTTP(rec, req)
-	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"cycle_detected"`)
 }
 
 func TestDeleteMenu_ServiceError_returns500_sqlmock(t *testing.T) {
@@ -381,7 +382,9 @@ func TestReorderMenu_ServiceError_returns500_sqlmock(t *testing.T) {
 	// keeps handler-level tests deterministic and avoids SQL-level brittleness.
 	orig := services.ReorderMenuFn
 	defer func() { services.ReorderMenuFn = orig }()
-	services.ReorderMenuFn = func(ctx context.Context, id uint, newOrder int) error { return fmt.Errorf("boom") }
+	services.ReorderMenuFn = func(ctx context.Context, id uint, newOrder int) ([]services.SiblingOrder, error) {
+		return nil, fmt.Errorf("boom")
+	}
 
 	r := routes.SetupRouter()
 	rec := httptest.NewRecorder()
@@ -394,7 +397,9 @@ func TestReorderMenu_ServiceError_returns500_sqlmock(t *testing.T) {
 func TestMoveMenu_Handler_sqlExecError_returns500_sqlmock(t *testing.T) {
 	orig := services.MoveMenuFn
 	defer func() { services.MoveMenuFn = orig }()
-	services.MoveMenuFn = func(ctx context.Context, id uint, newParentID *uint, newOrder *int) error { return fmt.Errorf("boom") }
+	services.MoveMenuFn = func(ctx context.Context, id uint, newParentID *uint, newOrder *int) ([]services.SiblingOrder, error) {
+		return nil, fmt.Errorf("boom")
+	}
 
 	r := routes.SetupRouter()
 	rec := httptest.NewRecorder()
@@ -423,10 +428,12 @@ func TestCreateMenu_ServiceError_returns500_sqlmock(t *testing.T) {
 func TestUpdateMenu_ServiceError_returns500_sqlmock(t *testing.T) {
 	orig := services.UpdateMenuFn
 	defer func() { services.UpdateMenuFn = orig }()
-	services.UpdateMenuFn = func(ctx context.Context, id uint, upd map[string]interface{}) error { return fmt.Errorf("boom") }
+	services.UpdateMenuFn = func(ctx context.Context, id uint, upd map[string]interface{}) ([]services.SiblingOrder, error) {
+		return nil, fmt.Errorf("boom")
+	}
 
 	r := routes.SetupRouter()
-	update := map[string]interface{}{"title": "updated"}
+	update := map[string]interface{}{"title": "updated", "parent_id": nil}
 	b, _ := json.Marshal(update)
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPut, "/api/menus/1", bytes.NewReader(b))
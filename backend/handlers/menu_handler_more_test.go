@@ -2,15 +2,19 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/galpt/sotekre/backend/config"
 	"github.com/galpt/sotekre/backend/models"
 	"github.com/galpt/sotekre/backend/routes"
+	"github.com/galpt/sotekre/backend/services"
 	"github.com/stretchr/testify/require"
 )
 
@@ -43,7 +47,7 @@ func TestUpdateMenu_updateParentAndOrder_viaHTTP(t *testing.T) {
 	config.DB.Create(&child)
 
 	r := routes.SetupRouter()
-	update := map[string]interface{}{"parent_id": parent.ID, "order": 5}
+	update := map[string]interface{}{"title": "C", "parent_id": parent.ID, "order": 5}
 	b, _ := json.Marshal(update)
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPut, "/api/menus/"+strconv.Itoa(int(child.ID)), bytes.NewReader(b))
@@ -54,5 +58,584 @@ func TestUpdateMenu_updateParentAndOrder_viaHTTP(t *testing.T) {
 	var got models.Menu
 	require.NoError(t, config.DB.First(&got, child.ID).Error)
 	require.NotNil(t, got.ParentID)
-	require.Equal(t, 5, got.Order)
+	require.Equal(t, 0, got.Order, "order is a position among the new siblings")
+}
+
+func TestDuplicateMenu_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	src := models.Menu{Title: "Products"}
+	config.DB.Create(&src)
+	config.DB.Create(&models.Menu{Title: "Phones", ParentID: &src.ID})
+
+	r := routes.SetupRouter()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/menus/"+strconv.Itoa(int(src.ID))+"/duplicate", bytes.NewReader([]byte(`{"new_parent_id": null, "title_suffix": " copy"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var res map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(t, "Products copy", res["data"].(map[string]any)["title"])
+
+	var n int64
+	config.DB.Model(&models.Menu{}).Count(&n)
+	require.Equal(t, int64(4), n)
+
+	// empty body: copy next to the source; unknown id: 404
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/menus/"+strconv.Itoa(int(src.ID))+"/duplicate", nil)
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/menus/9999/duplicate", nil)
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSetChildOrder_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	a := models.Menu{Title: "A", Order: 0}
+	b := models.Menu{Title: "B", Order: 1}
+	config.DB.Create(&a)
+	config.DB.Create(&b)
+	child := models.Menu{Title: "A1", ParentID: &a.ID}
+	config.DB.Create(&child)
+
+	r := routes.SetupRouter()
+	put := func(path, body string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, put("/api/menus/root/children/order", fmt.Sprintf(`{"ids":[%d,%d]}`, b.ID, a.ID)))
+	var got models.Menu
+	require.NoError(t, config.DB.First(&got, b.ID).Error)
+	require.Equal(t, 0, got.Order)
+
+	require.Equal(t, http.StatusOK, put(fmt.Sprintf("/api/menus/%d/children/order", a.ID), fmt.Sprintf(`{"ids":[%d]}`, child.ID)))
+	require.Equal(t, http.StatusConflict, put("/api/menus/root/children/order", fmt.Sprintf(`{"ids":[%d]}`, a.ID)))
+	require.Equal(t, http.StatusBadRequest, put("/api/menus/root/children/order", fmt.Sprintf(`{"ids":[%d,%d]}`, a.ID, a.ID)))
+	require.Equal(t, http.StatusNotFound, put("/api/menus/9999/children/order", `{"ids":[]}`))
+	require.Equal(t, http.StatusBadRequest, put("/api/menus/x/children/order", `{"ids":[]}`))
+}
+
+func TestListMenusFlat_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	root := models.Menu{Title: "Root"}
+	config.DB.Create(&root)
+	config.DB.Create(&models.Menu{Title: "Child", ParentID: &root.ID})
+
+	r := routes.SetupRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/menus/flat?parent_id=null&include_total=true")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var res map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res["data"].([]any), 1)
+	require.Equal(t, float64(1), res["total"])
+
+	rec = get("/api/menus/flat?sort=-title&limit=1")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(t, "Root", res["data"].([]any)[0].(map[string]any)["title"])
+	next := res["next_cursor"].(string)
+	rec = get("/api/menus/flat?sort=-title&limit=1&cursor=" + next)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Equal(t, "Child", res["data"].([]any)[0].(map[string]any)["title"])
+
+	for _, bad := range []string{"parent_id=x", "updated_since=yesterday", "has_url=maybe", "limit=0", "sort=id", "cursor=zzz"} {
+		require.Equal(t, http.StatusBadRequest, get("/api/menus/flat?"+bad).Code, bad)
+	}
+}
+
+func TestMenuChanges_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	r := routes.SetupRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/menus", bytes.NewBufferString(`{"title":"Home"}`)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = get("/api/menus/changes?since=0")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var set struct {
+		Upserted []models.Menu `json:"upserted"`
+		Deleted  []uint        `json:"deleted"`
+		Cursor   uint64        `json:"cursor"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &set))
+	require.Len(t, set.Upserted, 1)
+	require.Equal(t, uint64(1), set.Cursor)
+
+	require.Equal(t, http.StatusBadRequest, get("/api/menus/changes?since=-1").Code)
+	rec = get("/api/menus/changes?since=42")
+	require.Equal(t, http.StatusGone, rec.Code)
+	require.Contains(t, rec.Body.String(), `"cursor":1`)
+}
+
+func TestHealthProbes_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	r := routes.SetupRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, get("/healthz").Code)
+	rec := get("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code, "webhook tables are not migrated")
+	require.Contains(t, rec.Body.String(), `"migrations":"pending"`)
+	require.NotContains(t, rec.Body.String(), "webhook_subscriptions")
+
+	require.NoError(t, config.DB.AutoMigrate(models.All()...))
+	rec = get("/readyz")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Body.String(), `"saturation"`)
+}
+
+func TestMutatingRoutes_bodyCapped(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	t.Setenv("MAX_BODY_BYTES", "64")
+	r := routes.SetupRouter()
+
+	rec := httptest.NewRecorder()
+	body := `{"title":"` + strings.Repeat("x", 100) + `"}`
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/menus", strings.NewReader(body)))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestMenuValidation_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	r := routes.SetupRouter()
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	fields := func(rec *httptest.ResponseRecorder) map[string]string {
+		var res struct {
+			Code   string `json:"code"`
+			Errors []struct{ Field, Message string }
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res), rec.Body.String())
+		require.Equal(t, "validation_failed", res.Code)
+		out := map[string]string{}
+		for _, f := range res.Errors {
+			out[f.Field] = f.Message
+		}
+		return out
+	}
+
+	rec := send(http.MethodPost, "/api/menus", `{"title":"x","url":"javascript:alert(1)"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, fields(rec)["url"], "not allowed")
+
+	rec = send(http.MethodPost, "/api/menus", `{"title":"`+strings.Repeat("t", 256)+`","order":"1"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "must be an integer", fields(rec)["order"])
+
+	rec = send(http.MethodPost, "/api/menus", `{"title":"   "}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, "is required", fields(rec)["title"])
+
+	rec = send(http.MethodPost, "/api/menus", `{"title":" Orders ","url":"/orders/:id","icon":"box"}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created struct{ Data models.Menu }
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Equal(t, "Orders", created.Data.Title)
+	path := "/api/menus/" + strconv.Itoa(int(created.Data.ID))
+
+	rec = send(http.MethodPut, path, `{"order":"x","parent_id":"1","title":null}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, map[string]string{
+		"order":     "must be an integer",
+		"parent_id": "must be a menu id or null",
+		"title":     "must be a string",
+	}, fields(rec))
+
+	rec = send(http.MethodPut, path, `{"title":"`+strings.Repeat("t", 256)+`","parent_id":null}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, fields(rec), "title")
+
+	rec = send(http.MethodPut, path, `{"title":"Orders","icon":" star ","parent_id":null}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var got models.Menu
+	require.NoError(t, config.DB.First(&got, created.Data.ID).Error)
+	require.Nil(t, got.URL)
+	require.Equal(t, "star", *got.Icon)
+}
+
+func TestErrors_areProblemJSON(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	r := routes.SetupRouter()
+	send := func(method, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", "req-42")
+		r.ServeHTTP(rec, req)
+		require.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"), rec.Body.String())
+		var p map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+		require.Equal(t, "req-42", p["request_id"])
+		require.Equal(t, float64(rec.Code), p["status"])
+		return rec, p
+	}
+
+	rec, p := send(http.MethodPatch, "/api/menus/999/reorder", `{"new_order":0}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "menu_not_found", p["code"])
+	require.Equal(t, "/api/menus/999/reorder", p["instance"])
+
+	_, p = send(http.MethodDelete, "/api/menus/abc", "")
+	require.Equal(t, "invalid_id", p["code"])
+
+	_, p = send(http.MethodPost, "/api/menus", `{"title":`)
+	require.Equal(t, "malformed_body", p["code"])
+
+	rec, p = send(http.MethodGet, "/api/nope", "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "route_not_found", p["code"])
+
+	rec, p = send(http.MethodGet, "/api/menus/changes?since=42", "")
+	require.Equal(t, http.StatusGone, rec.Code)
+	require.Equal(t, "changes_expired", p["code"])
+	require.Contains(t, p, "cursor")
+
+	// storage errors are logged, not echoed
+	orig := services.GetMenuTreeFn
+	defer func() { services.GetMenuTreeFn = orig }()
+	services.GetMenuTreeFn = func(context.Context) ([]*models.MenuNode, error) {
+		return nil, fmt.Errorf("Error 1146: Table 'sotekre.menus' doesn't exist")
+	}
+	rec, p = send(http.MethodGet, "/api/menus", "")
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, "internal_error", p["code"])
+	require.NotContains(t, rec.Body.String(), "1146")
+}
+
+func TestPatchMenu_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	parent := models.Menu{Title: "P"}
+	require.NoError(t, config.DB.Create(&parent).Error)
+	url, icon := "/orders", "box"
+	item := models.Menu{Title: "Orders", URL: &url, Icon: &icon, ParentID: &parent.ID, Order: 2}
+	require.NoError(t, config.DB.Create(&item).Error)
+	path := "/api/menus/" + strconv.Itoa(int(item.ID))
+
+	r := routes.SetupRouter()
+	patch := func(contentType, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	reload := func() models.Menu {
+		var got models.Menu
+		require.NoError(t, config.DB.First(&got, item.ID).Error)
+		return got
+	}
+
+	// merge patch: null clears, absent keeps
+	rec := patch("application/merge-patch+json", `{"url":null,"title":"Orders!"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Header().Get("Accept-Patch"), "application/json-patch+json")
+	got := reload()
+	require.Nil(t, got.URL)
+	require.Equal(t, "Orders!", got.Title)
+	require.Equal(t, "box", *got.Icon)
+	require.Equal(t, parent.ID, *got.ParentID)
+	require.Equal(t, 2, got.Order)
+
+	// null parent_id moves to the root; title cannot be cleared
+	require.Equal(t, http.StatusOK, patch("application/merge-patch+json", `{"parent_id":null}`).Code)
+	require.Nil(t, reload().ParentID)
+	rec = patch("application/merge-patch+json", `{"title":null,"extra":1}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `"field":"extra"`)
+	require.Contains(t, rec.Body.String(), `"field":"title","message":"cannot be removed"`)
+
+	// JSON Patch, including test as a precondition
+	rec = patch("application/json-patch+json", `[{"op":"test","path":"/title","value":"Orders!"},{"op":"replace","path":"/url","value":"/o"},{"op":"remove","path":"/icon"}]`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	got = reload()
+	require.Equal(t, "/o", *got.URL)
+	require.Nil(t, got.Icon)
+	rec = patch("application/json-patch+json", `[{"op":"test","path":"/title","value":"stale"},{"op":"replace","path":"/title","value":"x"}]`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"patch_test_failed"`)
+	require.Equal(t, http.StatusUnprocessableEntity, patch("application/json-patch+json", `[{"op":"remove","path":"/nope/x"}]`).Code)
+	require.Equal(t, http.StatusBadRequest, patch("application/json-patch+json", `{"op":"remove"}`).Code)
+
+	// validated like any other write
+	rec = patch("application/merge-patch+json", `{"url":"javascript:alert(1)"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `"field":"url"`)
+	rec = patch("application/merge-patch+json", fmt.Sprintf(`{"parent_id":%d}`, item.ID))
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"cycle_detected"`)
+	rec = patch("application/merge-patch+json", `{"parent_id":9999}`)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"parent_not_found"`)
+
+	require.Equal(t, http.StatusUnsupportedMediaType, patch("text/plain", `{}`).Code)
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/api/menus/9999", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestUpdateMenu_putReplacesWholeItem_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	parent := models.Menu{Title: "P"}
+	require.NoError(t, config.DB.Create(&parent).Error)
+	url, icon := "/x", "star"
+	item := models.Menu{Title: "X", URL: &url, Icon: &icon, ParentID: &parent.ID, Order: 3}
+	require.NoError(t, config.DB.Create(&item).Error)
+	path := "/api/menus/" + strconv.Itoa(int(item.ID))
+
+	r := routes.SetupRouter()
+	put := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := put(`{"url":"/y"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `"field":"title","message":"is required"`)
+
+	rec = put(`{"title":"Y"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `"field":"parent_id","message":"is required"`)
+
+	require.Equal(t, http.StatusOK, put(`{"title":"Y","parent_id":`+strconv.Itoa(int(parent.ID))+`}`).Code)
+	var got models.Menu
+	require.NoError(t, config.DB.First(&got, item.ID).Error)
+	require.Equal(t, "Y", got.Title)
+	require.Nil(t, got.URL, "omitted url is cleared")
+	require.Nil(t, got.Icon, "omitted icon is cleared")
+	require.Equal(t, parent.ID, *got.ParentID)
+	require.Equal(t, 3, got.Order, "omitted order keeps the position")
+
+	require.Equal(t, http.StatusOK, put(`{"title":"Y","parent_id":null}`).Code)
+	require.NoError(t, config.DB.First(&got, item.ID).Error)
+	require.Nil(t, got.ParentID)
+	require.Equal(t, 1, got.Order, "a new parent without order appends after P")
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/menus/9999", strings.NewReader(`{"title":"Z","parent_id":null}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCreateMenu_idempotencyKey_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	r := routes.SetupRouter()
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/menus", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "create-home-1")
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post(`{"title":"Home"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	retry := post(`{"title":"Home"}`)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.JSONEq(t, first.Body.String(), retry.Body.String())
+	var n int64
+	require.NoError(t, config.DB.Model(&models.Menu{}).Count(&n).Error)
+	require.Equal(t, int64(1), n, "the retry did not create a duplicate")
+
+	rec := post(`{"title":"Other"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+}
+
+func TestMenuKeyAddressing_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	r := routes.SetupRouter()
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/menus", `{"title":"Billing & Invoices"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Contains(t, rec.Body.String(), `"key":"billing-invoices"`)
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/menus", `{"title":"Help","key":"help"}`).Code)
+
+	rec = do(http.MethodGet, "/api/menus/key:billing-invoices", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"key":"billing-invoices"`)
+
+	rec = do(http.MethodPatch, "/api/menus/key:billing-invoices", `{"key":"billing"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = do(http.MethodPatch, "/api/menus/key:billing/move", `{"new_parent_id":null,"new_order":1}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = do(http.MethodPatch, "/api/menus/key:help", `{"key":"billing"}`)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"key_conflict"`)
+	rec = do(http.MethodPatch, "/api/menus/key:help", `{"key":null}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `"field":"key","message":"cannot be removed"`)
+
+	rec = do(http.MethodGet, "/api/menus/key:billing-invoices", "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"menu_not_found"`)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/api/menus/abc", "").Code)
+}
+
+func TestResolveMenu_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	settingsURL, billingURL := "/settings", "/settings/billing"
+	settings := models.Menu{Title: "Settings", URL: &settingsURL}
+	require.NoError(t, config.DB.Create(&settings).Error)
+	billing := models.Menu{Title: "Billing", URL: &billingURL, ParentID: &settings.ID}
+	require.NoError(t, config.DB.Create(&billing).Error)
+	services.InvalidateMenuTree()
+
+	r := routes.SetupRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/menus/resolve?path=/settings/billing/cards")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var body struct {
+		Data services.MenuMatch `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, billing.ID, body.Data.Item.ID)
+	require.False(t, body.Data.Exact)
+	require.Len(t, body.Data.Ancestors, 1)
+	require.Equal(t, settings.ID, body.Data.Ancestors[0].ID)
+
+	require.Equal(t, http.StatusBadRequest, get("/api/menus/resolve").Code)
+	rec = get("/api/menus/resolve?path=/orders")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"menu_not_found"`)
+}
+
+func TestRenderMenus_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	settingsURL, billingURL := "/settings", "/settings/billing"
+	settings := models.Menu{Title: "Settings", URL: &settingsURL}
+	require.NoError(t, config.DB.Create(&settings).Error)
+	billing := models.Menu{Title: "Billing", URL: &billingURL, ParentID: &settings.ID}
+	require.NoError(t, config.DB.Create(&billing).Error)
+	services.InvalidateMenuTree()
+
+	r := routes.SetupRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/menus/render?path=/settings/billing/cards&list_class=nav&active_class=active")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), `<ul class="nav">`)
+	require.Contains(t, rec.Body.String(), `<li class="active"><a href="/settings/billing" aria-current="page">Billing</a></li>`)
+
+	rec = get("/api/menus/render?format=md&current=key:settings")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "text/markdown; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Equal(t, "- **[Settings](/settings)**\n  - [Billing](/settings/billing)\n", rec.Body.String())
+
+	require.Equal(t, http.StatusBadRequest, get("/api/menus/render?format=pdf").Code)
+	require.Equal(t, http.StatusNotFound, get("/api/menus/render?current=key:nope").Code)
+}
+
+func TestSitemapAndBreadcrumbs_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	t.Setenv("SITE_BASE_URL", "https://shop.example.com")
+	settingsURL, billingURL, external := "/settings", "/settings/billing", "https://github.com/galpt"
+	settings := models.Menu{Title: "Settings", URL: &settingsURL}
+	require.NoError(t, config.DB.Create(&settings).Error)
+	billing := models.Menu{Title: "Billing", URL: &billingURL, ParentID: &settings.ID}
+	require.NoError(t, config.DB.Create(&billing).Error)
+	require.NoError(t, config.DB.Create(&models.Menu{Title: "GitHub", URL: &external}).Error)
+	services.InvalidateMenuTree()
+
+	r := routes.SetupRouter()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/sitemap.xml")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "<loc>https://shop.example.com/settings/billing</loc>")
+	require.Contains(t, rec.Body.String(), "<lastmod>")
+	require.NotContains(t, rec.Body.String(), "github.com")
+
+	rec = get("/api/menus/key:billing/breadcrumbs.jsonld")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Equal(t, "application/ld+json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"@context":"https://schema.org","@type":"BreadcrumbList","itemListElement":[
+		{"@type":"ListItem","position":1,"name":"Settings","item":"https://shop.example.com/settings"},
+		{"@type":"ListItem","position":2,"name":"Billing","item":"https://shop.example.com/settings/billing"}]}`, rec.Body.String())
+	require.Equal(t, http.StatusNotFound, get("/api/menus/9999/breadcrumbs.jsonld").Code)
+
+	// without SITE_BASE_URL the Host header is not trusted: fail closed
+	for _, base := range []string{"", "shop.example.com"} {
+		t.Setenv("SITE_BASE_URL", base)
+		req := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+		req.Host = "evil.example"
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.Contains(t, rec.Body.String(), `"code":"site_url_not_configured"`)
+		require.NotContains(t, rec.Body.String(), "evil.example")
+		require.Equal(t, http.StatusServiceUnavailable, get("/api/menus/key:billing/breadcrumbs.jsonld").Code)
+	}
 }
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Patch formats accepted by PatchMenu (advertised in Accept-Patch).
+const (
+	mergePatchType = "application/merge-patch+json" // RFC 7396
+	jsonPatchType  = "application/json-patch+json"  // RFC 6902
+)
+
+// menuDocument is the writable part of a menu item: the document a PATCH
+// applies to.
+type menuDocument struct {
+	Title    string  `json:"title"`
+	Key      string  `json:"key"`
+	URL      *string `json:"url"`
+	Icon     *string `json:"icon"`
+	ParentID *uint   `json:"parent_id"`
+	Order    int     `json:"order"`
+}
+
+// jsonPatchOp documents one RFC 6902 operation (swag only).
+type jsonPatchOp struct {
+	Op    string      `json:"op" example:"replace"`
+	Path  string      `json:"path" example:"/url"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+var _ = (*jsonPatchOp)(nil)
+
+func documentOf(m *models.Menu) menuDocument {
+	return menuDocument{Title: m.Title, Key: m.Key, URL: m.URL, Icon: m.Icon, ParentID: m.ParentID, Order: m.Order}
+}
+
+// patchError is a patch that cannot be applied; PatchMenu writes its
+// problem.
+type patchError struct {
+	problem.Problem
+}
+
+func (e *patchError) Error() string { return e.Detail }
+
+func newPatchError(status int, code, detail string) error {
+	return &patchError{problem.New(status, code, detail)}
+}
+
+// applyPatch applies body, in the format named by contentType, to doc.
+func applyPatch(contentType string, doc, body []byte) ([]byte, error) {
+	switch contentType {
+	case mergePatchType, "application/json":
+		out, err := jsonpatch.MergePatch(doc, body)
+		if err != nil {
+			return nil, newPatchError(http.StatusBadRequest, problem.CodeMalformedBody, "request body must be a JSON merge patch")
+		}
+		return out, nil
+	case jsonPatchType:
+		p, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, newPatchError(http.StatusBadRequest, problem.CodeMalformedBody, "request body must be a JSON Patch array")
+		}
+		out, err := p.Apply(doc)
+		switch {
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			return nil, newPatchError(http.StatusConflict, problem.CodePatchTestFailed, err.Error())
+		case err != nil:
+			return nil, newPatchError(http.StatusUnprocessableEntity, problem.CodePatchNotApplied, err.Error())
+		}
+		return out, nil
+	}
+	return nil, newPatchError(http.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia,
+		"PATCH accepts "+mergePatchType+" or "+jsonPatchType)
+}
+
+// patchUpdate applies body to cur's document and returns the fields the
+// patch changed, as an update for services.PatchMenu.
+func patchUpdate(contentType string, cur *models.Menu, body []byte) (map[string]interface{}, error) {
+	doc, err := json.Marshal(documentOf(cur))
+	if err != nil {
+		return nil, err
+	}
+	patched, err := applyPatch(contentType, doc, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &out); err != nil {
+		return nil, newPatchError(http.StatusUnprocessableEntity, problem.CodePatchNotApplied, "patched document must be a JSON object")
+	}
+	upd, fields := decodeMenuUpdate(out)
+	var unknown []string
+	for k := range out {
+		if _, known := updateFieldTypes[k]; !known {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		fields = append(fields, services.FieldError{Field: k, Message: "is not a writable field"})
+	}
+	for _, k := range menuFields {
+		if _, ok := out[k]; ok {
+			continue
+		}
+		switch k {
+		case "title", "key", "order":
+			fields = append(fields, services.FieldError{Field: k, Message: "cannot be removed"})
+		default:
+			upd[k] = nil
+		}
+	}
+	if len(fields) > 0 {
+		return nil, &services.ValidationError{Fields: fields}
+	}
+
+	// write only what the patch changed
+	if t, _ := upd["title"].(string); t == cur.Title {
+		delete(upd, "title")
+	}
+	if k, _ := upd["key"].(string); k == cur.Key {
+		delete(upd, "key")
+	}
+	if n, _ := upd["order"].(int); n == cur.Order {
+		delete(upd, "order")
+	}
+	for _, k := range []string{"url", "icon"} {
+		s, _ := upd[k].(*string)
+		was := cur.URL
+		if k == "icon" {
+			was = cur.Icon
+		}
+		if (s == nil && was == nil) || (s != nil && was != nil && *s == *was) {
+			delete(upd, k)
+		}
+	}
+	if p, _ := upd["parent_id"].(*uint); (p == nil && cur.ParentID == nil) ||
+		(p != nil && cur.ParentID != nil && *p == *cur.ParentID) {
+		delete(upd, "parent_id")
+	}
+	return upd, nil
+}
+
+// PatchMenu godoc
+// @Summary Patch a menu item
+// @Description Send `application/merge-patch+json` (RFC 7396; plain `application/json` is treated the same) or
+// @Description `application/json-patch+json` (RFC 6902). The patch applies to
+// @Description `{"title","key","url","icon","parent_id","order"}`: `null` (merge patch) or `remove` (JSON Patch)
+// @Description clears `url` and `icon` and moves the item to the root for `parent_id`; `title`, `key` and
+// @Description `order` cannot be cleared. A failed JSON Patch `test` answers 409.
+// @Tags menus
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path string true "menu id, or key:<menu key>"
+// @Param input body updateMenuInput true "merge patch (or an array of jsonPatchOp)"
+// @Success 200 {object} menuMutationResponse "the updated item and the siblings a parent or order change renumbered"
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "menu_not_found or parent_not_found"
+// @Failure 409 {object} problem.Problem "cycle_detected, key_conflict or patch_test_failed"
+// @Failure 415 {object} problem.Problem
+// @Failure 422 {object} problem.Problem "patch_not_applicable"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/{id} [patch]
+func PatchMenu(c *gin.Context) {
+	c.Header("Accept-Patch", mergePatchType+", "+jsonPatchType)
+	id, ok := menuID(c)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		bindFailed(c, err)
+		return
+	}
+	siblings, changed, err := services.PatchMenuFn(c.Request.Context(), id, func(cur *models.Menu) (map[string]interface{}, error) {
+		return patchUpdate(c.ContentType(), cur, body)
+	})
+	var pe *patchError
+	switch {
+	case errors.As(err, &pe):
+		problem.Write(c, pe.Problem)
+		return
+	case err != nil:
+		serviceError(c, err)
+		return
+	case !changed:
+		respondMenu(c, id, "unchanged", nil)
+		return
+	}
+	respondMenu(c, id, "updated", siblings)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/galpt/sotekre/backend/render"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// renderCurrentID picks the item to mark as current: `current` (an id or
+// key:<menu key>) or, failing that, the item `path` resolves to. A path that
+// matches nothing marks nothing.
+func renderCurrentID(c *gin.Context) (uint, bool) {
+	ctx := c.Request.Context()
+	if cur := c.Query("current"); cur != "" {
+		id, err := services.LookupMenuID(ctx, cur)
+		switch {
+		case errors.Is(err, services.ErrInvalidMenuID):
+			invalidParam(c, "current must be a menu id or key:<menu key>")
+			return 0, false
+		case err != nil:
+			serviceError(c, err)
+			return 0, false
+		}
+		return id, true
+	}
+	if path := c.Query("path"); path != "" {
+		m, err := services.ResolveMenuPathFn(ctx, path)
+		switch {
+		case err == nil:
+			return m.Item.ID, true
+		case errors.Is(err, services.ErrNoMenuMatch):
+			return 0, true
+		}
+		serviceError(c, err)
+		return 0, false
+	}
+	return 0, true
+}
+
+// RenderMenus godoc
+// @Summary Render the menu tree as HTML or Markdown
+// @Description `html` is a fragment of nested `<ul>/<li>/<a>` (items without a URL are `<span>`); `md` is a nested
+// @Description bullet list of links. The current item — `current` (id or `key:<key>`), or the item `path` resolves
+// @Description to (see /api/v1/menus/resolve) — gets `aria-current` and `active_class`, its ancestors `ancestor_class`
+// @Description (in Markdown it is bold). The same renderers are available to Go code as package `render`.
+// @Tags menus
+// @Produce html
+// @Produce text/markdown
+// @Param format query string false "html (default) or md" Enums(html, md)
+// @Param current query string false "current item: menu id or key:<menu key>"
+// @Param path query string false "page path whose item is current"
+// @Param list_class query string false "class of every <ul>"
+// @Param sublist_class query string false "class of nested <ul> (list_class when omitted)"
+// @Param item_class query string false "class of every <li>"
+// @Param link_class query string false "class of every <a>"
+// @Param active_class query string false "extra <li> class of the current item"
+// @Param ancestor_class query string false "extra <li> class of the current item's ancestors"
+// @Param aria_current query string false "aria-current value (default page)"
+// @Success 200 {string} string
+// @Failure 400 {object} problem.Problem "invalid_parameter"
+// @Failure 404 {object} problem.Problem "menu_not_found: unknown current key"
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/menus/render [get]
+func RenderMenus(c *gin.Context) {
+	format := c.DefaultQuery("format", "html")
+	if format != "html" && format != "md" {
+		invalidParam(c, "format must be html or md")
+		return
+	}
+	current, ok := renderCurrentID(c)
+	if !ok {
+		return
+	}
+	tree, err := services.GetMenuTreeFn(c.Request.Context())
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	var b bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	if format == "md" {
+		contentType = "text/markdown; charset=utf-8"
+		err = render.Markdown(&b, tree, render.MarkdownOptions{CurrentID: current})
+	} else {
+		err = render.HTML(&b, tree, render.HTMLOptions{
+			ListClass:     c.Query("list_class"),
+			SubListClass:  c.Query("sublist_class"),
+			ItemClass:     c.Query("item_class"),
+			LinkClass:     c.Query("link_class"),
+			CurrentID:     current,
+			AriaCurrent:   c.Query("aria_current"),
+			ActiveClass:   c.Query("active_class"),
+			AncestorClass: c.Query("ancestor_class"),
+		})
+	}
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, contentType, b.Bytes())
+}
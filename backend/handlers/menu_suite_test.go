@@ -27,7 +27,7 @@ func (s *MenuSuite) SetupTest() {
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	require.NoError(s.T(), err)
 	config.DB = db
-	require.NoError(s.T(), config.DB.AutoMigrate(&models.Menu{}))
+	require.NoError(s.T(), config.DB.AutoMigrate(&models.Menu{}, &models.MenuChange{}))
 }
 
 func (s *MenuSuite) TearDownTest() {
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/galpt/sotekre/backend/middleware"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Response envelopes of /api/v1: every successful JSON response is
+// {"data": ...}, with list metadata such as cursors under "meta"; mutations
+// return the resource as it is afterwards and deletes answer 204. Updates,
+// moves and reorders also list the siblings whose order changed under
+// "meta.siblings", so clients can patch their copy of the tree. Errors are
+// problems on every version.
+//
+// The deprecated unversioned /api alias keeps its original shapes
+// ({"message": "updated"} for mutations, bare objects for some reads) until
+// its sunset.
+
+// legacyAPI reports whether the request came through the /api alias.
+func legacyAPI(c *gin.Context) bool {
+	return c.GetString(middleware.APIVersionKey) == ""
+}
+
+// respondData writes {"data": v}.
+func respondData(c *gin.Context, status int, v interface{}) {
+	c.JSON(status, gin.H{"data": v})
+}
+
+// menuMutationMeta is the "meta" of an update, move or reorder.
+type menuMutationMeta struct {
+	// Siblings are the items in the source and destination parents that were
+	// renumbered, with their new order (empty when none moved).
+	Siblings []services.SiblingOrder `json:"siblings"`
+}
+
+// menuMutationResponse documents respondMenu on /api/v1.
+type menuMutationResponse struct {
+	Data models.Menu      `json:"data"`
+	Meta menuMutationMeta `json:"meta"`
+}
+
+var _ = (*menuMutationResponse)(nil)
+
+// respondMenu answers a mutation of menu item id that renumbered siblings:
+// the item as it is now plus the siblings on /api/v1, {"message": msg} on
+// /api.
+func respondMenu(c *gin.Context, id uint, msg string, siblings []services.SiblingOrder) {
+	if legacyAPI(c) {
+		c.JSON(http.StatusOK, gin.H{"message": msg})
+		return
+	}
+	m, err := services.GetMenuFn(c.Request.Context(), id)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	if siblings == nil {
+		siblings = []services.SiblingOrder{}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": m, "meta": menuMutationMeta{Siblings: siblings}})
+}
+
+// respondDeleted answers a delete: 204 on /api/v1, {"message": "deleted"}
+// on /api.
+func respondDeleted(c *gin.Context) {
+	if legacyAPI(c) {
+		c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// childrenOf returns the children of parentID (nil: the root items) from the
+// cached tree, with their subtrees.
+func childrenOf(c *gin.Context, parentID *uint) ([]*models.MenuNode, error) {
+	tree, err := services.GetMenuTreeFn(c.Request.Context())
+	if err != nil {
+		return nil, err
+	}
+	if parentID == nil {
+		return tree, nil
+	}
+	var find func(level []*models.MenuNode) []*models.MenuNode
+	find = func(level []*models.MenuNode) []*models.MenuNode {
+		for _, n := range level {
+			if n.ID == *parentID {
+				return n.Children
+			}
+			if found := find(n.Children); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return find(tree), nil
+}
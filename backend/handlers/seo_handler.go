@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/galpt/sotekre/backend/render"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// siteBaseURL is the public site that menu URLs are relative to
+// (SITE_BASE_URL). The request's Host header is never used: it is client
+// controlled, and a cached sitemap or breadcrumb list would point crawlers at
+// whatever host the first caller sent. When SITE_BASE_URL is unset or invalid
+// it writes a 503 problem and reports false.
+func siteBaseURL(c *gin.Context) (*url.URL, bool) {
+	raw := config.EnvOr("SITE_BASE_URL", "")
+	u, err := url.Parse(raw)
+	if raw == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		ctx := c.Request.Context()
+		config.Logger(ctx).ErrorContext(ctx, "SITE_BASE_URL must be an absolute http(s) URL", "value", raw)
+		problem.Abort(c, http.StatusServiceUnavailable, problem.CodeSiteURLUnset, "the public site URL is not configured")
+		return nil, false
+	}
+	return u, true
+}
+
+// Sitemap godoc
+// @Summary sitemap.xml generated from the menu
+// @Description One `<url>` per site page a menu item links to, resolved against `SITE_BASE_URL`, with `lastmod`
+// @Description from the item's `updated_at`. Items without a URL, external links (other hosts, `mailto:`, ...)
+// @Description and pattern URLs such as `/orders/:id` are left out. Answers 503 `site_url_not_configured` until
+// @Description `SITE_BASE_URL` is set; the request's Host header is never used.
+// @Tags seo
+// @Produce xml
+// @Success 200 {string} string
+// @Failure 500 {object} problem.Problem
+// @Failure 503 {object} problem.Problem "site_url_not_configured"
+// @Router /sitemap.xml [get]
+func Sitemap(c *gin.Context) {
+	base, ok := siteBaseURL(c)
+	if !ok {
+		return
+	}
+	menus, err := services.GetAllMenusFn(c.Request.Context())
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	var b bytes.Buffer
+	if err := render.Sitemap(&b, menus, base); err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", b.Bytes())
+}
+
+// MenuBreadcrumbs godoc
+// @Summary schema.org BreadcrumbList (JSON-LD) for a menu item
+// @Description The item's ancestor chain, root first, ending with the item. Entries are resolved against
+// @Description `SITE_BASE_URL` (503 `site_url_not_configured` while it is unset); items without a URL, external
+// @Description links and pattern URLs are left out.
+// @Tags seo
+// @Produce application/ld+json
+// @Param id path string true "menu id, or key:<menu key>"
+// @Success 200 {object} render.BreadcrumbList
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem "menu_not_found"
+// @Failure 500 {object} problem.Problem
+// @Failure 503 {object} problem.Problem "site_url_not_configured"
+// @Router /api/v1/menus/{id}/breadcrumbs.jsonld [get]
+func MenuBreadcrumbs(c *gin.Context) {
+	id, ok := menuID(c)
+	if !ok {
+		return
+	}
+	base, ok := siteBaseURL(c)
+	if !ok {
+		return
+	}
+	chain, err := services.GetMenuChainFn(c.Request.Context(), id)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	b, err := json.Marshal(render.Breadcrumbs(chain, base))
+	if err != nil {
+		internalError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/ld+json", b)
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type createWebhookInput struct {
+	URL    string   `json:"url" binding:"required" example:"https://builder.example.com/hooks/menus"`
+	Secret string   `json:"secret" binding:"required"`
+	Events []string `json:"events" example:"moved,deleted"`
+	Active *bool    `json:"active"`
+}
+
+// --- types used only for API documentation (swag) ---
+type webhookResponse struct {
+	Data models.WebhookSubscription `json:"data"`
+}
+
+type webhookListResponse struct {
+	Data []models.WebhookSubscription `json:"data"`
+}
+
+type webhookDeliveryResponse struct {
+	Data models.WebhookDelivery `json:"data"`
+}
+
+type webhookDeliveryListResponse struct {
+	Data []models.WebhookDelivery `json:"data"`
+}
+
+var (
+	_ = (*webhookResponse)(nil)
+	_ = (*webhookListResponse)(nil)
+	_ = (*webhookDeliveryResponse)(nil)
+	_ = (*webhookDeliveryListResponse)(nil)
+)
+
+// webhookError maps service errors to status codes.
+func webhookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		problem.Abort(c, http.StatusBadRequest, problem.CodeValidationFailed, err.Error())
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		problem.Abort(c, http.StatusNotFound, problem.CodeWebhookNotFound, "webhook subscription or delivery does not exist")
+	default:
+		internalError(c, err)
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Subscribe a URL to menu change events
+// @Description Deliveries are POSTed with `X-Sotekre-Signature: sha256=<hex HMAC-SHA256 of body>`.
+// @Description `events` filters by type (created, updated, moved, reordered, deleted); empty means all.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param input body createWebhookInput true "subscription"
+// @Success 201 {object} webhookResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var in createWebhookInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	s := &models.WebhookSubscription{
+		URL:    in.URL,
+		Secret: in.Secret,
+		Events: strings.Join(in.Events, ","),
+		Active: true,
+	}
+	if in.Active != nil {
+		s.Active = *in.Active
+	}
+	if err := services.CreateWebhookFn(c.Request.Context(), s); err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": s})
+}
+
+// ListWebhooks godoc
+// @Summary List webhook subscriptions
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} webhookListResponse
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/webhooks [get]
+func ListWebhooks(c *gin.Context) {
+	subs, err := services.ListWebhooksFn(c.Request.Context())
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": subs})
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook subscription and its delivery log
+// @Tags webhooks
+// @Param id path int true "subscription id"
+// @Success 204
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidID(c)
+		return
+	}
+	if err := services.DeleteWebhookFn(c.Request.Context(), uint(id64)); err != nil {
+		webhookError(c, err)
+		return
+	}
+	respondDeleted(c)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary Delivery log of a subscription (newest first)
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "subscription id"
+// @Param limit query int false "max rows (default 50, max 200)"
+// @Success 200 {object} webhookDeliveryListResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func ListWebhookDeliveries(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidID(c)
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	dels, err := services.ListWebhookDeliveriesFn(c.Request.Context(), uint(id64), limit)
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": dels})
+}
+
+// RedeliverWebhook godoc
+// @Summary Queue a past delivery again
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "delivery id"
+// @Success 202 {object} webhookDeliveryResponse
+// @Failure 400 {object} problem.Problem
+// @Failure 404 {object} problem.Problem
+// @Failure 500 {object} problem.Problem
+// @Router /api/v1/webhooks/deliveries/{id}/redeliver [post]
+func RedeliverWebhook(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidID(c)
+		return
+	}
+	del, err := services.RedeliverWebhookFn(c.Request.Context(), uint(id64))
+	if err != nil {
+		webhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"data": del})
+}
@@ -0,0 +1,57 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/routes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks_CRUD_viaHTTP(t *testing.T) {
+	setupInMemoryDB(t)
+	defer config.CloseDB()
+	require.NoError(t, config.DB.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}))
+	r := routes.SetupRouter()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/webhooks", `{"url":"https://hooks.example.com/x","secret":"k","events":["moved","deleted"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.NotContains(t, rec.Body.String(), `"secret"`, "secret must never be echoed")
+	var created struct {
+		Data models.WebhookSubscription `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Equal(t, "moved,deleted", created.Data.Events)
+
+	rec = do(http.MethodPost, "/api/webhooks", `{"url":"javascript:alert(1)","secret":"k"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = do(http.MethodGet, "/api/webhooks", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "hooks.example.com")
+
+	rec = do(http.MethodGet, fmt.Sprintf("/api/webhooks/%d/deliveries", created.Data.ID), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = do(http.MethodPost, "/api/webhooks/deliveries/999/redeliver", "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = do(http.MethodDelete, fmt.Sprintf("/api/webhooks/%d", created.Data.ID), "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = do(http.MethodDelete, fmt.Sprintf("/api/webhooks/%d", created.Data.ID), "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
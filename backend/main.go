@@ -1,6 +1,9 @@
 // @title Sotekre — Menu Tree API
 // @version 0.1.0
-// @description Minimal OpenAPI for the Menu Tree MVP
+// @description Minimal OpenAPI for the Menu Tree MVP. Routes are documented under /api/v1, where every
+// @description JSON response is `{"data": ...}` and mutations return the changed resource. The unversioned
+// @description /api alias is deprecated (`Deprecation`/`Sunset` headers) and keeps the old shapes:
+// @description `{"message": ...}` for mutations and deletes, and bare objects for /menus/flat and /menus/changes.
 // @contact.name API Support
 // @contact.email dev@example.com
 // @host localhost:8080
@@ -12,7 +15,8 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -20,32 +24,59 @@ import (
 	"time"
 
 	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/grpcapi"
 	"github.com/galpt/sotekre/backend/models"
 	"github.com/galpt/sotekre/backend/routes"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/galpt/sotekre/backend/tracing"
 	"github.com/joho/godotenv"
 )
 
-func init() {
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-}
-
 func run(quit <-chan os.Signal) error {
 	// Helpful runtime hint: if the app is started in Docker (DB_HOST=db) but the
 	// DB password is empty, the official MySQL image will fail to start because
 	// it requires a non-empty MYSQL_ROOT_PASSWORD. This is *only* a warning.
 	if os.Getenv("DB_HOST") == "db" && os.Getenv("DB_PASS") == "" {
-		log.Println("WARNING: DB_HOST=db but DB_PASS is empty — Docker MySQL requires a non-empty MYSQL_ROOT_PASSWORD. Use .env.docker or set MYSQL_ROOT_PASSWORD when running docker-compose.")
+		slog.Warn("DB_HOST=db but DB_PASS is empty — Docker MySQL requires a non-empty MYSQL_ROOT_PASSWORD. Use .env.docker or set MYSQL_ROOT_PASSWORD when running docker-compose.")
 	}
 
+	// tracing: OTEL_TRACES_EXPORTER=otlp|stdout|none (default none)
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		return fmt.Errorf("tracing setup failed: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("tracing shutdown failed", "error", err)
+		}
+	}()
+
+	services.SetDraining(false)
 	if err := config.InitDB(); err != nil {
 		return fmt.Errorf("failed to connect to db: %w", err)
 	}
 	defer config.CloseDB()
+	// one span per SQL statement (queries issued with a traced context)
+	if err := config.DB.Use(tracing.GormPlugin()); err != nil {
+		return fmt.Errorf("gorm tracing plugin: %w", err)
+	}
 
 	// Auto-migrate schema (safe for interview / MVP)
-	if err := config.DB.AutoMigrate(&models.Menu{}); err != nil {
+	if err := config.DB.AutoMigrate(models.All()...); err != nil {
 		return fmt.Errorf("auto-migrate failed: %w", err)
 	}
+	// rows imported via SQL (or from before keys existed) get generated keys
+	if n, err := services.BackfillMenuKeys(context.Background()); err != nil {
+		return fmt.Errorf("menu key backfill failed: %w", err)
+	} else if n > 0 {
+		slog.Info("generated menu keys", "count", n)
+	}
+
+	// outbound webhooks: deliveries are queued by every committed mutation
+	stopWebhooks := services.StartWebhookDispatcher(services.WebhookOptionsFromEnv())
+	defer stopWebhooks()
 
 	r := routes.SetupRouter()
 
@@ -63,33 +94,68 @@ func run(quit <-chan os.Signal) error {
 	}
 
 	go func() {
-		log.Printf("listening on http://localhost:%s", port)
+		slog.Info("listening", "addr", fmt.Sprintf("http://localhost:%s", port))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server error: %v", err)
+			slog.Error("server error", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	// gRPC API for internal services, on its own address (loopback unless
+	// GRPC_ADDR says otherwise: it has no authentication or TLS)
+	grpcLis, err := net.Listen("tcp", config.EnvOr("GRPC_ADDR", "127.0.0.1:9090"))
+	if err != nil {
+		return fmt.Errorf("grpc listen failed: %w", err)
+	}
+	grpcSrv := grpcapi.NewServer(grpcapi.OptionsFromEnv())
+	go func() {
+		slog.Info("grpc listening", "addr", grpcLis.Addr().String())
+		if err := grpcSrv.Serve(grpcLis); err != nil {
+			slog.Error("grpc server error", "error", err)
 		}
 	}()
 
 	// graceful shutdown - delegate signal handling to caller (tests can inject)
 	<-quit
-	log.Println("shutting down server...")
+	// report not-ready first so load balancers stop routing new traffic, give
+	// them SHUTDOWN_DRAIN_MS to notice, then stop accepting connections
+	services.SetDraining(true)
+	drain := config.EnvDurationMSOr("SHUTDOWN_DRAIN_MS", 0)
+	slog.Info("shutting down server", "drain", drain)
+	time.Sleep(drain)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
+		grpcSrv.Stop()
 		return fmt.Errorf("server forced to shutdown: %w", err)
 	}
+	// WatchChanges streams only end when clients leave; cut them off with the
+	// same deadline
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcSrv.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcSrv.Stop()
+	}
 
-	log.Println("server stopped")
+	slog.Info("server stopped")
 	return nil
 }
 
 func main() {
 	// load .env if present
 	_ = godotenv.Load()
+	config.InitLogger()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	if err := run(quit); err != nil {
-		log.Fatalf("%v", err)
+		slog.Error("exiting", "error", err)
+		os.Exit(1)
 	}
 }
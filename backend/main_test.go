@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net"
@@ -24,7 +25,7 @@ func TestRun_startsAndGracefullyShutsDown(t *testing.T) {
 	config.OpenGorm = func(dialector gorm.Dialector, opts ...gorm.Option) (*gorm.DB, error) {
 		return gorm.Open(sqlite.Open("file:memtest_main?mode=memory&cache=shared"), opts...)
 	}
-	config.PingFn = func(db *sql.DB) error { return nil }
+	config.PingFn = func(_ context.Context, db *sql.DB) error { return nil }
 
 	// find a free port and export it so run() binds to it
 	ln, err := net.Listen("tcp", ":0")
@@ -33,6 +34,8 @@ func TestRun_startsAndGracefullyShutsDown(t *testing.T) {
 	ln.Close()
 	os.Setenv("PORT", fmt.Sprintf("%d", port))
 	defer os.Unsetenv("PORT")
+	os.Setenv("GRPC_ADDR", "127.0.0.1:0") // any free port
+	defer os.Unsetenv("GRPC_ADDR")
 	// avoid docs file resolution during the test
 	os.Setenv("SOTEKRE_TEST_NO_DOCS", "1")
 	defer os.Unsetenv("SOTEKRE_TEST_NO_DOCS")
@@ -77,7 +80,7 @@ func TestRun_withDBHostDocker_warningAndInit(t *testing.T) {
 	config.OpenGorm = func(dialector gorm.Dialector, opts ...gorm.Option) (*gorm.DB, error) {
 		return gorm.Open(sqlite.Open("file:memtest_main2?mode=memory&cache=shared"), opts...)
 	}
-	config.PingFn = func(db *sql.DB) error { return nil }
+	config.PingFn = func(_ context.Context, db *sql.DB) error { return nil }
 
 	os.Setenv("DB_HOST", "db")
 	os.Unsetenv("DB_PASS")
@@ -90,6 +93,8 @@ func TestRun_withDBHostDocker_warningAndInit(t *testing.T) {
 	ln.Close()
 	os.Setenv("PORT", fmt.Sprintf("%d", port))
 	defer os.Unsetenv("PORT")
+	os.Setenv("GRPC_ADDR", "127.0.0.1:0") // any free port
+	defer os.Unsetenv("GRPC_ADDR")
 	os.Setenv("SOTEKRE_TEST_NO_DOCS", "1")
 	defer os.Unsetenv("SOTEKRE_TEST_NO_DOCS")
 
@@ -123,3 +128,45 @@ waitdone:
 		t.Fatal("run did not return after shutdown")
 	}
 }
+
+func TestRun_readinessFlipsWhileDraining(t *testing.T) {
+	origOpen := config.OpenGorm
+	origPing := config.PingFn
+	defer func() { config.OpenGorm = origOpen; config.PingFn = origPing }()
+	config.OpenGorm = func(dialector gorm.Dialector, opts ...gorm.Option) (*gorm.DB, error) {
+		return gorm.Open(sqlite.Open("file:memtest_main_drain?mode=memory&cache=shared"), opts...)
+	}
+	config.PingFn = func(_ context.Context, db *sql.DB) error { return nil }
+
+	ln, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	t.Setenv("PORT", fmt.Sprintf("%d", port))
+	t.Setenv("SOTEKRE_TEST_NO_DOCS", "1")
+	t.Setenv("SHUTDOWN_DRAIN_MS", "500")
+
+	quit := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() { done <- run(quit) }()
+
+	readyz := func() int {
+		res, err := http.Get(fmt.Sprintf("http://localhost:%d/readyz", port))
+		if err != nil {
+			return 0
+		}
+		_ = res.Body.Close()
+		return res.StatusCode
+	}
+	require.Eventually(t, func() bool { return readyz() == http.StatusOK }, 3*time.Second, 10*time.Millisecond)
+
+	quit <- os.Interrupt
+	require.Eventually(t, func() bool { return readyz() == http.StatusServiceUnavailable }, 400*time.Millisecond, 10*time.Millisecond)
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("run did not return after shutdown")
+	}
+}
@@ -0,0 +1,165 @@
+// Package metrics exposes Prometheus collectors for the HTTP layer, the DB
+// pool, menu transactions and the menu tree.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sotekre"
+
+// Registry holds the process-wide collectors. A dedicated registry (rather
+// than prometheus.DefaultRegisterer) keeps tests free of duplicate
+// registration panics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts finished requests by method, route template and status.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes request latency by method, route template and status.
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// TxRetries counts menu transactions retried after a deadlock or lock
+	// wait timeout, by operation (move, delete).
+	TxRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "menu_tx_retries_total",
+		Help:      "Menu transactions retried after a lock conflict, by operation.",
+	}, []string{"op"})
+
+	// TxRollbacks counts menu transactions that rolled back, by operation.
+	TxRollbacks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "menu_tx_rollbacks_total",
+		Help:      "Menu transactions rolled back, by operation.",
+	}, []string{"op"})
+
+	// TreeCacheLookups counts menu tree reads by cache result (hit, miss).
+	// Scrapes of the tree gauges are not counted.
+	TreeCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "menu_tree_cache_lookups_total",
+		Help:      "Menu tree reads by cache result (hit or miss).",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration, TxRetries, TxRollbacks, TreeCacheLookups,
+		dbStatsCollector{},
+	)
+}
+
+// TreeStats describes the shape of the menu tree.
+type TreeStats struct {
+	Items     int // all menu items
+	MaxDepth  int // roots are depth 1; 0 for an empty tree
+	MaxFanOut int // most children under one parent (the root level counts)
+}
+
+// TreeStatsFunc computes TreeStats at scrape time.
+type TreeStatsFunc func() (TreeStats, error)
+
+var (
+	treeItemsDesc = prometheus.NewDesc(namespace+"_menu_items", "Number of menu items.", nil, nil)
+	treeDepthDesc = prometheus.NewDesc(namespace+"_menu_tree_max_depth", "Depth of the deepest menu item (roots are 1).", nil, nil)
+	treeFanDesc   = prometheus.NewDesc(namespace+"_menu_tree_max_fanout", "Most children under a single parent (the root level counts).", nil, nil)
+)
+
+// treeCollector reports TreeStats gauges; a failing source reports nothing.
+type treeCollector struct{ stats TreeStatsFunc }
+
+func (c treeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- treeItemsDesc
+	ch <- treeDepthDesc
+	ch <- treeFanDesc
+}
+
+func (c treeCollector) Collect(ch chan<- prometheus.Metric) {
+	s, err := c.stats()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(treeItemsDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(treeItemsDesc, prometheus.GaugeValue, float64(s.Items))
+	ch <- prometheus.MustNewConstMetric(treeDepthDesc, prometheus.GaugeValue, float64(s.MaxDepth))
+	ch <- prometheus.MustNewConstMetric(treeFanDesc, prometheus.GaugeValue, float64(s.MaxFanOut))
+}
+
+// Handler serves the Prometheus text format for Registry plus the tree gauges
+// computed by tree (nil skips them).
+func Handler(tree TreeStatsFunc) http.Handler {
+	gatherers := prometheus.Gatherers{Registry}
+	if tree != nil {
+		local := prometheus.NewRegistry()
+		local.MustRegister(treeCollector{stats: tree})
+		gatherers = append(gatherers, local)
+	}
+	return promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{})
+}
+
+// dbStatsCollector reports sql.DBStats of config.DB at scrape time, so it
+// follows whatever pool InitDB (or a test) installed.
+type dbStatsCollector struct{}
+
+var (
+	dbMaxOpenDesc      = prometheus.NewDesc(namespace+"_db_max_open_connections", "Maximum number of open connections to the database.", nil, nil)
+	dbOpenDesc         = prometheus.NewDesc(namespace+"_db_open_connections", "The number of established connections both in use and idle.", nil, nil)
+	dbInUseDesc        = prometheus.NewDesc(namespace+"_db_in_use_connections", "The number of connections currently in use.", nil, nil)
+	dbIdleDesc         = prometheus.NewDesc(namespace+"_db_idle_connections", "The number of idle connections.", nil, nil)
+	dbWaitCountDesc    = prometheus.NewDesc(namespace+"_db_wait_count_total", "The total number of connections waited for.", nil, nil)
+	dbWaitDurationDesc = prometheus.NewDesc(namespace+"_db_wait_duration_seconds_total", "The total time blocked waiting for a new connection.", nil, nil)
+	dbMaxIdleDesc      = prometheus.NewDesc(namespace+"_db_max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns.", nil, nil)
+	dbMaxIdleTimeDesc  = prometheus.NewDesc(namespace+"_db_max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime.", nil, nil)
+	dbMaxLifetimeDesc  = prometheus.NewDesc(namespace+"_db_max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime.", nil, nil)
+)
+
+func (dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{dbMaxOpenDesc, dbOpenDesc, dbInUseDesc, dbIdleDesc, dbWaitCountDesc,
+		dbWaitDurationDesc, dbMaxIdleDesc, dbMaxIdleTimeDesc, dbMaxLifetimeDesc} {
+		ch <- d
+	}
+}
+
+func (dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	if config.DB == nil {
+		return
+	}
+	sqlDB, err := config.DB.DB()
+	if err != nil {
+		return
+	}
+	s := sqlDB.Stats()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge(dbMaxOpenDesc, float64(s.MaxOpenConnections))
+	gauge(dbOpenDesc, float64(s.OpenConnections))
+	gauge(dbInUseDesc, float64(s.InUse))
+	gauge(dbIdleDesc, float64(s.Idle))
+	counter(dbWaitCountDesc, float64(s.WaitCount))
+	counter(dbWaitDurationDesc, s.WaitDuration.Seconds())
+	counter(dbMaxIdleDesc, float64(s.MaxIdleClosed))
+	counter(dbMaxIdleTimeDesc, float64(s.MaxIdleTimeClosed))
+	counter(dbMaxLifetimeDesc, float64(s.MaxLifetimeClosed))
+}
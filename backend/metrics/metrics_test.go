@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func scrape(t *testing.T, h http.Handler) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return rec.Code, string(body)
+}
+
+func TestHandler_exposesTreeAndDBStats(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:metrics_%d?mode=memory&cache=shared", time.Now().UnixNano())), &gorm.Config{})
+	require.NoError(t, err)
+	config.DB = db
+	defer config.CloseDB()
+
+	TxRollbacks.WithLabelValues("move").Inc()
+	code, body := scrape(t, Handler(func() (TreeStats, error) {
+		return TreeStats{Items: 7, MaxDepth: 3, MaxFanOut: 4}, nil
+	}))
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "sotekre_menu_items 7")
+	require.Contains(t, body, "sotekre_menu_tree_max_depth 3")
+	require.Contains(t, body, "sotekre_menu_tree_max_fanout 4")
+	require.Contains(t, body, "sotekre_db_open_connections")
+	require.Contains(t, body, `sotekre_menu_tx_rollbacks_total{op="move"}`)
+	require.Contains(t, body, "go_goroutines")
+}
+
+func TestHandler_treeErrorIsReported(t *testing.T) {
+	code, _ := scrape(t, Handler(func() (TreeStats, error) { return TreeStats{}, errors.New("db down") }))
+	require.Equal(t, http.StatusInternalServerError, code)
+}
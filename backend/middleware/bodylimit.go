@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxBodyBytes caps mutating request bodies unless MAX_BODY_BYTES
+// overrides it.
+const DefaultMaxBodyBytes = 1 << 20 // 1 MiB
+
+// MaxBodyBytesFromEnv returns MAX_BODY_BYTES or DefaultMaxBodyBytes.
+func MaxBodyBytesFromEnv() int64 {
+	return int64(config.EnvIntOr("MAX_BODY_BYTES", DefaultMaxBodyBytes))
+}
+
+// BodyLimit rejects POST/PUT/PATCH/DELETE bodies larger than max bytes with
+// 413. The body is read up front (it is small by definition), so handlers
+// never see a truncated body or a mid-decode error.
+func BodyLimit(max int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > max {
+			tooLarge(c, max)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, max))
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				tooLarge(c, max)
+				return
+			}
+			problem.Abort(c, http.StatusBadRequest, problem.CodeMalformedBody, "could not read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+func tooLarge(c *gin.Context, max int64) {
+	problem.Abort(c, http.StatusRequestEntityTooLarge, problem.CodeBodyTooLarge, "request body exceeds "+strconv.FormatInt(max, 10)+" bytes")
+}
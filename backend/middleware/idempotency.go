@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader carries the client-chosen key of a mutating request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set to "true" on replayed responses.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// DefaultIdempotencyTTL is how long keys are remembered unless
+// IDEMPOTENCY_TTL_MS overrides it.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLen bounds client-supplied keys.
+const maxIdempotencyKeyLen = 255
+
+// IdempotentResponse is what a store remembers about a key: the fingerprint
+// of the request that claimed it and, once it finished, its response.
+type IdempotentResponse struct {
+	Fingerprint string
+	Done        bool
+	Status      int
+	Header      http.Header
+	Body        []byte
+}
+
+// IdempotencyStore remembers keys for a TTL. Reserve claims an unknown key
+// for fingerprint (claimed true); for a known key it returns the stored entry,
+// whose Done is false while the original request is still running. Save
+// stores the finished response; Release forgets a claim so the request can be
+// retried. MemoryIdempotencyStore is the in-process implementation; a shared
+// store can implement the same interface for multi-instance setups.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (existing *IdempotentResponse, claimed bool, err error)
+	Save(ctx context.Context, key string, resp IdempotentResponse, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
+
+type idempotencyEntry struct {
+	resp    IdempotentResponse
+	expires time.Time
+}
+
+// MemoryIdempotencyStore keeps keys in memory. Expired keys are swept
+// periodically.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+	now       func() time.Time // test hook
+}
+
+// NewMemoryIdempotencyStore returns an empty in-memory store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: map[string]*idempotencyEntry{}, now: time.Now}
+}
+
+// Reserve implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Reserve(_ context.Context, key, fingerprint string, ttl time.Duration) (*IdempotentResponse, bool, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.lastSweep = now
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	if e, ok := s.entries[key]; ok && !now.After(e.expires) {
+		resp := e.resp
+		return &resp, false, nil
+	}
+	s.entries[key] = &idempotencyEntry{resp: IdempotentResponse{Fingerprint: fingerprint}, expires: now.Add(ttl)}
+	return nil, true, nil
+}
+
+// Save implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Save(_ context.Context, key string, resp IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp.Done = true
+	s.entries[key] = &idempotencyEntry{resp: resp, expires: s.now().Add(ttl)}
+	return nil
+}
+
+// Release implements IdempotencyStore.
+func (s *MemoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// IdempotencyOptions configures Idempotency.
+type IdempotencyOptions struct {
+	TTL   time.Duration
+	Store IdempotencyStore
+}
+
+// IdempotencyOptionsFromEnv reads IDEMPOTENCY_TTL_MS with an in-memory store.
+func IdempotencyOptionsFromEnv() IdempotencyOptions {
+	return IdempotencyOptions{
+		TTL:   config.EnvDurationMSOr("IDEMPOTENCY_TTL_MS", DefaultIdempotencyTTL),
+		Store: NewMemoryIdempotencyStore(),
+	}
+}
+
+// requestFingerprint hashes what makes two requests "the same".
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// captureWriter tees the response body so it can be stored.
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes POST/PUT/PATCH/DELETE requests carrying an
+// Idempotency-Key safe to retry. The first request with a key runs and its
+// response is stored for the TTL; a retry with the same key and the same
+// method, URI and body gets the stored response replayed (with
+// Idempotent-Replayed: true). The same key with a different request is 422,
+// and a retry while the original is still running is 409. Keys are scoped per
+// client like rate limits. 5xx responses are not stored, so the request can
+// be retried. Store errors fail open.
+func Idempotency(opts IdempotencyOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			key = ""
+		}
+		if key == "" {
+			c.Next()
+			return
+		}
+		if !printableToken(key, maxIdempotencyKeyLen) {
+			problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidParameter,
+				"Idempotency-Key must be 1-255 printable ASCII characters without spaces")
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			problem.Abort(c, http.StatusBadRequest, problem.CodeMalformedBody, "could not read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key = clientKey(c) + ":" + key
+		fp := requestFingerprint(c.Request, body)
+		existing, claimed, err := opts.Store.Reserve(ctx, key, fp, opts.TTL)
+		if err != nil {
+			config.Logger(ctx).WarnContext(ctx, "idempotency store failed; running request", "error", err)
+			c.Next()
+			return
+		}
+		if !claimed {
+			switch {
+			case existing.Fingerprint != fp:
+				problem.Abort(c, http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused,
+					"Idempotency-Key was already used for a different request")
+			case !existing.Done:
+				c.Header("Retry-After", "1")
+				problem.Abort(c, http.StatusConflict, problem.CodeIdempotencyInFlight,
+					"a request with this Idempotency-Key is still being processed")
+			default:
+				for k, vs := range existing.Header {
+					c.Writer.Header()[k] = vs
+				}
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Status(existing.Status)
+				_, _ = c.Writer.Write(existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		saved := false
+		defer func() {
+			// runs on panics too, so a crashed request can be retried
+			if !saved {
+				if err := opts.Store.Release(ctx, key); err != nil {
+					config.Logger(ctx).WarnContext(ctx, "idempotency store release failed", "error", err)
+				}
+			}
+		}()
+		w := &captureWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.Status() >= http.StatusInternalServerError {
+			return
+		}
+		header := w.Header().Clone()
+		header.Del(RequestIDHeader) // the retry has its own
+		resp := IdempotentResponse{Fingerprint: fp, Status: w.Status(), Header: header, Body: w.body.Bytes()}
+		if err := opts.Store.Save(ctx, key, resp, opts.TTL); err != nil {
+			config.Logger(ctx).WarnContext(ctx, "idempotency store save failed", "error", err)
+			return
+		}
+		saved = true
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency_replaysAndRejectsReuse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	status := http.StatusCreated
+	r := gin.New()
+	r.Use(RequestID(), Idempotency(IdempotencyOptions{TTL: time.Hour, Store: NewMemoryIdempotencyStore()}))
+	r.POST("/items", func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/items/1")
+		c.JSON(status, gin.H{"calls": calls})
+	})
+	send := func(key, body string, token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send("k1", `{"a":1}`, "")
+	require.Equal(t, http.StatusCreated, first.Code)
+	retry := send("k1", `{"a":1}`, "")
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, first.Body.String(), retry.Body.String())
+	require.Equal(t, "/items/1", retry.Header().Get("Location"))
+	require.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	require.NotEqual(t, first.Header().Get(RequestIDHeader), retry.Header().Get(RequestIDHeader))
+	require.Equal(t, 1, calls)
+
+	rec := send("k1", `{"a":2}`, "")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	require.Contains(t, rec.Body.String(), `"code":"idempotency_key_reused"`)
+
+	// keys are per client, requests without a key always run
+	require.Equal(t, http.StatusCreated, send("k1", `{"a":2}`, "tok").Code)
+	send("", `{"a":1}`, "")
+	send("", `{"a":1}`, "")
+	require.Equal(t, 4, calls)
+
+	// server errors are not stored
+	status = http.StatusInternalServerError
+	require.Equal(t, http.StatusInternalServerError, send("k2", `{}`, "").Code)
+	status = http.StatusCreated
+	rec = send("k2", `{}`, "")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Empty(t, rec.Header().Get(IdempotentReplayedHeader))
+
+	require.Equal(t, http.StatusBadRequest, send(strings.Repeat("k", 256), `{}`, "").Code)
+}
+
+func TestMemoryIdempotencyStore_inFlightAndExpiry(t *testing.T) {
+	s := NewMemoryIdempotencyStore()
+	now := time.Unix(1000, 0)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, claimed, _ := s.Reserve(ctx, "k", "fp", time.Minute)
+	require.True(t, claimed)
+	existing, claimed, _ := s.Reserve(ctx, "k", "fp", time.Minute)
+	require.False(t, claimed)
+	require.False(t, existing.Done, "the first request is still running")
+
+	require.NoError(t, s.Save(ctx, "k", IdempotentResponse{Fingerprint: "fp", Status: 201}, time.Minute))
+	existing, _, _ = s.Reserve(ctx, "k", "fp", time.Minute)
+	require.True(t, existing.Done)
+	require.Equal(t, 201, existing.Status)
+
+	now = now.Add(2 * time.Minute)
+	_, claimed, _ = s.Reserve(ctx, "k", "other", time.Minute)
+	require.True(t, claimed, "expired keys can be reused")
+	require.NoError(t, s.Release(ctx, "k"))
+	require.Empty(t, s.entries)
+}
@@ -0,0 +1,120 @@
+// Package middleware holds the Gin middleware shared by every route.
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is read from incoming requests and echoed on responses.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the gin context key holding the request id.
+const RequestIDKey = "request_id"
+
+// maxRequestIDLen bounds client-supplied ids so they cannot bloat log lines.
+const maxRequestIDLen = 128
+
+// printableToken accepts 1 to max bytes of printable ASCII without spaces.
+func printableToken(s string, max int) bool {
+	if s == "" || len(s) > max {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] <= ' ' || s[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func validRequestID(id string) bool { return printableToken(id, maxRequestIDLen) }
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// RequestIDOrNew returns id when it is usable as a request id (1-128
+// printable ASCII characters without spaces) and a new random one otherwise.
+func RequestIDOrNew(id string) string {
+	if !validRequestID(id) {
+		return newRequestID()
+	}
+	return id
+}
+
+// RequestID honours a valid incoming X-Request-ID or generates one, echoes it
+// on the response and stores a logger tagged with request_id (and trace_id
+// when the request is traced) in the request context (see config.Logger), so
+// services log against the same id.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := RequestIDOrNew(c.GetHeader(RequestIDHeader))
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		ctx := c.Request.Context()
+		l := config.Logger(ctx).With(RequestIDKey, id)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			// lets a log line be matched to its trace
+			l = l.With("trace_id", sc.TraceID().String())
+		}
+		c.Request = c.Request.WithContext(config.WithLogger(ctx, l))
+		c.Next()
+	}
+}
+
+// AccessLog writes one structured line per request once it completes.
+// 5xx responses log at error, 4xx at warn, everything else at info.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypeAny).String(); errs != "" {
+			attrs = append(attrs, slog.String("errors", errs))
+		}
+		ctx := c.Request.Context()
+		config.Logger(ctx).LogAttrs(ctx, level, "request", attrs...)
+	}
+}
+
+// Recovery turns a panic into a 500 problem and logs it with its stack through the
+// request logger (gin.Recovery would print plain text to stderr).
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		ctx := c.Request.Context()
+		config.Logger(ctx).ErrorContext(ctx, "panic recovered", "panic", err, "stack", string(debug.Stack()))
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "internal server error")
+	})
+}
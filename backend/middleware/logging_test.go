@@ -0,0 +1,103 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(config.NewLogger(&buf, "debug", "json"))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(l), &m), l)
+		out = append(out, m)
+	}
+	return out
+}
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.AccessLog(), middleware.Recovery())
+	r.GET("/work", func(c *gin.Context) {
+		// what a service does with the request context
+		ctx := c.Request.Context()
+		config.Logger(ctx).WarnContext(ctx, "service failed")
+		c.Status(http.StatusConflict)
+	})
+	r.GET("/panic", func(c *gin.Context) { panic("kaboom") })
+	return r
+}
+
+func TestRequestID_honouredAndPropagated(t *testing.T) {
+	buf := captureLogs(t)
+	r := newRouter()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/work", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	r.ServeHTTP(rec, req)
+	require.Equal(t, "req-123", rec.Header().Get(middleware.RequestIDHeader))
+
+	lines := logLines(t, buf)
+	require.Len(t, lines, 2)
+	require.Equal(t, "service failed", lines[0]["msg"])
+	require.Equal(t, "req-123", lines[0]["request_id"])
+	require.Equal(t, "request", lines[1]["msg"])
+	require.Equal(t, "req-123", lines[1]["request_id"])
+	require.Equal(t, float64(http.StatusConflict), lines[1]["status"])
+	require.Equal(t, "/work", lines[1]["route"])
+	require.Equal(t, "WARN", lines[1]["level"])
+}
+
+func TestRequestID_generatedWhenMissingOrInvalid(t *testing.T) {
+	captureLogs(t)
+	r := newRouter()
+	for _, incoming := range []string{"", "has spaces", strings.Repeat("x", 200)} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/work", nil)
+		if incoming != "" {
+			req.Header.Set(middleware.RequestIDHeader, incoming)
+		}
+		r.ServeHTTP(rec, req)
+		got := rec.Header().Get(middleware.RequestIDHeader)
+		require.Len(t, got, 32, "incoming %q", incoming)
+		require.NotEqual(t, incoming, got)
+	}
+}
+
+func TestRecovery_logsPanicWithRequestID(t *testing.T) {
+	buf := captureLogs(t)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil).WithContext(context.Background())
+	req.Header.Set(middleware.RequestIDHeader, "p-1")
+	newRouter().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	lines := logLines(t, buf)
+	require.Equal(t, "panic recovered", lines[0]["msg"])
+	require.Equal(t, "kaboom", lines[0]["panic"])
+	require.Equal(t, "p-1", lines[0]["request_id"])
+	require.Equal(t, "ERROR", lines[1]["level"])
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// Limit is a token bucket: Burst requests at once, refilled at PerMinute.
+// A zero PerMinute disables limiting.
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+// RateLimitStore decides whether key may make another request under limit.
+// When it may not, retryAfter says when the next token is available.
+// MemoryRateLimitStore is the in-process implementation; a shared store
+// (e.g. Redis) can implement the same interface for multi-instance setups.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, limit Limit) (ok bool, retryAfter time.Duration, err error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryRateLimitStore keeps token buckets in memory. Buckets idle long enough
+// to have refilled completely are swept periodically.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time // test hook
+}
+
+// NewMemoryRateLimitStore returns an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// sweepInterval bounds how often idle buckets are dropped.
+const sweepInterval = time.Minute
+
+// Allow implements RateLimitStore.
+func (s *MemoryRateLimitStore) Allow(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	if limit.PerMinute <= 0 {
+		return true, 0, nil
+	}
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	rate := float64(limit.PerMinute) / 60 // tokens per second
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.lastSweep = now
+		full := time.Duration(burst / rate * float64(time.Second))
+		for k, b := range s.buckets {
+			if now.Sub(b.last) > full {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// RateLimitOptions configures RateLimit.
+type RateLimitOptions struct {
+	PerIP    Limit // every request, keyed by client IP
+	PerToken Limit // additionally, requests with a validated bearer token, keyed by token
+	Store    RateLimitStore
+	// ValidToken reports whether a bearer token is one this server issued.
+	// Only those get a per-token bucket: an arbitrary header value must not
+	// buy a fresh bucket. Nil validates nothing.
+	ValidToken func(ctx context.Context, token string) bool
+}
+
+// RateLimitOptionsFromEnv reads RATE_LIMIT_IP_PER_MIN / RATE_LIMIT_IP_BURST
+// and RATE_LIMIT_TOKEN_PER_MIN / RATE_LIMIT_TOKEN_BURST (0 per minute
+// disables that limit) with an in-memory store. RATE_LIMIT_TOKENS is a
+// comma-separated list of the client tokens that get their own bucket.
+func RateLimitOptionsFromEnv() RateLimitOptions {
+	return RateLimitOptions{
+		PerIP: Limit{
+			PerMinute: config.EnvIntOr("RATE_LIMIT_IP_PER_MIN", 600),
+			Burst:     config.EnvIntOr("RATE_LIMIT_IP_BURST", 60),
+		},
+		PerToken: Limit{
+			PerMinute: config.EnvIntOr("RATE_LIMIT_TOKEN_PER_MIN", 1200),
+			Burst:     config.EnvIntOr("RATE_LIMIT_TOKEN_BURST", 120),
+		},
+		Store:      NewMemoryRateLimitStore(),
+		ValidToken: StaticTokens(strings.Split(config.EnvOr("RATE_LIMIT_TOKENS", ""), ",")),
+	}
+}
+
+// StaticTokens validates bearer tokens against a fixed list (empty entries
+// are ignored). Only digests are kept, and they are compared in constant
+// time.
+func StaticTokens(tokens []string) func(ctx context.Context, token string) bool {
+	var digests [][sha256.Size]byte
+	for _, t := range tokens {
+		if t = strings.TrimSpace(t); t != "" {
+			digests = append(digests, sha256.Sum256([]byte(t)))
+		}
+	}
+	return func(_ context.Context, token string) bool {
+		sum := sha256.Sum256([]byte(token))
+		found := 0
+		for _, d := range digests {
+			found |= subtle.ConstantTimeCompare(sum[:], d[:])
+		}
+		return found == 1
+	}
+}
+
+// bearerToken returns the token of an `Authorization: Bearer` header.
+func bearerToken(c *gin.Context) string {
+	h := c.GetHeader("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// tokenKey is the bucket key of a validated token: its SHA-256, so stores
+// never hold credentials.
+func tokenKey(tok string) string {
+	sum := sha256.Sum256([]byte(tok))
+	return "token:" + hex.EncodeToString(sum[:])
+}
+
+// clientKey identifies the caller for scoping idempotency keys: the bearer
+// token's key when one is sent, "ip:<addr>" otherwise.
+func clientKey(c *gin.Context) string {
+	if tok := bearerToken(c); tok != "" {
+		return tokenKey(tok)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// charge is one bucket a request is counted against.
+type charge struct {
+	key   string
+	limit Limit
+}
+
+// RateLimit rejects requests over their bucket with 429 and Retry-After
+// (whole seconds, rounded up). Every request is charged to its client IP
+// (c.ClientIP, which only honours X-Forwarded-For from the engine's trusted
+// proxies); a request with a validated bearer token is also charged to that
+// token. Store errors fail open.
+func RateLimit(opts RateLimitOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		buckets := []charge{{"ip:" + c.ClientIP(), opts.PerIP}}
+		if tok := bearerToken(c); tok != "" && opts.ValidToken != nil && opts.ValidToken(ctx, tok) {
+			buckets = append(buckets, charge{tokenKey(tok), opts.PerToken})
+		}
+		for _, b := range buckets {
+			ok, retryAfter, err := opts.Store.Allow(ctx, b.key, b.limit)
+			if err != nil {
+				config.Logger(ctx).WarnContext(ctx, "rate limit store failed; allowing request", "error", err)
+				continue
+			}
+			if !ok {
+				secs := int(math.Ceil(retryAfter.Seconds()))
+				if secs < 1 {
+					secs = 1
+				}
+				c.Header("Retry-After", strconv.Itoa(secs))
+				problem.Abort(c, http.StatusTooManyRequests, problem.CodeRateLimited, "rate limit exceeded; retry after "+strconv.Itoa(secs)+"s")
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// TrustedProxiesFromEnv reads TRUSTED_PROXIES, a comma-separated list of
+// proxy IPs or CIDRs whose X-Forwarded-For / X-Real-IP headers are believed.
+// Unset means none: the client IP is the connection's remote address.
+func TrustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, p := range strings.Split(config.EnvOr("TRUSTED_PROXIES", ""), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
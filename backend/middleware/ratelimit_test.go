@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateLimitStore_tokenBucket(t *testing.T) {
+	s := NewMemoryRateLimitStore()
+	now := time.Unix(1000, 0)
+	s.now = func() time.Time { return now }
+	lim := Limit{PerMinute: 60, Burst: 2} // one token per second
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		ok, _, _ := s.Allow(ctx, "k", lim)
+		require.True(t, ok)
+	}
+	ok, retry, _ := s.Allow(ctx, "k", lim)
+	require.False(t, ok)
+	require.Equal(t, time.Second, retry)
+
+	ok, _, _ = s.Allow(ctx, "other", lim)
+	require.True(t, ok, "buckets are per key")
+
+	now = now.Add(1500 * time.Millisecond)
+	ok, _, _ = s.Allow(ctx, "k", lim)
+	require.True(t, ok)
+	ok, retry, _ = s.Allow(ctx, "k", lim)
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, retry)
+
+	ok, _, _ = s.Allow(ctx, "k", Limit{})
+	require.True(t, ok, "a zero limit disables limiting")
+
+	// idle, refilled buckets are swept
+	now = now.Add(time.Hour)
+	s.Allow(ctx, "fresh", lim)
+	require.Len(t, s.buckets, 1)
+}
+
+type failingStore struct{}
+
+func (failingStore) Allow(context.Context, string, Limit) (bool, time.Duration, error) {
+	return false, 0, errors.New("store down")
+}
+
+func TestRateLimit_perIPAndPerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RateLimit(RateLimitOptions{
+		PerIP:      Limit{PerMinute: 1, Burst: 2},
+		PerToken:   Limit{PerMinute: 1, Burst: 2},
+		Store:      NewMemoryRateLimitStore(),
+		ValidToken: StaticTokens([]string{"t1", "t2"}),
+	}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(token, ip string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, get("", "10.0.0.1").Code)
+	require.Equal(t, http.StatusOK, get("", "10.0.0.1").Code)
+	rec := get("", "10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "60", rec.Header().Get("Retry-After"))
+	require.Equal(t, http.StatusOK, get("", "10.0.0.2").Code)
+
+	// unknown tokens do not get a bucket of their own
+	require.Equal(t, http.StatusTooManyRequests, get("random-1", "10.0.0.1").Code)
+	require.Equal(t, http.StatusTooManyRequests, get("random-2", "10.0.0.1").Code)
+
+	// a validated token has its own bucket across addresses, and each
+	// address is still charged
+	require.Equal(t, http.StatusOK, get("t1", "10.0.0.3").Code)
+	require.Equal(t, http.StatusOK, get("t1", "10.0.0.4").Code)
+	require.Equal(t, http.StatusTooManyRequests, get("t1", "10.0.0.5").Code)
+	require.Equal(t, http.StatusOK, get("t2", "10.0.0.4").Code)
+	require.Equal(t, http.StatusTooManyRequests, get("t2", "10.0.0.4").Code, "10.0.0.4 is out of tokens")
+
+	r2 := gin.New()
+	r2.Use(RateLimit(RateLimitOptions{PerIP: Limit{PerMinute: 1, Burst: 1}, Store: failingStore{}}))
+	r2.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	rec = httptest.NewRecorder()
+	r2.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "store errors fail open")
+}
+
+func TestRateLimit_forwardedForOnlyFromTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(proxies []string) *gin.Engine {
+		r := gin.New()
+		require.NoError(t, r.SetTrustedProxies(proxies))
+		r.Use(RateLimit(RateLimitOptions{PerIP: Limit{PerMinute: 1, Burst: 1}, Store: NewMemoryRateLimitStore()}))
+		r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+	get := func(r *gin.Engine, forwardedFor string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.0.2.10:1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	untrusted := newRouter(nil)
+	require.Equal(t, http.StatusOK, get(untrusted, "203.0.113.1"))
+	require.Equal(t, http.StatusTooManyRequests, get(untrusted, "203.0.113.2"), "a spoofed header is ignored")
+
+	trusted := newRouter([]string{"192.0.2.0/24"})
+	require.Equal(t, http.StatusOK, get(trusted, "203.0.113.1"))
+	require.Equal(t, http.StatusOK, get(trusted, "203.0.113.2"))
+	require.Equal(t, http.StatusTooManyRequests, get(trusted, "203.0.113.1"))
+}
+
+func TestStaticTokens(t *testing.T) {
+	valid := StaticTokens([]string{" a ", "", "b"})
+	ctx := context.Background()
+	require.True(t, valid(ctx, "a"))
+	require.True(t, valid(ctx, "b"))
+	require.False(t, valid(ctx, "c"))
+	require.False(t, valid(ctx, ""))
+	require.False(t, StaticTokens(nil)(ctx, ""))
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(BodyLimit(10))
+	echo := func(c *gin.Context) {
+		b, _ := c.GetRawData()
+		c.String(http.StatusOK, string(b))
+	}
+	r.POST("/", echo)
+	r.GET("/", echo)
+
+	do := func(method, body string, chunked bool) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := do(http.MethodPost, `{"a":1}`, false)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `{"a":1}`, rec.Body.String())
+	require.Equal(t, http.StatusRequestEntityTooLarge, do(http.MethodPost, strings.Repeat("x", 11), false).Code)
+	require.Equal(t, http.StatusRequestEntityTooLarge, do(http.MethodPost, strings.Repeat("x", 11), true).Code,
+		"bodies without Content-Length are capped while reading")
+	require.Equal(t, http.StatusOK, do(http.MethodGet, strings.Repeat("x", 11), false).Code, "only mutating methods")
+}
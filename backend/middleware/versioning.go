@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersionKey holds the version of the route group that matched ("v1").
+// It is empty on the deprecated unversioned /api alias, whose handlers keep
+// the pre-v1 response shapes.
+const APIVersionKey = "api_version"
+
+// APIVersion marks every request of a group with version v.
+func APIVersion(v string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(APIVersionKey, v)
+		c.Next()
+	}
+}
+
+// Deprecation and sunset of the unversioned /api alias. Override the sunset
+// with LEGACY_API_SUNSET (YYYY-MM-DD).
+var (
+	LegacyAPIDeprecatedAt  = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	DefaultLegacyAPISunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// DeprecationOptions configures Deprecation.
+type DeprecationOptions struct {
+	Since  time.Time // when the routes were deprecated
+	Sunset time.Time // when they stop working
+	// From and To rewrite the request path into its successor, e.g. "/api"
+	// and "/api/v1"; no Link header is sent when From is empty.
+	From, To string
+}
+
+// LegacyAPIOptionsFromEnv describes the /api alias of /api/v1.
+func LegacyAPIOptionsFromEnv() DeprecationOptions {
+	sunset := DefaultLegacyAPISunset
+	if t, err := time.Parse(time.DateOnly, config.EnvOr("LEGACY_API_SUNSET", "")); err == nil {
+		sunset = t
+	}
+	return DeprecationOptions{Since: LegacyAPIDeprecatedAt, Sunset: sunset, From: "/api", To: "/api/v1"}
+}
+
+// Deprecation marks every response of a group as deprecated: Deprecation
+// (RFC 9745, "@<unix time>"), Sunset (RFC 8594, an HTTP date) and a Link to
+// the successor-version of the requested path.
+func Deprecation(opts DeprecationOptions) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(opts.Since.Unix(), 10)
+	sunset := opts.Sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Deprecation", deprecation)
+		h.Set("Sunset", sunset)
+		if opts.From != "" {
+			if rest, ok := strings.CutPrefix(c.Request.URL.Path, opts.From); ok {
+				h.Add("Link", "<"+opts.To+rest+`>; rel="successor-version"`)
+			}
+		}
+		c.Next()
+	}
+}
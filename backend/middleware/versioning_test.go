@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecation(t *testing.T) {
+	t.Setenv("LEGACY_API_SUNSET", "2027-01-31")
+	r := gin.New()
+	opts := LegacyAPIOptionsFromEnv()
+	require.Equal(t, time.Date(2027, time.January, 31, 0, 0, 0, 0, time.UTC), opts.Sunset)
+	r.GET("/api/menus/:id", Deprecation(opts), func(c *gin.Context) {
+		require.Empty(t, c.GetString(APIVersionKey))
+		c.Status(http.StatusOK)
+	})
+	r.GET("/api/v1/menus/:id", APIVersion("v1"), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(APIVersionKey))
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/menus/3", nil))
+	require.Equal(t, "@1792281600", rec.Header().Get("Deprecation"))
+	require.Equal(t, "Sun, 31 Jan 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+	require.Equal(t, `</api/v1/menus/3>; rel="successor-version"`, rec.Header().Get("Link"))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/menus/3", nil))
+	require.Equal(t, "v1", rec.Body.String())
+	require.Empty(t, rec.Header().Get("Deprecation"))
+
+	t.Setenv("LEGACY_API_SUNSET", "soon")
+	require.Equal(t, DefaultLegacyAPISunset, LegacyAPIOptionsFromEnv().Sunset, "invalid dates fall back to the default")
+}
@@ -0,0 +1,33 @@
+-- Migration: webhook subscriptions and delivery log (MySQL)
+CREATE TABLE IF NOT EXISTS `webhook_subscriptions` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `url` VARCHAR(1024) NOT NULL,
+  `secret` VARCHAR(255) NOT NULL,
+  `events` VARCHAR(255) DEFAULT NULL,
+  `active` TINYINT(1) DEFAULT 1,
+  `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX `idx_webhook_subscriptions_active` (`active`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `subscription_id` BIGINT UNSIGNED NOT NULL,
+  `event_type` VARCHAR(32) NOT NULL,
+  `revision` BIGINT UNSIGNED DEFAULT NULL,
+  `payload` TEXT NOT NULL,
+  `status` VARCHAR(16) NOT NULL,
+  `attempts` BIGINT DEFAULT 0,
+  `last_status_code` BIGINT DEFAULT NULL,
+  `last_error` VARCHAR(1024) DEFAULT NULL,
+  `next_attempt_at` DATETIME DEFAULT NULL,
+  `delivered_at` DATETIME DEFAULT NULL,
+  `redelivery_of` BIGINT UNSIGNED DEFAULT NULL,
+  `created_at` DATETIME DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX `idx_webhook_deliveries_subscription_id` (`subscription_id`),
+  INDEX `idx_webhook_deliveries_status` (`status`),
+  INDEX `idx_webhook_deliveries_next_attempt_at` (`next_attempt_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,10 @@
+-- Migration: menu change log for incremental sync (MySQL)
+CREATE TABLE IF NOT EXISTS `menu_changes` (
+  `seq` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `menu_id` BIGINT UNSIGNED NOT NULL,
+  `op` VARCHAR(16) NOT NULL,
+  `changed_at` DATETIME(3) NOT NULL,
+  PRIMARY KEY (`seq`),
+  INDEX `idx_menu_changes_menu_id` (`menu_id`),
+  INDEX `idx_menu_changes_changed_at` (`changed_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
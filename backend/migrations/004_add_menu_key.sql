@@ -0,0 +1,6 @@
+-- Migration: stable, human-readable menu keys (MySQL)
+-- Existing rows stay NULL here; the backend fills them from the titles on
+-- startup (services.BackfillMenuKeys). NULLs do not collide in the index.
+ALTER TABLE `menus`
+  ADD COLUMN `menu_key` VARCHAR(100) DEFAULT NULL AFTER `title`,
+  ADD UNIQUE INDEX `idx_menus_menu_key` (`menu_key`);
@@ -0,0 +1,5 @@
+-- Migration: delivery claims, so several backend replicas send each webhook
+-- delivery once (MySQL)
+ALTER TABLE `webhook_deliveries`
+  ADD COLUMN `claimed_at` DATETIME DEFAULT NULL AFTER `next_attempt_at`,
+  ADD INDEX `idx_webhook_deliveries_claimed_at` (`claimed_at`);
@@ -1,15 +1,21 @@
 package models
 
 import (
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"gorm.io/gorm"
 )
 
-// Menu represents a hierarchical menu item stored in the DB.
+// Menu represents a hierarchical menu item stored in the DB. Key is a unique,
+// human-readable slug ("billing") that stays the same across environments; its
+// column is menu_key because KEY is reserved in MySQL.
 type Menu struct {
 	ID        uint           `gorm:":primaryKey" json:"id"`
 	Title     string         `gorm:"size:255;not null" json:"title"`
+	Key       string         `gorm:"column:menu_key;size:100;uniqueIndex" json:"key"`
 	URL       *string        `gorm:"size:1024" json:"url,omitempty"`
 	Icon      *string        `gorm:"size:255" json:"icon,omitempty"`
 	ParentID  *uint          `gorm:"index" json:"parent_id,omitempty"`
@@ -22,6 +28,7 @@ type Menu struct {
 // MenuNode is the API representation with nested children.
 type MenuNode struct {
 	ID       uint        `json:"id"`
+	Key      string      `json:"key,omitempty"`
 	Title    string      `json:"title"`
 	URL      *string     `json:"url,omitempty"`
 	ParentID *uint       `json:"parent_id,omitempty"`
@@ -33,9 +40,86 @@ type MenuNode struct {
 func (m *Menu) ToNode() *MenuNode {
 	return &MenuNode{
 		ID:       m.ID,
+		Key:      m.Key,
 		Title:    m.Title,
 		URL:      m.URL,
 		ParentID: m.ParentID,
 		Order:    m.Order,
 	}
 }
+
+// MaxKeyLen bounds menu keys.
+const MaxKeyLen = 100
+
+// Slugify derives a key from a title: lower-case ASCII letters and digits
+// separated by single dashes ("Billing & Invoices" -> "billing-invoices").
+// Titles without any usable character yield "item".
+func Slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+		if b.Len() >= MaxKeyLen-8 { // leave room for a "-<n>" suffix
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "item"
+	}
+	return strings.TrimRight(b.String(), "-")
+}
+
+// ValidKey reports whether key has the shape Slugify produces.
+func ValidKey(key string) bool {
+	if key == "" || len(key) > MaxKeyLen || key[0] == '-' || key[len(key)-1] == '-' || strings.Contains(key, "--") {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// UniqueKey returns base, or base-2, base-3, ... when taken (soft-deleted rows
+// included, since the unique index covers them).
+func UniqueKey(tx *gorm.DB, base string) (string, error) {
+	var taken []string
+	err := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&Menu{}).
+		Where("menu_key = ? OR menu_key LIKE ?", base, base+"-%").Pluck("menu_key", &taken).Error
+	if err != nil {
+		return "", err
+	}
+	used := make(map[string]bool, len(taken))
+	for _, k := range taken {
+		used[k] = true
+	}
+	key := base
+	for n := 2; used[key]; n++ {
+		key = base + "-" + strconv.Itoa(n)
+	}
+	return key, nil
+}
+
+// BeforeCreate generates a unique Key from the title when none is set.
+func (m *Menu) BeforeCreate(tx *gorm.DB) error {
+	if m.Key != "" {
+		return nil
+	}
+	key, err := UniqueKey(tx, Slugify(m.Title))
+	if err != nil {
+		return err
+	}
+	m.Key = key
+	return nil
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Change log operations.
+const (
+	ChangeUpsert = "upsert"
+	ChangeDelete = "delete" // tombstone: the menu no longer exists
+)
+
+// MenuChange is one entry of the menu change log used for incremental sync.
+// Seq is the monotonically increasing change sequence clients sync from.
+type MenuChange struct {
+	Seq       uint64    `gorm:"primaryKey;autoIncrement" json:"seq"`
+	MenuID    uint      `gorm:"not null;index" json:"menu_id"`
+	Op        string    `gorm:"size:16;not null" json:"op"`
+	ChangedAt time.Time `gorm:"not null;index" json:"changed_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// All returns every model the application persists, in migration order.
+// main.go auto-migrates them and readiness checks that their tables exist.
+func All() []interface{} {
+	return []interface{}{
+		&Menu{},
+		&MenuChange{},
+		&WebhookSubscription{},
+		&WebhookDelivery{},
+	}
+}
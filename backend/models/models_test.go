@@ -1,6 +1,7 @@
 package models_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/galpt/sotekre/backend/models"
@@ -19,3 +20,17 @@ func TestMenu_ToNode(t *testing.T) {
 	require.Equal(t, 5, n.Order)
 	require.Nil(t, n.Children)
 }
+
+func TestSlugify(t *testing.T) {
+	require.Equal(t, "billing-invoices", models.Slugify("  Billing & Invoices! "))
+	require.Equal(t, "faq-2024", models.Slugify("FAQ / 2024"))
+	require.Equal(t, "item", models.Slugify("日本語"))
+	long := models.Slugify(strings.Repeat("ab ", 100))
+	require.LessOrEqual(t, len(long), models.MaxKeyLen-8)
+	require.True(t, models.ValidKey(long))
+
+	require.True(t, models.ValidKey("billing-2"))
+	for _, k := range []string{"", "Billing", "-x", "x-", "a--b", "a b", "a_b"} {
+		require.False(t, models.ValidKey(k), k)
+	}
+}
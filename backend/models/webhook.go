@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookSubscription is an outbound endpoint notified about menu changes.
+// Events is a comma-separated filter of event types; empty means all. Active
+// has no column default on purpose: GORM leaves zero values out of INSERTs
+// when a default exists, which would store Active: false as true.
+type WebhookSubscription struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	URL       string    `gorm:"size:1024;not null" json:"url"`
+	Secret    string    `gorm:"size:255;not null" json:"-"`
+	Events    string    `gorm:"size:255" json:"events"`
+	Active    bool      `gorm:"not null;index" json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Wants reports whether the subscription's filter includes eventType.
+func (s *WebhookSubscription) Wants(eventType string) bool {
+	if strings.TrimSpace(s.Events) == "" {
+		return true
+	}
+	for _, e := range strings.Split(s.Events, ",") {
+		if strings.TrimSpace(e) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one queued/attempted POST of an event to a subscription.
+// Rows double as the delivery log.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SubscriptionID uint       `gorm:"index;not null" json:"subscription_id"`
+	EventType      string     `gorm:"size:32;not null" json:"event_type"`
+	Revision       uint64     `json:"revision"`
+	Payload        string     `gorm:"type:text;not null" json:"payload"`
+	Status         string     `gorm:"size:16;not null;index" json:"status"`
+	Attempts       int        `gorm:"default:0" json:"attempts"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `gorm:"size:1024" json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `gorm:"index" json:"next_attempt_at,omitempty"`
+	// ClaimedAt is set while a dispatcher (of any replica) is sending the
+	// delivery; claims older than the dispatcher's lease are taken over.
+	ClaimedAt    *time.Time `gorm:"index" json:"-"`
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
+	RedeliveryOf *uint      `json:"redelivery_of,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,121 @@
+// Package problem writes RFC 7807 `application/problem+json` error responses.
+// Every error the API returns carries a stable Code clients can switch on; the
+// human-readable Detail may change between releases.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of every error response.
+const ContentType = "application/problem+json"
+
+// Stable error codes.
+const (
+	CodeValidationFailed     = "validation_failed"
+	CodeMalformedBody        = "malformed_body"
+	CodeInvalidID            = "invalid_id"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeMenuNotFound         = "menu_not_found"
+	CodeParentNotFound       = "parent_not_found"
+	CodeCycleDetected        = "cycle_detected"
+	CodeChildSetMismatch     = "child_set_mismatch"
+	CodeKeyConflict          = "key_conflict"
+	CodeChangesExpired       = "changes_expired"
+	CodeUnsupportedMedia     = "unsupported_media_type"
+	CodePatchTestFailed      = "patch_test_failed"
+	CodePatchNotApplied      = "patch_not_applicable"
+	CodeWebhookNotFound      = "webhook_not_found"
+	CodeRouteNotFound        = "route_not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeQueryTooDeep         = "query_too_deep"
+	CodeQueryTooComplex      = "query_too_complex"
+	CodeDocsNotGenerated     = "docs_not_generated"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeRateLimited          = "rate_limited"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeIdempotencyInFlight  = "idempotency_key_in_flight"
+	CodeSiteURLUnset         = "site_url_not_configured"
+	CodeInternal             = "internal_error"
+)
+
+// FieldError is one invalid input field.
+type FieldError struct {
+	Field   string `json:"field" example:"url"`
+	Message string `json:"message" example:"scheme \"javascript\" is not allowed"`
+}
+
+// Problem is an RFC 7807 problem details object. Type is always
+// "about:blank", so Title is the HTTP status text; Code identifies the error.
+// Extensions are extra top-level members (e.g. "cursor").
+type Problem struct {
+	Type       string                 `json:"type" example:"about:blank"`
+	Title      string                 `json:"title" example:"Bad Request"`
+	Status     int                    `json:"status" example:"400"`
+	Code       string                 `json:"code" example:"validation_failed"`
+	Detail     string                 `json:"detail,omitempty" example:"request failed validation"`
+	Instance   string                 `json:"instance,omitempty" example:"/api/menus"`
+	RequestID  string                 `json:"request_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	Errors     []FieldError           `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON flattens Extensions into the top-level object. Standard
+// members win over extensions of the same name.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	b, err := json.Marshal(plain(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return b, err
+	}
+	out := make(map[string]interface{}, len(p.Extensions)+8)
+	for k, v := range p.Extensions {
+		out[k] = v
+	}
+	var std map[string]interface{}
+	if err := json.Unmarshal(b, &std); err != nil {
+		return nil, err
+	}
+	for k, v := range std {
+		out[k] = v
+	}
+	return json.Marshal(out)
+}
+
+// New returns a problem for status with the given code and detail.
+func New(status int, code, detail string) Problem {
+	return Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Code: code, Detail: detail}
+}
+
+// Validation returns a 400 validation_failed problem listing fields.
+func Validation(fields []FieldError) Problem {
+	p := New(http.StatusBadRequest, CodeValidationFailed, "request failed validation")
+	p.Errors = fields
+	return p
+}
+
+// Write aborts c with p. Instance defaults to the request path and RequestID
+// to the X-Request-ID response header set by the RequestID middleware.
+func Write(c *gin.Context, p Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = c.Writer.Header().Get("X-Request-ID")
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		c.AbortWithStatus(p.Status)
+		return
+	}
+	c.Abort()
+	c.Data(p.Status, ContentType, b)
+}
+
+// Abort writes a problem built from status, code and detail.
+func Abort(c *gin.Context, status int, code, detail string) {
+	Write(c, New(status, code, detail))
+}
@@ -0,0 +1,47 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite_problemJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/menus/changes", nil)
+	c.Header("X-Request-ID", "req-1")
+
+	p := New(http.StatusGone, CodeChangesExpired, "too old")
+	p.Extensions = map[string]interface{}{"cursor": 7, "code": "ignored"}
+	Write(c, p)
+
+	require.True(t, c.IsAborted())
+	require.Equal(t, http.StatusGone, rec.Code)
+	require.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, map[string]interface{}{
+		"type":       "about:blank",
+		"title":      "Gone",
+		"status":     float64(410),
+		"code":       "changes_expired",
+		"detail":     "too old",
+		"instance":   "/api/menus/changes",
+		"request_id": "req-1",
+		"cursor":     float64(7),
+	}, got)
+}
+
+func TestValidation_listsFields(t *testing.T) {
+	p := Validation([]FieldError{{Field: "url", Message: "bad"}})
+	b, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"code":"validation_failed",
+		"detail":"request failed validation","errors":[{"field":"url","message":"bad"}]}`, string(b))
+}
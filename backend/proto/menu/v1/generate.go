@@ -0,0 +1,8 @@
+// Package menuv1 holds the generated code for menu.proto. After editing the
+// .proto, regenerate from the backend directory with protoc and the
+// protoc-gen-go (v1.35.1) and protoc-gen-go-grpc (v1.5.1) plugins:
+//
+//	go generate ./proto/...
+package menuv1
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative proto/menu/v1/menu.proto
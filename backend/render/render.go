@@ -0,0 +1,196 @@
+// Package render turns menus into output for other consumers: the tree built
+// by services.BuildTree as HTML or Markdown for server-rendered and static
+// sites, and sitemap.xml and schema.org breadcrumbs for crawlers. It only
+// depends on models, so other services can import it and render menus they
+// fetched themselves.
+package render
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/galpt/sotekre/backend/models"
+)
+
+// HTMLOptions configures HTML. Empty class names are omitted.
+type HTMLOptions struct {
+	ListClass    string // every <ul>
+	SubListClass string // nested <ul>; ListClass when empty
+	ItemClass    string // every <li>
+	LinkClass    string // every <a> (and <span> for items without a URL)
+
+	// CurrentID marks the item for the page being shown: its link gets
+	// aria-current and its <li> ActiveClass; the <li>s of its ancestors get
+	// AncestorClass, so the path to it can be expanded. 0 marks nothing.
+	CurrentID     uint
+	AriaCurrent   string // aria-current value; "page" when empty
+	ActiveClass   string
+	AncestorClass string
+}
+
+// MarkdownOptions configures Markdown.
+type MarkdownOptions struct {
+	// CurrentID is rendered in bold. 0 marks nothing.
+	CurrentID uint
+	// Indent is prefixed once per nesting level; two spaces when empty.
+	Indent string
+}
+
+// unsafeSchemes are never emitted as links, whatever the input.
+var unsafeSchemes = map[string]bool{"javascript": true, "vbscript": true, "data": true}
+
+// linkURL returns the URL an item links to, or "" when it has none or it is
+// not safe to link.
+func linkURL(n *models.MenuNode) string {
+	if n.URL == nil {
+		return ""
+	}
+	s := strings.TrimSpace(*n.URL)
+	u, err := url.Parse(s)
+	if err != nil || unsafeSchemes[strings.ToLower(u.Scheme)] {
+		return ""
+	}
+	return s
+}
+
+// ancestorsOf returns the ids of the ancestors of id in tree.
+func ancestorsOf(tree []*models.MenuNode, id uint) map[uint]bool {
+	if id == 0 {
+		return nil
+	}
+	var chain []uint
+	var find func(level []*models.MenuNode) bool
+	find = func(level []*models.MenuNode) bool {
+		for _, n := range level {
+			if n.ID == id {
+				return true
+			}
+			chain = append(chain, n.ID)
+			if find(n.Children) {
+				return true
+			}
+			chain = chain[:len(chain)-1]
+		}
+		return false
+	}
+	if !find(tree) {
+		return nil
+	}
+	out := make(map[uint]bool, len(chain))
+	for _, a := range chain {
+		out[a] = true
+	}
+	return out
+}
+
+func classAttr(classes ...string) string {
+	var parts []string
+	for _, c := range classes {
+		if c != "" {
+			parts = append(parts, c)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return ` class="` + html.EscapeString(strings.Join(parts, " ")) + `"`
+}
+
+// HTML writes tree as nested <ul>/<li>/<a> lists. Items without a URL are
+// rendered as <span>. Titles and URLs are escaped; javascript:, vbscript: and
+// data: URLs are not linked. An empty tree writes nothing.
+func HTML(w io.Writer, tree []*models.MenuNode, opts HTMLOptions) error {
+	if opts.AriaCurrent == "" {
+		opts.AriaCurrent = "page"
+	}
+	if opts.SubListClass == "" {
+		opts.SubListClass = opts.ListClass
+	}
+	ancestors := ancestorsOf(tree, opts.CurrentID)
+	var b strings.Builder
+	var list func(level []*models.MenuNode, depth int)
+	list = func(level []*models.MenuNode, depth int) {
+		if len(level) == 0 {
+			return
+		}
+		indent := strings.Repeat("  ", 2*depth)
+		cls := opts.ListClass
+		if depth > 0 {
+			cls = opts.SubListClass
+		}
+		fmt.Fprintf(&b, "%s<ul%s>\n", indent, classAttr(cls))
+		for _, n := range level {
+			current := opts.CurrentID != 0 && n.ID == opts.CurrentID
+			liClass := []string{opts.ItemClass}
+			if current {
+				liClass = append(liClass, opts.ActiveClass)
+			} else if ancestors[n.ID] {
+				liClass = append(liClass, opts.AncestorClass)
+			}
+			fmt.Fprintf(&b, "%s  <li%s>", indent, classAttr(liClass...))
+			aria := ""
+			if current {
+				aria = ` aria-current="` + html.EscapeString(opts.AriaCurrent) + `"`
+			}
+			title := html.EscapeString(n.Title)
+			if href := linkURL(n); href != "" {
+				fmt.Fprintf(&b, `<a%s href="%s"%s>%s</a>`, classAttr(opts.LinkClass), html.EscapeString(href), aria, title)
+			} else {
+				fmt.Fprintf(&b, `<span%s%s>%s</span>`, classAttr(opts.LinkClass), aria, title)
+			}
+			if len(n.Children) > 0 {
+				b.WriteString("\n")
+				list(n.Children, depth+1)
+				b.WriteString(indent + "  ")
+			}
+			b.WriteString("</li>\n")
+		}
+		fmt.Fprintf(&b, "%s</ul>\n", indent)
+	}
+	list(tree, 0)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscaper escapes the characters that would start emphasis, code or
+// a link inside a list item's text.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+)
+
+// markdownURL wraps destinations Markdown would otherwise cut short.
+func markdownURL(s string) string {
+	if strings.ContainsAny(s, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(s) + ">"
+	}
+	return s
+}
+
+// Markdown writes tree as a nested bullet list of links ("- [Title](/url)").
+// Items without a (safe) URL are plain text.
+func Markdown(w io.Writer, tree []*models.MenuNode, opts MarkdownOptions) error {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	var b strings.Builder
+	var list func(level []*models.MenuNode, depth int)
+	list = func(level []*models.MenuNode, depth int) {
+		for _, n := range level {
+			text := markdownEscaper.Replace(n.Title)
+			if href := linkURL(n); href != "" {
+				text = "[" + text + "](" + markdownURL(href) + ")"
+			}
+			if opts.CurrentID != 0 && n.ID == opts.CurrentID {
+				text = "**" + text + "**"
+			}
+			b.WriteString(strings.Repeat(opts.Indent, depth) + "- " + text + "\n")
+			list(n.Children, depth+1)
+		}
+	}
+	list(tree, 0)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
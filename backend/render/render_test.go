@@ -0,0 +1,60 @@
+package render_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/render"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleTree() []*models.MenuNode {
+	u := func(s string) *string { return &s }
+	return []*models.MenuNode{
+		{ID: 1, Title: "Settings", URL: u("/settings"), Children: []*models.MenuNode{
+			{ID: 2, Title: "Billing & Cards", URL: u("/settings/billing")},
+			{ID: 3, Title: "Evil", URL: u("javascript:alert(1)")},
+		}},
+		{ID: 4, Title: "Help [beta]", URL: u("/help (old)")},
+		{ID: 5, Title: "Group"},
+	}
+}
+
+func TestHTML(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, render.HTML(&b, sampleTree(), render.HTMLOptions{
+		ListClass: "nav", SubListClass: "nav-sub", ItemClass: "nav-item", LinkClass: "nav-link",
+		CurrentID: 2, ActiveClass: "active", AncestorClass: "open",
+	}))
+	require.Equal(t, `<ul class="nav">
+  <li class="nav-item open"><a class="nav-link" href="/settings">Settings</a>
+    <ul class="nav-sub">
+      <li class="nav-item active"><a class="nav-link" href="/settings/billing" aria-current="page">Billing &amp; Cards</a></li>
+      <li class="nav-item"><span class="nav-link">Evil</span></li>
+    </ul>
+  </li>
+  <li class="nav-item"><a class="nav-link" href="/help (old)">Help [beta]</a></li>
+  <li class="nav-item"><span class="nav-link">Group</span></li>
+</ul>
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, render.HTML(&b, sampleTree()[1:2], render.HTMLOptions{}))
+	require.Equal(t, "<ul>\n  <li><a href=\"/help (old)\">Help [beta]</a></li>\n</ul>\n", b.String())
+
+	b.Reset()
+	require.NoError(t, render.HTML(&b, nil, render.HTMLOptions{}))
+	require.Empty(t, b.String())
+}
+
+func TestMarkdown(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, render.Markdown(&b, sampleTree(), render.MarkdownOptions{CurrentID: 1}))
+	require.Equal(t, `- **[Settings](/settings)**
+  - [Billing & Cards](/settings/billing)
+  - Evil
+- [Help \[beta\]](</help (old)>)
+- Group
+`, b.String())
+}
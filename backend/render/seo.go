@@ -0,0 +1,137 @@
+package render
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/galpt/sotekre/backend/models"
+)
+
+// PageURL resolves a menu item URL against the site's base URL (e.g.
+// https://example.com or https://example.com/app). ok is false for URLs that
+// do not name a page of the site: external links (another host or a non-HTTP
+// scheme such as mailto:), fragment-only links and pattern URLs with
+// ":name" segments. Fragments are dropped.
+func PageURL(raw string, base *url.URL) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+	u.Fragment, u.RawFragment = "", ""
+	if u.Scheme != "" || u.Host != "" {
+		if (u.Scheme != "http" && u.Scheme != "https") || !strings.EqualFold(u.Host, base.Host) || hasPattern(u.Path) {
+			return "", false
+		}
+		return u.String(), true
+	}
+	if u.Path == "" || hasPattern(u.Path) {
+		return "", false
+	}
+	path := u.EscapedPath()
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	out := strings.TrimRight(base.String(), "/") + path
+	if u.RawQuery != "" {
+		out += "?" + u.RawQuery
+	}
+	return out, true
+}
+
+func hasPattern(path string) bool {
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, ":") {
+			return true
+		}
+	}
+	return false
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// Sitemap writes a sitemaps.org urlset with one <url> per page that menus
+// link to (see PageURL), sorted by location. lastmod is the UpdatedAt of the
+// item; when several items link to the same page the latest one wins.
+func Sitemap(w io.Writer, menus []models.Menu, base *url.URL) error {
+	latest := map[string]time.Time{}
+	for _, m := range menus {
+		if m.URL == nil {
+			continue
+		}
+		loc, ok := PageURL(*m.URL, base)
+		if !ok {
+			continue
+		}
+		if t, seen := latest[loc]; !seen || m.UpdatedAt.After(t) {
+			latest[loc] = m.UpdatedAt
+		}
+	}
+	set := sitemapURLSet{URLs: make([]sitemapURL, 0, len(latest))}
+	for loc, t := range latest {
+		u := sitemapURL{Loc: loc}
+		if !t.IsZero() {
+			u.LastMod = t.UTC().Format(time.RFC3339)
+		}
+		set.URLs = append(set.URLs, u)
+	}
+	sort.Slice(set.URLs, func(i, j int) bool { return set.URLs[i].Loc < set.URLs[j].Loc })
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(set); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// BreadcrumbItem is one schema.org ListItem.
+type BreadcrumbItem struct {
+	Type     string `json:"@type"`
+	Position int    `json:"position"`
+	Name     string `json:"name"`
+	Item     string `json:"item"`
+}
+
+// BreadcrumbList is a schema.org BreadcrumbList, ready to be marshalled as
+// JSON-LD.
+type BreadcrumbList struct {
+	Context         string           `json:"@context"`
+	Type            string           `json:"@type"`
+	ItemListElement []BreadcrumbItem `json:"itemListElement"`
+}
+
+// Breadcrumbs builds the BreadcrumbList for chain, an item's ancestors from
+// the root down followed by the item itself. Entries without a page URL (see
+// PageURL) are left out and the remaining ones numbered from 1.
+func Breadcrumbs(chain []*models.MenuNode, base *url.URL) BreadcrumbList {
+	list := BreadcrumbList{Context: "https://schema.org", Type: "BreadcrumbList", ItemListElement: []BreadcrumbItem{}}
+	for _, n := range chain {
+		if n.URL == nil {
+			continue
+		}
+		loc, ok := PageURL(*n.URL, base)
+		if !ok {
+			continue
+		}
+		list.ItemListElement = append(list.ItemListElement, BreadcrumbItem{
+			Type: "ListItem", Position: len(list.ItemListElement) + 1, Name: n.Title, Item: loc,
+		})
+	}
+	return list
+}
@@ -0,0 +1,73 @@
+package render_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/render"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageURL(t *testing.T) {
+	base, _ := url.Parse("https://example.com/app/")
+	for raw, want := range map[string]string{
+		"/settings":                   "https://example.com/app/settings",
+		"docs/faq?x=1#top":            "https://example.com/app/docs/faq?x=1",
+		"https://EXAMPLE.com/pricing": "https://EXAMPLE.com/pricing",
+		"https://other.com/settings":  "",
+		"mailto:help@example.com":     "",
+		"#top":                        "",
+		"/orders/:id":                 "",
+		"https://example.com/o/:id/x": "",
+	} {
+		got, ok := render.PageURL(raw, base)
+		require.Equal(t, want, got, raw)
+		require.Equal(t, want != "", ok, raw)
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	u := func(s string) *string { return &s }
+	t1 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	base, _ := url.Parse("https://example.com")
+	var b strings.Builder
+	require.NoError(t, render.Sitemap(&b, []models.Menu{
+		{Title: "Settings", URL: u("/settings"), UpdatedAt: t1},
+		{Title: "Also settings", URL: u("/settings#billing"), UpdatedAt: t2},
+		{Title: "Home", URL: u("/"), UpdatedAt: t1},
+		{Title: "Group"},
+		{Title: "GitHub", URL: u("https://github.com/galpt"), UpdatedAt: t1},
+	}, base))
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/</loc>
+    <lastmod>2026-01-02T03:04:05Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.com/settings</loc>
+    <lastmod>2026-01-02T04:04:05Z</lastmod>
+  </url>
+</urlset>
+`, b.String())
+}
+
+func TestBreadcrumbs(t *testing.T) {
+	u := func(s string) *string { return &s }
+	base, _ := url.Parse("https://example.com")
+	list := render.Breadcrumbs([]*models.MenuNode{
+		{ID: 1, Title: "Settings", URL: u("/settings")},
+		{ID: 2, Title: "Account"},
+		{ID: 3, Title: "Billing", URL: u("/settings/billing")},
+	}, base)
+	b, err := json.Marshal(list)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"@context":"https://schema.org","@type":"BreadcrumbList","itemListElement":[
+		{"@type":"ListItem","position":1,"name":"Settings","item":"https://example.com/settings"},
+		{"@type":"ListItem","position":2,"name":"Billing","item":"https://example.com/settings/billing"}]}`, string(b))
+}
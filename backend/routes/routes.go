@@ -1,20 +1,90 @@
 package routes
 
 import (
+	"context"
+	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 
 	"github.com/galpt/sotekre/backend/handlers"
+	"github.com/galpt/sotekre/backend/metrics"
+	"github.com/galpt/sotekre/backend/middleware"
+	"github.com/galpt/sotekre/backend/problem"
+	"github.com/galpt/sotekre/backend/services"
+	"github.com/galpt/sotekre/backend/tracing"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// tracedRequest skips spans for probes and scrapes, which would otherwise
+// drown real traffic.
+func tracedRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/metrics":
+		return false
+	}
+	return true
+}
+
+// registerAPI adds the REST routes to api (/api/v1 or the /api alias).
+func registerAPI(api *gin.RouterGroup) {
+	menus := api.Group("/menus")
+	{
+		// Register both with and without trailing slash for compatibility
+		// Frontend calls without trailing slash, tests call with trailing slash
+		menus.GET("", handlers.GetMenus)
+		menus.GET("/", handlers.GetMenus)
+		menus.POST("", handlers.CreateMenu)
+		menus.POST("/", handlers.CreateMenu)
+		menus.GET("/events", handlers.MenuEvents)
+		menus.GET("/flat", handlers.ListMenusFlat)
+		menus.GET("/changes", handlers.MenuChanges)
+		menus.GET("/resolve", handlers.ResolveMenu)
+		menus.GET("/render", handlers.RenderMenus)
+		menus.GET("/:id", handlers.GetMenu)
+		menus.PUT("/:id", handlers.UpdateMenu)
+		menus.PATCH("/:id", handlers.PatchMenu)
+		menus.PATCH("/:id/reorder", handlers.ReorderMenu)
+		menus.PATCH("/:id/move", handlers.MoveMenu)
+		menus.POST("/:id/duplicate", handlers.DuplicateMenu)
+		menus.GET("/:id/breadcrumbs.jsonld", handlers.MenuBreadcrumbs)
+		menus.PUT("/:id/children/order", handlers.SetChildOrder)
+		menus.PUT("/root/children/order", handlers.SetRootOrder)
+		menus.DELETE("/:id", handlers.DeleteMenu)
+	}
+
+	webhooks := api.Group("/webhooks")
+	{
+		webhooks.GET("", handlers.ListWebhooks)
+		webhooks.POST("", handlers.CreateWebhook)
+		webhooks.DELETE("/:id", handlers.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", handlers.ListWebhookDeliveries)
+		webhooks.POST("/deliveries/:id/redeliver", handlers.RedeliverWebhook)
+	}
+}
+
 // SetupRouter configures routes and middleware
 func SetupRouter() *gin.Engine {
-	r := gin.Default()
+	// gin.New instead of gin.Default: access logs and panics go through slog
+	// with the request id (see middleware). otelgin comes first so every later
+	// middleware, handler, service and query runs inside the request span
+	// (continuing an incoming W3C traceparent).
+	r := gin.New()
+	// ClientIP (rate limit buckets, access logs) only believes forwarding
+	// headers from TRUSTED_PROXIES; gin trusts every peer by default
+	if err := r.SetTrustedProxies(middleware.TrustedProxiesFromEnv()); err != nil {
+		slog.Error("invalid TRUSTED_PROXIES; trusting no proxies", "error", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+	r.Use(
+		otelgin.Middleware(tracing.ServiceName, otelgin.WithFilter(tracedRequest)),
+		middleware.RequestID(), middleware.AccessLog(), middleware.Metrics(), middleware.Recovery(),
+	)
 
 	// Disable automatic redirect for trailing slashes to prevent CORS issues
 	r.RedirectTrailingSlash = false
@@ -28,26 +98,48 @@ func SetupRouter() *gin.Engine {
 		cfg.AllowOrigins = []string{allow}
 	}
 	cfg.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	cfg.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	cfg.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "Last-Event-ID", middleware.RequestIDHeader,
+		middleware.IdempotencyKeyHeader, "traceparent", "tracestate"}
+	cfg.ExposeHeaders = []string{middleware.RequestIDHeader, "Retry-After", "Accept-Patch", middleware.IdempotentReplayedHeader,
+		"Deprecation", "Sunset", "Link"}
 	r.Use(cors.New(cfg))
 
-	api := r.Group("/api")
+	// every API route (REST and GraphQL share the buckets) is rate limited;
+	// mutating ones also get a body cap and honour Idempotency-Key
+	apiMiddleware := []gin.HandlerFunc{
+		middleware.RateLimit(middleware.RateLimitOptionsFromEnv()),
+		middleware.BodyLimit(middleware.MaxBodyBytesFromEnv()),
+		middleware.Idempotency(middleware.IdempotencyOptionsFromEnv()),
+	}
+	// /api/v1 is the current API; /api is its deprecated alias with the
+	// pre-v1 response shapes, which announces its sunset on every response
+	v1 := r.Group("/api/v1", append([]gin.HandlerFunc{middleware.APIVersion("v1")}, apiMiddleware...)...)
+	registerAPI(v1)
+	legacy := r.Group("/api", append([]gin.HandlerFunc{middleware.Deprecation(middleware.LegacyAPIOptionsFromEnv())}, apiMiddleware...)...)
+	registerAPI(legacy)
+
+	gql := r.Group("/graphql", apiMiddleware...)
 	{
-		menus := api.Group("/menus")
-		{
-			// Register both with and without trailing slash for compatibility
-			// Frontend calls without trailing slash, tests call with trailing slash
-			menus.GET("", handlers.GetMenus)
-			menus.GET("/", handlers.GetMenus)
-			menus.POST("", handlers.CreateMenu)
-			menus.POST("/", handlers.CreateMenu)
-			menus.PUT("/:id", handlers.UpdateMenu)
-			menus.PATCH("/:id/reorder", handlers.ReorderMenu)
-			menus.PATCH("/:id/move", handlers.MoveMenu)
-			menus.DELETE("/:id", handlers.DeleteMenu)
-		}
+		gql.GET("", handlers.GraphQL)
+		gql.POST("", handlers.GraphQL)
 	}
 
+	// liveness / readiness probes (outside /api: not part of the public API)
+	r.GET("/healthz", handlers.Healthz)
+	r.GET("/readyz", handlers.Readyz)
+
+	// sitemap for crawlers, generated from the menu
+	r.GET("/sitemap.xml", handlers.Sitemap)
+
+	// Prometheus scrape endpoint (HTTP, DB pool, transaction and tree metrics)
+	r.GET("/metrics", gin.WrapH(metrics.Handler(func() (metrics.TreeStats, error) {
+		return services.MenuTreeStats(context.Background())
+	})))
+
+	r.NoRoute(func(c *gin.Context) {
+		problem.Abort(c, http.StatusNotFound, problem.CodeRouteNotFound, "no route for "+c.Request.Method+" "+c.Request.URL.Path)
+	})
+
 	// serve static frontend (simple SPA)
 	r.StaticFile("/", "../frontend/index.html")
 	r.Static("/static", "../frontend")
@@ -87,13 +179,13 @@ func SetupRouter() *gin.Engine {
 		} else {
 			// helpful 404 so users know how to generate docs.
 			r.GET("/openapi.json", func(c *gin.Context) {
-				c.JSON(404, gin.H{"error": "openapi.json not found; run `go generate ./...` in backend"})
+				problem.Abort(c, http.StatusNotFound, problem.CodeDocsNotGenerated, "openapi.json not found; run `go generate ./...` in backend")
 			})
 		}
 	} else {
 		// test-only: simulate missing docs
 		r.GET("/openapi.json", func(c *gin.Context) {
-			c.JSON(404, gin.H{"error": "openapi.json not found; run `go generate ./...` in backend"})
+			problem.Abort(c, http.StatusNotFound, problem.CodeDocsNotGenerated, "openapi.json not found; run `go generate ./...` in backend")
 		})
 	}
 
@@ -4,9 +4,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
 	"github.com/galpt/sotekre/backend/routes"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
 )
 
 func TestOpenAPI_and_DOCS_404_when_missing(t *testing.T) {
@@ -51,3 +60,67 @@ func TestCORS_Respects_ENV_ALLOW_ORIGINS(t *testing.T) {
 		t.Fatalf("unexpected Access-Control-Allow-Origin: %q", got)
 	}
 }
+
+func TestMetrics_countsRequestsPerRoute(t *testing.T) {
+	// tree gauges are computed at scrape time and need a database
+	db, err := gorm.Open(sqlite.Open("file:memtest_routes_metrics?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	config.DB = db
+	defer config.CloseDB()
+	if err := db.AutoMigrate(&models.Menu{}, &models.MenuChange{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	os.Setenv("SOTEKRE_TEST_NO_DOCS", "1")
+	defer os.Unsetenv("SOTEKRE_TEST_NO_DOCS")
+	r := routes.SetupRouter()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/no/such/path/123", nil))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, `sotekre_http_requests_total{method="GET",route="/openapi.json",status="404"}`) {
+		t.Fatalf("missing request counter:\n%s", body)
+	}
+	if !strings.Contains(body, `route="unmatched"`) || strings.Contains(body, "/no/such/path") {
+		t.Fatalf("unmatched paths must be collapsed:\n%s", body)
+	}
+	if !strings.Contains(body, "sotekre_menu_items 0") {
+		t.Fatalf("missing tree gauges:\n%s", body)
+	}
+	if !strings.Contains(body, "sotekre_http_request_duration_seconds_bucket") {
+		t.Fatalf("missing latency histogram")
+	}
+}
+
+func TestTracing_continuesIncomingTraceparent(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+	prevProp := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(prevProp)
+
+	os.Setenv("SOTEKRE_TEST_NO_DOCS", "1")
+	defer os.Unsetenv("SOTEKRE_TEST_NO_DOCS")
+	r := routes.SetupRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("want one span (probes are not traced), got %d", len(spans))
+	}
+	if got := spans[0].SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace id not propagated: %s", got)
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReadiness(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	// the test DB only has menus + menu_changes
+	r := CheckReadiness(ctx)
+	require.False(t, r.Ready())
+	require.Equal(t, "ok", r.Checks["database"])
+	require.Equal(t, "pending", r.Checks["migrations"])
+
+	require.NoError(t, config.DB.AutoMigrate(models.All()...))
+	r = CheckReadiness(ctx)
+	require.True(t, r.Ready(), "%+v", r)
+	require.NotNil(t, r.Pool)
+
+	// tables from an older migration, without a later column, are not ready
+	require.NoError(t, config.DB.Migrator().DropColumn(&models.WebhookDelivery{}, "claimed_at"))
+	require.EqualError(t, checkSchema(config.DB), "missing column webhook_deliveries.claimed_at")
+	r = CheckReadiness(ctx)
+	require.False(t, r.Ready())
+	require.Equal(t, "pending", r.Checks["migrations"])
+	require.NoError(t, config.DB.AutoMigrate(models.All()...))
+	require.True(t, CheckReadiness(ctx).Ready())
+
+	origPing := config.PingFn
+	defer func() { config.PingFn = origPing }()
+	config.PingFn = func(context.Context, *sql.DB) error { return errors.New("dial tcp 10.0.0.5:3306: connection refused") }
+	r = CheckReadiness(ctx)
+	require.Equal(t, StatusNotReady, r.Status)
+	require.Equal(t, "unavailable", r.Checks["database"], "the driver error is logged, not returned")
+
+	// a hung database fails the probe once READYZ_TIMEOUT_MS elapses
+	t.Setenv("READYZ_TIMEOUT_MS", "20")
+	config.PingFn = func(ctx context.Context, _ *sql.DB) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	r = CheckReadiness(ctx)
+	require.Equal(t, StatusNotReady, r.Status)
+	require.Equal(t, "unavailable", r.Checks["database"])
+	config.PingFn = origPing
+
+	SetDraining(true)
+	defer SetDraining(false)
+	require.Equal(t, StatusDraining, CheckReadiness(ctx).Status)
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/metrics"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// defaultTreeCacheTTL bounds how long a cached tree is served when nothing in
+// this package invalidated it (e.g. rows edited with manual SQL).
+// Override with MENU_CACHE_TTL_MS; 0 disables the cache.
+const defaultTreeCacheTTL = 30 * time.Second
+
+// CacheStats is a snapshot of the tree cache counters.
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// treeCache keeps the last built tree in memory. The tree is shared between
+// readers and must be treated as read-only.
+type treeCache struct {
+	mu      sync.RWMutex
+	valid   bool
+	tree    []*models.MenuNode
+	db      *gorm.DB // connection the tree was loaded from (tests swap config.DB)
+	expires time.Time
+	gen     uint64 // bumped on every invalidation
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+var menuTreeCache = &treeCache{}
+
+// get returns the cached tree if it is still valid. It does not touch the
+// hit/miss counters; see count.
+func (c *treeCache) get(db *gorm.DB, now time.Time) ([]*models.MenuNode, uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.valid && c.db == db && now.Before(c.expires) {
+		return c.tree, c.gen, true
+	}
+	return nil, c.gen, false
+}
+
+// count records the result of a lookup in the counters and in
+// metrics.TreeCacheLookups.
+func (c *treeCache) count(hit bool) {
+	if hit {
+		c.hits.Add(1)
+		metrics.TreeCacheLookups.WithLabelValues("hit").Inc()
+		return
+	}
+	c.misses.Add(1)
+	metrics.TreeCacheLookups.WithLabelValues("miss").Inc()
+}
+
+// set stores tree unless an invalidation happened since gen was read, so a
+// slow reader cannot put back a tree that predates a concurrent mutation.
+func (c *treeCache) set(db *gorm.DB, gen uint64, tree []*models.MenuNode, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	c.valid = true
+	c.tree = tree
+	c.db = db
+	c.expires = expires
+}
+
+func (c *treeCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.valid = false
+	c.tree = nil
+	c.db = nil
+	c.gen++
+}
+
+// GetMenuTree returns the nested menu tree, served from memory when possible.
+func GetMenuTree(ctx context.Context) (_ []*models.MenuNode, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetMenuTree")
+	defer func() { tracing.End(span, err) }()
+
+	tree, hit, err := menuTree(ctx)
+	span.SetAttributes(attribute.Bool("menu.cache_hit", hit))
+	if err == nil {
+		menuTreeCache.count(hit)
+	}
+	return tree, err
+}
+
+// menuTree returns the cached tree, loading and caching it on a miss. hit
+// reports whether the cache answered; the caller decides whether to count it.
+func menuTree(ctx context.Context) (_ []*models.MenuNode, hit bool, err error) {
+	db := config.DB
+	now := time.Now()
+	tree, gen, ok := menuTreeCache.get(db, now)
+	if ok {
+		return tree, true, nil
+	}
+
+	flat, err := GetAllMenusFn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	tree, err = BuildTree(flat)
+	if err != nil {
+		return nil, false, err
+	}
+	if tree == nil {
+		tree = []*models.MenuNode{}
+	}
+
+	if ttl := config.EnvDurationMSOr("MENU_CACHE_TTL_MS", defaultTreeCacheTTL); ttl > 0 {
+		menuTreeCache.set(db, gen, tree, now.Add(ttl))
+	}
+	return tree, false, nil
+}
+
+// InvalidateMenuTree drops the cached tree. Mutations in this package call it
+// after a successful write; callers that modify menus directly can too.
+func InvalidateMenuTree() {
+	menuTreeCache.invalidate()
+}
+
+// TreeCacheStats returns the cache hit/miss counters since process start.
+func TreeCacheStats() CacheStats {
+	return CacheStats{
+		Hits:   menuTreeCache.hits.Load(),
+		Misses: menuTreeCache.misses.Load(),
+	}
+}
+
+// MenuTreeStats reports the size and shape of the (cached) menu tree for the
+// metrics endpoint. Scrapes are not counted as cache hits or misses, so the
+// hit ratio reflects API traffic only.
+func MenuTreeStats(ctx context.Context) (metrics.TreeStats, error) {
+	tree, _, err := menuTree(ctx)
+	if err != nil {
+		return metrics.TreeStats{}, err
+	}
+	var s metrics.TreeStats
+	var walk func(level []*models.MenuNode, depth int)
+	walk = func(level []*models.MenuNode, depth int) {
+		if len(level) == 0 {
+			return
+		}
+		s.Items += len(level)
+		if depth > s.MaxDepth {
+			s.MaxDepth = depth
+		}
+		if len(level) > s.MaxFanOut {
+			s.MaxFanOut = len(level)
+		}
+		for _, n := range level {
+			walk(n.Children, depth+1)
+		}
+	}
+	walk(tree, 1)
+	return s, nil
+}
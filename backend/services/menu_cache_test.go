@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/metrics"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMenuTree_cachesUntilMutation(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "A"}))
+
+	before := TreeCacheStats()
+	hits := testutil.ToFloat64(metrics.TreeCacheLookups.WithLabelValues("hit"))
+	misses := testutil.ToFloat64(metrics.TreeCacheLookups.WithLabelValues("miss"))
+	tree, err := GetMenuTree(ctx)
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	_, err = GetMenuTree(ctx)
+	require.NoError(t, err)
+	after := TreeCacheStats()
+	require.Equal(t, before.Misses+1, after.Misses)
+	require.Equal(t, before.Hits+1, after.Hits)
+	require.Equal(t, hits+1, testutil.ToFloat64(metrics.TreeCacheLookups.WithLabelValues("hit")))
+	require.Equal(t, misses+1, testutil.ToFloat64(metrics.TreeCacheLookups.WithLabelValues("miss")))
+
+	// a write that bypasses the service layer is not seen until invalidation/TTL
+	require.NoError(t, config.DB.Create(&models.Menu{Title: "raw"}).Error)
+	tree, _ = GetMenuTree(ctx)
+	require.Len(t, tree, 1)
+
+	// every service mutation drops the cached tree
+	b := models.Menu{Title: "B"}
+	require.NoError(t, CreateMenu(ctx, &b))
+	tree, _ = GetMenuTree(ctx)
+	require.Len(t, tree, 3)
+
+	require.NoError(t, updateErr(ctx, b.ID, map[string]interface{}{"title": "B2"}))
+	tree, _ = GetMenuTree(ctx)
+	require.Equal(t, "B2", tree[len(tree)-1].Title)
+
+	_, err = ReorderMenu(ctx, b.ID, 0)
+	require.NoError(t, err)
+	tree, _ = GetMenuTree(ctx)
+	require.Equal(t, b.ID, tree[0].ID)
+
+	_, err = MoveMenu(ctx, b.ID, &tree[1].ID, nil)
+	require.NoError(t, err)
+	tree, _ = GetMenuTree(ctx)
+	require.Len(t, tree, 2)
+
+	require.NoError(t, DeleteMenuRecursive(ctx, tree[0].ID))
+	tree, _ = GetMenuTree(ctx)
+	require.Len(t, tree, 1)
+}
+
+func TestGetMenuTree_zeroTTLDisablesCache(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	os.Setenv("MENU_CACHE_TTL_MS", "0")
+	defer os.Unsetenv("MENU_CACHE_TTL_MS")
+	ctx := context.Background()
+
+	_, err := GetMenuTree(ctx)
+	require.NoError(t, err)
+	require.NoError(t, config.DB.Create(&models.Menu{Title: "raw"}).Error)
+	tree, err := GetMenuTree(ctx)
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+}
+
+func TestTreeCache_staleLoadIsDiscarded(t *testing.T) {
+	c := &treeCache{}
+	_, gen, ok := c.get(nil, time.Now())
+	require.False(t, ok)
+	c.invalidate() // a mutation lands while the reader is loading
+	c.set(nil, gen, []*models.MenuNode{{ID: 1}}, time.Now().Add(time.Minute))
+	_, _, ok = c.get(nil, time.Now())
+	require.False(t, ok, "tree loaded before the invalidation must not be cached")
+}
+
+func TestGetMenuTree_concurrentReaders(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "A"}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%4 == 0 {
+				InvalidateMenuTree()
+			}
+			tree, err := GetMenuTree(ctx)
+			if err != nil || len(tree) != 1 {
+				t.Errorf("unexpected tree: %v %v", tree, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/tracing"
+	"gorm.io/gorm"
+)
+
+// ErrChangesExpired is returned when a sync cursor predates the change log's
+// retention window (or comes from another database); the client must resync.
+var ErrChangesExpired = errors.New("change cursor is older than the retention window")
+
+// defaultChangeRetention is how long change log entries are kept. Override
+// with MENU_CHANGES_RETENTION_MS.
+const defaultChangeRetention = 30 * 24 * time.Hour
+
+// changePruneInterval throttles retention pruning to once per interval.
+const changePruneInterval = time.Minute
+
+// ChangeSet is the delta after a cursor. Upserted holds the current state of
+// every created/updated/moved item; Deleted holds tombstoned ids. Cursor is
+// the sequence to pass as `since` next time.
+type ChangeSet struct {
+	Upserted []models.Menu `json:"upserted"`
+	Deleted  []uint        `json:"deleted"`
+	Cursor   uint64        `json:"cursor"`
+}
+
+var (
+	// changeLogMu is held from a mutation's change log insert until its
+	// transaction has finished, so sequences become visible in order (an
+	// earlier seq committing after a later one would be skipped by readers).
+	// It only orders writers of this process: with several backend instances
+	// writing to one database a reader can still miss a sequence, so the
+	// changes feed assumes a single writing instance.
+	changeLogMu sync.Mutex
+	lastPrune   time.Time
+)
+
+// changeLog records a mutation's change log entries, and the webhook
+// deliveries of the change, inside its transaction, so both exist exactly
+// when the change committed. Call record as the transaction's last statements
+// and done once the transaction has finished (committed or not); run retried
+// transactions through l.runTx, which releases the log between attempts.
+type changeLog struct {
+	locked bool
+}
+
+// record appends one change log entry per id in tx and, while a webhook
+// dispatcher runs, queues the event's deliveries. The deliveries' revision is
+// the sequence of the event's last entry.
+func (l *changeLog) record(tx *gorm.DB, typ EventType, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	op := models.ChangeUpsert
+	if typ == EventDeleted {
+		op = models.ChangeDelete
+	}
+	now := time.Now().UTC()
+	rows := make([]models.MenuChange, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, models.MenuChange{MenuID: id, Op: op, ChangedAt: now})
+	}
+	if !l.locked {
+		changeLogMu.Lock()
+		l.locked = true
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return err
+	}
+	if activeWebhooks.Load() == nil {
+		return nil
+	}
+	return enqueueDeliveries(tx, ChangeEvent{Revision: rows[len(rows)-1].Seq, Type: typ, IDs: ids, At: now})
+}
+
+// runTx is runTx for a mutation that records into l. Every attempt starts by
+// releasing the change log a failed attempt took, so a retry never holds
+// changeLogMu while it waits for row locks of a writer that is itself
+// waiting for changeLogMu.
+func (l *changeLog) runTx(ctx context.Context, op string, fn func(tx *gorm.DB) error) error {
+	return runTx(ctx, op, func(tx *gorm.DB) error {
+		l.release()
+		return fn(tx)
+	})
+}
+
+func (l *changeLog) release() {
+	if l.locked {
+		l.locked = false
+		changeLogMu.Unlock()
+	}
+}
+
+// done releases the change log and, at most once per interval, prunes
+// entries past retention. Prune failures are only logged.
+func (l *changeLog) done(ctx context.Context) {
+	if !l.locked {
+		return
+	}
+	defer l.release()
+	now := time.Now().UTC()
+	if now.Sub(lastPrune) >= changePruneInterval {
+		lastPrune = now
+		if err := pruneChanges(config.DB.WithContext(ctx), now); err != nil {
+			config.Logger(ctx).Error("change log: prune failed", "error", err)
+		}
+	}
+}
+
+// pruneChanges drops entries older than the retention window but always keeps
+// the newest one, so the current sequence survives quiet periods.
+func pruneChanges(db *gorm.DB, now time.Time) error {
+	var maxSeq uint64
+	if err := db.Model(&models.MenuChange{}).Select("COALESCE(MAX(seq), 0)").Scan(&maxSeq).Error; err != nil {
+		return err
+	}
+	cutoff := now.Add(-config.EnvDurationMSOr("MENU_CHANGES_RETENTION_MS", defaultChangeRetention))
+	return db.Where("changed_at < ? AND seq < ?", cutoff, maxSeq).Delete(&models.MenuChange{}).Error
+}
+
+// GetChangesSince returns what changed after the `since` sequence, collapsed
+// to the latest operation per item. When the cursor can no longer be served
+// the error is ErrChangesExpired and the returned set carries only the
+// current Cursor: refetch the full tree, then sync from that cursor.
+func GetChangesSince(ctx context.Context, since uint64) (_ *ChangeSet, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetChangesSince")
+	defer func() { tracing.End(span, err) }()
+
+	db := config.DB.WithContext(ctx)
+	var bounds struct {
+		MinSeq uint64
+		MaxSeq uint64
+	}
+	if err := db.Model(&models.MenuChange{}).
+		Select("COALESCE(MIN(seq), 0) AS min_seq, COALESCE(MAX(seq), 0) AS max_seq").
+		Scan(&bounds).Error; err != nil {
+		return nil, err
+	}
+	set := &ChangeSet{Upserted: []models.Menu{}, Deleted: []uint{}, Cursor: bounds.MaxSeq}
+	if since > bounds.MaxSeq || (bounds.MinSeq > 0 && since+1 < bounds.MinSeq) {
+		return set, ErrChangesExpired
+	}
+	if since == bounds.MaxSeq {
+		return set, nil
+	}
+
+	var entries []models.MenuChange
+	if err := db.Where("seq > ? AND seq <= ?", since, bounds.MaxSeq).Order("seq").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	latest := map[uint]string{}
+	var order []uint // first-seen order keeps the response deterministic
+	for _, e := range entries {
+		if _, seen := latest[e.MenuID]; !seen {
+			order = append(order, e.MenuID)
+		}
+		latest[e.MenuID] = e.Op
+	}
+
+	var upsertIDs []uint
+	for _, id := range order {
+		if latest[id] == models.ChangeDelete {
+			set.Deleted = append(set.Deleted, id)
+		} else {
+			upsertIDs = append(upsertIDs, id)
+		}
+	}
+	if len(upsertIDs) > 0 {
+		// an item deleted after the cursor was read is simply absent here;
+		// its tombstone arrives with the next sync
+		if err := db.Where("id IN ?", upsertIDs).Order("id").Find(&set.Upserted).Error; err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// Test hooks — allow handlers to stub behavior in tests.
+var GetChangesSinceFn = GetChangesSince
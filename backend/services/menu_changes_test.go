@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func menuIDs(items []models.Menu) []uint {
+	out := make([]uint, 0, len(items))
+	for _, m := range items {
+		out = append(out, m.ID)
+	}
+	return out
+}
+
+func TestGetChangesSince_upsertsAndTombstones(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	empty, err := GetChangesSince(ctx, 0)
+	require.NoError(t, err)
+	require.Zero(t, empty.Cursor)
+
+	a := models.Menu{Title: "A"}
+	b := models.Menu{Title: "B", Order: 1}
+	require.NoError(t, CreateMenu(ctx, &a))
+	require.NoError(t, CreateMenu(ctx, &b))
+	child := models.Menu{Title: "A1", ParentID: &a.ID}
+	require.NoError(t, CreateMenu(ctx, &child))
+
+	first, err := GetChangesSince(ctx, 0)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []uint{a.ID, b.ID, child.ID}, menuIDs(first.Upserted))
+	require.Empty(t, first.Deleted)
+	require.Equal(t, uint64(3), first.Cursor)
+
+	require.NoError(t, updateErr(ctx, b.ID, map[string]interface{}{"title": "B2"}))
+	require.NoError(t, DeleteMenuRecursive(ctx, a.ID))
+
+	delta, err := GetChangesSince(ctx, first.Cursor)
+	require.NoError(t, err)
+	require.Len(t, delta.Upserted, 1)
+	require.Equal(t, "B2", delta.Upserted[0].Title)
+	require.ElementsMatch(t, []uint{a.ID, child.ID}, delta.Deleted)
+	require.Greater(t, delta.Cursor, first.Cursor)
+
+	// created then deleted inside the window: only the tombstone is reported
+	all, err := GetChangesSince(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint{b.ID}, menuIDs(all.Upserted))
+	require.ElementsMatch(t, []uint{a.ID, child.ID}, all.Deleted)
+
+	none, err := GetChangesSince(ctx, delta.Cursor)
+	require.NoError(t, err)
+	require.Empty(t, none.Upserted)
+	require.Empty(t, none.Deleted)
+	require.Equal(t, delta.Cursor, none.Cursor)
+}
+
+func TestGetChangesSince_expiredCursor(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	t.Setenv("MENU_CHANGES_RETENTION_MS", "3600000")
+
+	old := time.Now().Add(-2 * time.Hour)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, config.DB.Create(&models.MenuChange{MenuID: 1, Op: models.ChangeUpsert, ChangedAt: old}).Error)
+	}
+	changeLogMu.Lock()
+	lastPrune = time.Time{}
+	changeLogMu.Unlock()
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "A"}))
+
+	var left int64
+	config.DB.Model(&models.MenuChange{}).Count(&left)
+	require.Equal(t, int64(1), left, "entries past retention are pruned")
+
+	set, err := GetChangesSince(ctx, 1)
+	require.True(t, errors.Is(err, ErrChangesExpired))
+	require.Equal(t, uint64(4), set.Cursor)
+
+	_, err = GetChangesSince(ctx, 3)
+	require.NoError(t, err, "the newest pruned sequence is still a valid cursor")
+	_, err = GetChangesSince(ctx, 99)
+	require.True(t, errors.Is(err, ErrChangesExpired), "cursor from the future")
+}
+
+func TestChangeLog_writtenInMutationTransaction(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	a := models.Menu{Title: "A"}
+	require.NoError(t, CreateMenu(ctx, &a))
+
+	// without a change log the mutation cannot commit
+	require.NoError(t, config.DB.Migrator().DropTable(&models.MenuChange{}))
+	require.Error(t, CreateMenu(ctx, &models.Menu{Title: "B"}))
+	require.Error(t, updateErr(ctx, a.ID, map[string]interface{}{"title": "A2"}))
+	require.Error(t, DeleteMenuRecursive(ctx, a.ID))
+
+	var items []models.Menu
+	require.NoError(t, config.DB.Find(&items).Error)
+	require.Len(t, items, 1)
+	require.Equal(t, "A", items[0].Title)
+}
+
+func TestChangeLog_releasedBetweenRetries(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	var changes changeLog
+	defer changes.done(ctx)
+	attempts := 0
+	err := changes.runTx(ctx, "test", func(tx *gorm.DB) error {
+		attempts++
+		if attempts > 1 {
+			// another writer can take the change log while this one retries
+			require.True(t, changeLogMu.TryLock(), "attempt %d started holding changeLogMu", attempts)
+			changeLogMu.Unlock()
+		}
+		if err := changes.record(tx, EventUpdated, []uint{1}); err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return &mysql.MySQLError{Number: mysqlDeadlock}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+}
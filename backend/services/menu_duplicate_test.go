@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// seedProducts creates roots Products(0), Support(1) where
+// Products -> [Phones -> [Android], Laptops].
+func seedProducts(t *testing.T) (products, support, phones models.Menu) {
+	t.Helper()
+	ctx := context.Background()
+	products = models.Menu{Title: "Products", URL: ptrString("/products"), Order: 0}
+	support = models.Menu{Title: "Support", Order: 1}
+	require.NoError(t, CreateMenu(ctx, &products))
+	require.NoError(t, CreateMenu(ctx, &support))
+	phones = models.Menu{Title: "Phones", ParentID: &products.ID, Order: 0}
+	require.NoError(t, CreateMenu(ctx, &phones))
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "Laptops", ParentID: &products.ID, Order: 1}))
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "Android", ParentID: &phones.ID, Order: 0}))
+	return products, support, phones
+}
+
+func ptrString(s string) *string { return &s }
+
+func titles(nodes []*models.MenuNode) []string {
+	out := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, n.Title)
+	}
+	return out
+}
+
+func TestDuplicateMenu_nextToSource_deepCopy(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	products, _, _ := seedProducts(t)
+
+	cp, err := DuplicateMenu(ctx, products.ID, DuplicateOptions{NextToSource: true, TitleSuffix: " (EU)"})
+	require.NoError(t, err)
+	require.NotEqual(t, products.ID, cp.ID)
+	require.Equal(t, "Products (EU)", cp.Title)
+	require.Equal(t, "/products", *cp.URL)
+
+	tree, err := GetMenuTree(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Products", "Products (EU)", "Support"}, titles(tree))
+	copied := tree[1]
+	require.Equal(t, []string{"Phones", "Laptops"}, titles(copied.Children))
+	require.Equal(t, []string{"Android"}, titles(copied.Children[0].Children))
+	require.NotEqual(t, tree[0].Children[0].ID, copied.Children[0].ID)
+}
+
+func TestDuplicateMenu_intoTargetAtPosition(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	_, support, phones := seedProducts(t)
+
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "FAQ", ParentID: &support.ID, Order: 0}))
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "Contact", ParentID: &support.ID, Order: 1}))
+
+	_, err := DuplicateMenu(ctx, phones.ID, DuplicateOptions{ParentID: &support.ID, Position: ptrInt(1)})
+	require.NoError(t, err)
+	tree, _ := GetMenuTree(ctx)
+	require.Equal(t, []string{"FAQ", "Phones", "Contact"}, titles(tree[1].Children))
+	for i, ch := range tree[1].Children {
+		require.Equal(t, i, ch.Order)
+	}
+}
+
+func TestDuplicateMenu_intoOwnSubtree_terminates(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	products, _, phones := seedProducts(t)
+
+	_, err := DuplicateMenu(ctx, products.ID, DuplicateOptions{ParentID: &phones.ID})
+	require.NoError(t, err)
+	var n int64
+	config.DB.Model(&models.Menu{}).Count(&n)
+	require.Equal(t, int64(5+4), n, "exactly the original 4-node subtree is copied")
+}
+
+func TestDuplicateMenu_notFound(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	products, _, _ := seedProducts(t)
+
+	_, err := DuplicateMenu(ctx, 9999, DuplicateOptions{})
+	require.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	_, err = DuplicateMenu(ctx, products.ID, DuplicateOptions{ParentID: ptrUint(9999)})
+	require.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+}
+
+func TestDuplicateMenu_shiftedSiblingsAreReordered(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	products, support, _ := seedProducts(t)
+
+	sub, _, ok := SubscribeEvents(CurrentRevision(), false)
+	require.True(t, ok)
+	defer sub.Close()
+	cp, err := DuplicateMenu(ctx, products.ID, DuplicateOptions{Position: ptrInt(0)})
+	require.NoError(t, err)
+
+	created := <-sub.C
+	require.Equal(t, EventCreated, created.Type)
+	require.Contains(t, created.IDs, cp.ID)
+	require.NotContains(t, created.IDs, products.ID)
+	require.NotContains(t, created.IDs, support.ID)
+	reordered := <-sub.C
+	require.Equal(t, EventReordered, reordered.Type)
+	require.ElementsMatch(t, []uint{products.ID, support.ID}, reordered.IDs)
+}
+
+// TestOrderColumn_quotedForMySQL guards the sibling queries against MySQL,
+// where a double-quoted "order" is a string constant, not the column.
+func TestOrderColumn_quotedForMySQL(t *testing.T) {
+	sqlDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	gdb, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+
+	var max *int
+	sql := gdb.Model(&models.Menu{}).Select("MAX(?)", orderColumn).Where("parent_id IS NULL").Scan(&max).Statement.SQL.String()
+	require.Contains(t, sql, "SELECT MAX(`order`)")
+	sql = gdb.Where("parent_id IS NULL").Order(byOrder).Find(&[]models.Menu{}).Statement.SQL.String()
+	require.Contains(t, sql, "ORDER BY `order`")
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+)
+
+// EventType names the kind of change a ChangeEvent describes.
+type EventType string
+
+const (
+	EventCreated   EventType = "created"
+	EventUpdated   EventType = "updated"
+	EventMoved     EventType = "moved"
+	EventReordered EventType = "reordered"
+	EventDeleted   EventType = "deleted"
+)
+
+// defaultEventHistory is how many past events are kept for Last-Event-ID
+// resume. Override with MENU_EVENTS_HISTORY.
+const defaultEventHistory = 256
+
+// subscriberBuffer bounds how far a subscriber may lag before it is dropped;
+// a dropped client reconnects and resumes from history.
+const subscriberBuffer = 64
+
+// ChangeEvent is published after a mutation commits.
+// IDs lists every row the mutation wrote (e.g. renumbered siblings on a move,
+// the whole subtree on a delete).
+type ChangeEvent struct {
+	Revision uint64    `json:"revision"`
+	Type     EventType `json:"type"`
+	IDs      []uint    `json:"ids"`
+	At       time.Time `json:"at"`
+}
+
+// eventBroker fans events out to live subscribers and keeps a bounded
+// history. Revisions restart at 1 with every process.
+type eventBroker struct {
+	mu       sync.Mutex
+	revision uint64
+	history  []ChangeEvent // oldest first
+	subs     map[chan ChangeEvent]struct{}
+}
+
+var menuEvents = &eventBroker{subs: map[chan ChangeEvent]struct{}{}}
+
+func (b *eventBroker) publish(typ EventType, ids []uint) ChangeEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.revision++
+	ev := ChangeEvent{Revision: b.revision, Type: typ, IDs: ids, At: time.Now().UTC()}
+
+	b.history = append(b.history, ev)
+	if max := config.EnvIntOr("MENU_EVENTS_HISTORY", defaultEventHistory); len(b.history) > max {
+		b.history = append([]ChangeEvent(nil), b.history[len(b.history)-max:]...)
+	}
+
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			// too slow: disconnect so the client resumes from history
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return ev
+}
+
+// subscribe registers a live subscriber and returns the events after
+// lastRevision. ok is false when that revision can no longer be resumed
+// (evicted from history or from a previous process); the backlog is then empty
+// and the caller should tell the client to refetch.
+func (b *eventBroker) subscribe(lastRevision uint64) (backlog []ChangeEvent, ch chan ChangeEvent, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch = make(chan ChangeEvent, subscriberBuffer)
+	b.subs[ch] = struct{}{}
+
+	if lastRevision > b.revision {
+		return nil, ch, false
+	}
+	if lastRevision < b.revision {
+		if len(b.history) == 0 || b.history[0].Revision > lastRevision+1 {
+			return nil, ch, false
+		}
+		for _, ev := range b.history {
+			if ev.Revision > lastRevision {
+				backlog = append(backlog, ev)
+			}
+		}
+	}
+	return backlog, ch, true
+}
+
+func (b *eventBroker) unsubscribe(ch chan ChangeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *eventBroker) current() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.revision
+}
+
+// EventSubscription is a live feed of ChangeEvents. C is closed when the
+// subscriber falls too far behind or Close is called.
+type EventSubscription struct {
+	C  <-chan ChangeEvent
+	ch chan ChangeEvent
+}
+
+// Close stops delivery and releases the subscription.
+func (s *EventSubscription) Close() { menuEvents.unsubscribe(s.ch) }
+
+// SubscribeEvents starts a subscription. With resume set, events after
+// lastRevision are returned as backlog; ok reports whether that was possible.
+func SubscribeEvents(lastRevision uint64, resume bool) (sub *EventSubscription, backlog []ChangeEvent, ok bool) {
+	if !resume {
+		lastRevision = menuEvents.current()
+	}
+	backlog, ch, ok := menuEvents.subscribe(lastRevision)
+	return &EventSubscription{C: ch, ch: ch}, backlog, ok
+}
+
+// CurrentRevision returns the revision of the last published event.
+func CurrentRevision() uint64 { return menuEvents.current() }
+
+// afterCommit runs once a mutation has committed (its change log entries and
+// webhook deliveries were written in the transaction, see changeLog): it
+// drops the cached tree, publishes the change and wakes the webhook
+// dispatcher. No-op moves (no rows written) publish nothing.
+func afterCommit(ctx context.Context, typ EventType, ids []uint) {
+	InvalidateMenuTree()
+	if len(ids) == 0 {
+		return
+	}
+	menuEvents.publish(typ, ids)
+	notifyWebhooks()
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMutations_publishTypedEvents(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	sub, backlog, ok := SubscribeEvents(0, false)
+	defer sub.Close()
+	require.True(t, ok)
+	require.Empty(t, backlog)
+
+	a := models.Menu{Title: "A"}
+	b := models.Menu{Title: "B"}
+	require.NoError(t, CreateMenu(ctx, &a))
+	require.NoError(t, CreateMenu(ctx, &b))
+	require.NoError(t, updateErr(ctx, a.ID, map[string]interface{}{"title": "A2"}))
+	_, err := ReorderMenu(ctx, b.ID, 0)
+	require.NoError(t, err)
+	_, err = MoveMenu(ctx, b.ID, &a.ID, nil)
+	require.NoError(t, err)
+	require.NoError(t, DeleteMenuRecursive(ctx, a.ID))
+
+	want := []EventType{EventCreated, EventCreated, EventUpdated, EventReordered, EventMoved, EventDeleted}
+	var last ChangeEvent
+	for i, typ := range want {
+		ev := <-sub.C
+		require.Equal(t, typ, ev.Type, "event %d", i)
+		require.Greater(t, ev.Revision, last.Revision)
+		last = ev
+	}
+	// the delete reports the whole subtree
+	require.ElementsMatch(t, []uint{a.ID, b.ID}, last.IDs)
+}
+
+func TestSubscribeEvents_resumeAndGap(t *testing.T) {
+	os.Setenv("MENU_EVENTS_HISTORY", "3")
+	defer os.Unsetenv("MENU_EVENTS_HISTORY")
+
+	start := CurrentRevision()
+	for i := 0; i < 5; i++ {
+		menuEvents.publish(EventUpdated, []uint{uint(i + 1)})
+	}
+
+	// within history: the missed events are replayed in order
+	sub, backlog, ok := SubscribeEvents(start+3, true)
+	sub.Close()
+	require.True(t, ok)
+	require.Len(t, backlog, 2)
+	require.Equal(t, start+4, backlog[0].Revision)
+	require.Equal(t, start+5, backlog[1].Revision)
+
+	// evicted from history or from the future: cannot resume
+	sub, backlog, ok = SubscribeEvents(start+1, true)
+	sub.Close()
+	require.False(t, ok)
+	require.Empty(t, backlog)
+	sub, _, ok = SubscribeEvents(start+100, true)
+	sub.Close()
+	require.False(t, ok)
+
+	// up to date: nothing to replay
+	sub, backlog, ok = SubscribeEvents(start+5, true)
+	sub.Close()
+	require.True(t, ok)
+	require.Empty(t, backlog)
+}
+
+func TestEventBroker_dropsSlowSubscriber(t *testing.T) {
+	sub, _, _ := SubscribeEvents(0, false)
+	defer sub.Close()
+	for i := 0; i < subscriberBuffer+1; i++ {
+		menuEvents.publish(EventUpdated, []uint{1})
+	}
+	n := 0
+	for range sub.C {
+		n++
+	}
+	require.Equal(t, subscriberBuffer, n, "channel should be closed after the buffer overflowed")
+}
+
+func TestMoveMenu_noop_publishesNothing(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	a := models.Menu{Title: "A"}
+	require.NoError(t, CreateMenu(ctx, &a))
+
+	before := CurrentRevision()
+	_, err := ReorderMenu(ctx, a.ID, 0)
+	require.NoError(t, err)
+	require.Equal(t, before, CurrentRevision())
+}
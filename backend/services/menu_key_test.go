@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMenuKeys(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	a := &models.Menu{Title: "Billing"}
+	b := &models.Menu{Title: "billing!"}
+	require.NoError(t, CreateMenu(ctx, a))
+	require.NoError(t, CreateMenu(ctx, b))
+	require.Equal(t, "billing", a.Key)
+	require.Equal(t, "billing-2", b.Key, "collisions get a numeric suffix")
+
+	require.ErrorIs(t, CreateMenu(ctx, &models.Menu{Title: "X", Key: "billing"}), ErrKeyTaken)
+	require.ErrorIs(t, CreateMenu(ctx, &models.Menu{Title: "X", Key: "Not A Slug"}), ErrValidation)
+
+	require.ErrorIs(t, updateErr(ctx, b.ID, map[string]interface{}{"key": "billing"}), ErrKeyTaken)
+	require.ErrorIs(t, updateErr(ctx, b.ID, map[string]interface{}{"key": ""}), ErrValidation)
+	require.NoError(t, updateErr(ctx, b.ID, map[string]interface{}{"key": "invoices"}))
+	require.NoError(t, updateErr(ctx, b.ID, map[string]interface{}{"key": "invoices"}), "keeping its own key is fine")
+
+	id, err := GetMenuIDByKey(ctx, "invoices")
+	require.NoError(t, err)
+	require.Equal(t, b.ID, id)
+	_, err = GetMenuIDByKey(ctx, "nope")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	id, err = LookupMenuID(ctx, "key:invoices")
+	require.NoError(t, err)
+	require.Equal(t, b.ID, id)
+	id, err = LookupMenuID(ctx, "42")
+	require.NoError(t, err)
+	require.EqualValues(t, 42, id)
+	for _, raw := range []string{"0", "-1", "abc", ""} {
+		_, err = LookupMenuID(ctx, raw)
+		require.ErrorIs(t, err, ErrInvalidMenuID, raw)
+		p, ok := ErrorProblem(err)
+		require.True(t, ok)
+		require.Equal(t, "invalid_id", p.Code)
+	}
+
+	// rows written without a key (SQL imports) are filled in on startup
+	require.NoError(t, config.DB.Exec("INSERT INTO menus (title, menu_key, `order`) VALUES ('Billing', NULL, 0), ('Help', '', 0)").Error)
+	n, err := BackfillMenuKeys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	var keys []string
+	require.NoError(t, config.DB.Model(&models.Menu{}).Order("id").Pluck("menu_key", &keys).Error)
+	require.Equal(t, []string{"billing", "invoices", "billing-2", "help"}, keys)
+}
+
+func TestMenuKeys_concurrentWriterTakesKey(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	// another writer inserts the key after the checks ran but before the
+	// statement: the unique index rejects it
+	race := func(key string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			if key == "" {
+				return
+			}
+			k := key
+			key = ""
+			require.NoError(t, tx.Session(&gorm.Session{NewDB: true}).Exec(
+				"INSERT INTO menus (title, menu_key, `order`) VALUES ('Other', ?, 9)", k).Error)
+		}
+	}
+	require.NoError(t, config.DB.Callback().Create().Before("gorm:create").Register("test:race", race("billing")))
+	err := CreateMenu(ctx, &models.Menu{Title: "Billing"})
+	require.ErrorIs(t, err, ErrKeyTaken)
+	require.NoError(t, config.DB.Callback().Create().Remove("test:race"))
+
+	a := models.Menu{Title: "A"}
+	require.NoError(t, CreateMenu(ctx, &a))
+	require.NoError(t, config.DB.Callback().Update().Before("gorm:update").Register("test:race", race("invoices")))
+	require.ErrorIs(t, updateErr(ctx, a.ID, map[string]interface{}{"key": "invoices"}), ErrKeyTaken)
+	require.NoError(t, config.DB.Callback().Update().Remove("test:race"))
+
+	var n int64
+	require.NoError(t, config.DB.Model(&models.Menu{}).Count(&n).Error)
+	require.Equal(t, int64(1), n, "the failed writes rolled back, including the racing rows")
+}
+
+func TestKeyConflict(t *testing.T) {
+	require.ErrorIs(t, keyConflict(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'idx_menus_menu_key'"}), ErrKeyTaken)
+	other := &mysql.MySQLError{Number: 1213}
+	require.Equal(t, other, keyConflict(other))
+	require.NoError(t, keyConflict(nil))
+}
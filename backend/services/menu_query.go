@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/tracing"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidQuery is returned for unusable FlatQuery parameters (bad sort
+// field, cursor from another query, ...).
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	defaultFlatLimit = 50
+	maxFlatLimit     = 500
+)
+
+// flatSortColumns maps the public sort names to columns.
+var flatSortColumns = map[string]string{
+	"order":      "order",
+	"title":      "title",
+	"updated_at": "updated_at",
+}
+
+// FlatQuery filters and pages the flat menu listing.
+type FlatQuery struct {
+	ParentID     *uint      // only children of this parent
+	RootsOnly    bool       // only items without a parent (parent_id=null)
+	TitlePrefix  string     // case as stored; LIKE 'prefix%'
+	UpdatedSince *time.Time // updated_at >= UpdatedSince
+	HasURL       *bool      // items with (true) or without (false) a non-empty url
+	Sort         string     // order (default), title or updated_at
+	Desc         bool
+	Limit        int    // default 50, max 500
+	Cursor       string // NextCursor of the previous page
+	IncludeTotal bool   // count all matches (ignores the cursor)
+}
+
+// FlatPage is one page of ListMenusFlat.
+type FlatPage struct {
+	Items      []models.Menu `json:"data"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	Total      *int64        `json:"total,omitempty"`
+}
+
+// flatCursor is the keyset position after the last returned row. Sort and
+// direction are embedded so a cursor cannot be replayed against another order.
+type flatCursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d,omitempty"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+func encodeFlatCursor(q FlatQuery, last models.Menu) (string, error) {
+	var v interface{}
+	switch q.Sort {
+	case "title":
+		v = last.Title
+	case "updated_at":
+		v = last.UpdatedAt
+	default:
+		v = last.Order
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(flatCursor{Sort: q.Sort, Desc: q.Desc, Value: raw, ID: last.ID})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeFlatCursor returns the cursor's sort value typed for the column.
+func decodeFlatCursor(q FlatQuery) (value interface{}, id uint, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var c flatCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if c.Sort != q.Sort || c.Desc != q.Desc {
+		return nil, 0, fmt.Errorf("%w: cursor does not match sort", ErrInvalidQuery)
+	}
+	switch q.Sort {
+	case "title":
+		var s string
+		err = json.Unmarshal(c.Value, &s)
+		value = s
+	case "updated_at":
+		var t time.Time
+		err = json.Unmarshal(c.Value, &t)
+		value = t
+	default:
+		var n int
+		err = json.Unmarshal(c.Value, &n)
+		value = n
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return value, c.ID, nil
+}
+
+// likeEscape is the LIKE escape character. It must not be a backslash: MySQL
+// (without NO_BACKSLASH_ESCAPES) reads '\' as an unterminated string literal.
+const likeEscape = "!"
+
+// escapeLike escapes LIKE wildcards (and the escape character) so s matches
+// literally in a pattern used with likeClause.
+func escapeLike(s string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, `%`, likeEscape+`%`, `_`, likeEscape+`_`).Replace(s)
+}
+
+// likeClause is "col LIKE ?" with the escape character of escapeLike.
+func likeClause(col string) string {
+	return col + " LIKE ? ESCAPE '" + likeEscape + "'"
+}
+
+// ListMenusFlat returns menu rows (no nesting) matching q, one page at a time.
+// Pagination is keyset-based on (sort column, id), so pages stay stable while
+// rows are inserted elsewhere.
+func ListMenusFlat(ctx context.Context, q FlatQuery) (_ *FlatPage, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListMenusFlat")
+	defer func() { tracing.End(span, err) }()
+
+	if q.Sort == "" {
+		q.Sort = "order"
+	}
+	col, ok := flatSortColumns[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("%w: sort must be one of order, title, updated_at", ErrInvalidQuery)
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultFlatLimit
+	}
+	if q.Limit > maxFlatLimit {
+		q.Limit = maxFlatLimit
+	}
+
+	db := config.DB.WithContext(ctx).Model(&models.Menu{})
+	switch {
+	case q.RootsOnly:
+		db = db.Where("parent_id IS NULL")
+	case q.ParentID != nil:
+		db = db.Where("parent_id = ?", *q.ParentID)
+	}
+	if q.TitlePrefix != "" {
+		db = db.Where(likeClause("title"), escapeLike(q.TitlePrefix)+"%")
+	}
+	if q.UpdatedSince != nil {
+		db = db.Where("updated_at >= ?", *q.UpdatedSince)
+	}
+	if q.HasURL != nil {
+		if *q.HasURL {
+			db = db.Where("url IS NOT NULL AND url <> ''")
+		} else {
+			db = db.Where("url IS NULL OR url = ''")
+		}
+	}
+
+	page := &FlatPage{Items: []models.Menu{}}
+	if q.IncludeTotal {
+		var total int64
+		if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	sortCol := clause.Column{Name: col}
+	if q.Cursor != "" {
+		v, id, err := decodeFlatCursor(q)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if q.Desc {
+			op = "<"
+		}
+		db = db.Where(fmt.Sprintf("(? %s ?) OR (? = ? AND id %s ?)", op, op), sortCol, v, sortCol, v, id)
+	}
+
+	var rows []models.Menu
+	err = db.Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: sortCol, Desc: q.Desc},
+		{Column: clause.Column{Name: "id"}, Desc: q.Desc},
+	}}).Limit(q.Limit + 1).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+		next, err := encodeFlatCursor(q, rows[len(rows)-1])
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = next
+	}
+	page.Items = rows
+	return page, nil
+}
+
+// Test hooks — allow handlers to stub behavior in tests.
+var ListMenusFlatFn = ListMenusFlat
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchMenus returns up to limit items (default 20, max 100) whose title,
+// key or URL contains q, ordered by title. Matching is case-insensitive as
+// far as the column collation is.
+func SearchMenus(ctx context.Context, q string, limit int) (_ []models.Menu, err error) {
+	ctx, span := tracing.Start(ctx, "services.SearchMenus")
+	defer func() { tracing.End(span, err) }()
+
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, fmt.Errorf("%w: search text is required", ErrInvalidQuery)
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	like := "%" + escapeLike(q) + "%"
+	var rows []models.Menu
+	err = config.DB.WithContext(ctx).
+		Where(likeClause("title")+" OR "+likeClause("menu_key")+" OR "+likeClause("url"), like, like, like).
+		Order("title, id").Limit(limit).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/stretchr/testify/require"
+)
+
+func flatTitles(items []models.Menu) []string {
+	out := make([]string, 0, len(items))
+	for _, m := range items {
+		out = append(out, m.Title)
+	}
+	return out
+}
+
+// collect pages through the whole result set.
+func collectFlat(t *testing.T, q FlatQuery) []string {
+	t.Helper()
+	var all []string
+	for i := 0; i < 100; i++ {
+		page, err := ListMenusFlat(context.Background(), q)
+		require.NoError(t, err)
+		all = append(all, flatTitles(page.Items)...)
+		if page.NextCursor == "" {
+			return all
+		}
+		q.Cursor = page.NextCursor
+	}
+	t.Fatal("pagination did not terminate")
+	return nil
+}
+
+func TestListMenusFlat_paginatesAndSorts(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	// duplicate orders exercise the id tie-breaker
+	for i, title := range []string{"e", "d", "c", "b", "a"} {
+		require.NoError(t, config.DB.Create(&models.Menu{Title: title, Order: i / 2}).Error)
+	}
+
+	require.Equal(t, []string{"e", "d", "c", "b", "a"}, collectFlat(t, FlatQuery{Limit: 2}))
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, collectFlat(t, FlatQuery{Sort: "title", Limit: 2}))
+	require.Equal(t, []string{"a", "b", "c", "d", "e"}, collectFlat(t, FlatQuery{Sort: "order", Desc: true, Limit: 3}))
+	require.Len(t, collectFlat(t, FlatQuery{Sort: "updated_at", Limit: 1}), 5)
+
+	page, err := ListMenusFlat(context.Background(), FlatQuery{Limit: 2, IncludeTotal: true})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), *page.Total)
+	require.NotEmpty(t, page.NextCursor)
+
+	// a cursor only continues the query it came from
+	_, err = ListMenusFlat(context.Background(), FlatQuery{Sort: "title", Cursor: page.NextCursor})
+	require.ErrorIs(t, err, ErrInvalidQuery)
+	_, err = ListMenusFlat(context.Background(), FlatQuery{Cursor: "!!!"})
+	require.ErrorIs(t, err, ErrInvalidQuery)
+	_, err = ListMenusFlat(context.Background(), FlatQuery{Sort: "id; drop table menus"})
+	require.ErrorIs(t, err, ErrInvalidQuery)
+}
+
+func TestListMenusFlat_filters(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	root := models.Menu{Title: "Settings", URL: ptrString("/settings")}
+	require.NoError(t, config.DB.Create(&root).Error)
+	require.NoError(t, config.DB.Create(&models.Menu{Title: "Set_up", ParentID: &root.ID}).Error)
+	require.NoError(t, config.DB.Create(&models.Menu{Title: "Billing", ParentID: &root.ID, URL: ptrString("/settings/billing")}).Error)
+	require.NoError(t, config.DB.Create(&models.Menu{Title: "Setup wizard", URL: ptrString("")}).Error)
+
+	yes, no := true, false
+	for _, tc := range []struct {
+		q    FlatQuery
+		want []string
+	}{
+		{FlatQuery{RootsOnly: true}, []string{"Settings", "Setup wizard"}},
+		{FlatQuery{ParentID: &root.ID}, []string{"Set_up", "Billing"}},
+		{FlatQuery{TitlePrefix: "Set_", Sort: "title"}, []string{"Set_up"}},
+		{FlatQuery{TitlePrefix: "Set", Sort: "title"}, []string{"Set_up", "Settings", "Setup wizard"}},
+		{FlatQuery{HasURL: &yes}, []string{"Settings", "Billing"}},
+		{FlatQuery{HasURL: &no}, []string{"Set_up", "Setup wizard"}},
+	} {
+		t.Run(fmt.Sprintf("%+v", tc.q), func(t *testing.T) {
+			got := collectFlat(t, tc.q)
+			require.ElementsMatch(t, tc.want, got)
+		})
+	}
+
+	// wildcards and the escape character in the prefix match literally
+	require.NoError(t, config.DB.Create(&models.Menu{Title: "100% off"}).Error)
+	require.NoError(t, config.DB.Create(&models.Menu{Title: "100 percent"}).Error)
+	require.NoError(t, config.DB.Create(&models.Menu{Title: "Hey! there"}).Error)
+	require.Equal(t, []string{"100% off"}, collectFlat(t, FlatQuery{TitlePrefix: "100%"}))
+	require.Equal(t, []string{"Hey! there"}, collectFlat(t, FlatQuery{TitlePrefix: "Hey!"}))
+	require.Empty(t, collectFlat(t, FlatQuery{TitlePrefix: "Set_upx"}))
+	require.NoError(t, config.DB.Where("title IN ?", []string{"100% off", "100 percent", "Hey! there"}).Delete(&models.Menu{}).Error)
+
+	future := time.Now().Add(time.Hour)
+	page, err := ListMenusFlat(context.Background(), FlatQuery{UpdatedSince: &future})
+	require.NoError(t, err)
+	require.Empty(t, page.Items)
+	past := time.Now().Add(-time.Hour)
+	page, err = ListMenusFlat(context.Background(), FlatQuery{UpdatedSince: &past})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 4)
+}
+
+func TestEscapeLike(t *testing.T) {
+	require.Equal(t, `a!%b!_c!!d\e`, escapeLike(`a%b_c!d\e`))
+	require.Equal(t, "title LIKE ? ESCAPE '!'", likeClause("title"))
+}
+
+func TestSearchMenus(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	for _, m := range []models.Menu{
+		{Title: "Billing", URL: ptrString("/settings/billing")},
+		{Title: "50% sale"},
+		{Title: "500 items"},
+		{Title: "Wow!"},
+	} {
+		require.NoError(t, CreateMenu(ctx, &m))
+	}
+
+	titles := func(q string) []string {
+		rows, err := SearchMenus(ctx, q, 0)
+		require.NoError(t, err)
+		var out []string
+		for _, r := range rows {
+			out = append(out, r.Title)
+		}
+		return out
+	}
+	require.Equal(t, []string{"Billing"}, titles("settings/bill"))
+	require.Equal(t, []string{"50% sale"}, titles("0%"))
+	require.Equal(t, []string{"Wow!"}, titles("w!"))
+	require.Empty(t, titles("5_0"))
+
+	_, err := SearchMenus(ctx, " ", 0)
+	require.ErrorIs(t, err, ErrInvalidQuery)
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// ErrNoMenuMatch is returned by ResolveMenuPath when no item URL matches.
+var ErrNoMenuMatch = fmt.Errorf("no menu item matches the path: %w", gorm.ErrRecordNotFound)
+
+// MenuMatch is the item whose URL best matches a page path, with its
+// ancestors from the root down (empty for a root item). Nodes are returned
+// without children.
+type MenuMatch struct {
+	Item      *models.MenuNode   `json:"item"`
+	Ancestors []*models.MenuNode `json:"ancestors"`
+	// Exact is false when the item URL is only a prefix of the path.
+	Exact bool `json:"exact"`
+	// Params holds the values of ":name" segments of a pattern URL.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// splitPath returns the cleaned segments of the path part of raw, or ok false
+// when raw is not a site-relative path ("/..."). Query and fragment are
+// ignored; "/" has no segments.
+func splitPath(raw string) (segs []string, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+		return nil, false
+	}
+	for _, s := range strings.Split(u.Path, "/") {
+		if s != "" {
+			segs = append(segs, s)
+		}
+	}
+	return segs, true
+}
+
+// menuPathScore ranks how well an item URL matches a path: exact matches beat
+// prefixes, then more matched segments and more literal (non ":param")
+// segments win.
+type menuPathScore struct {
+	exact    bool
+	segments int
+	literals int
+}
+
+func (s menuPathScore) beats(o menuPathScore) bool {
+	if s.exact != o.exact {
+		return s.exact
+	}
+	if s.segments != o.segments {
+		return s.segments > o.segments
+	}
+	return s.literals > o.literals
+}
+
+// matchMenuPath matches the segments of an item URL against a path; ok is
+// false when pattern is not a (segment-wise) prefix of path.
+func matchMenuPath(pattern, path []string) (score menuPathScore, params map[string]string, ok bool) {
+	if len(pattern) > len(path) {
+		return score, nil, false
+	}
+	for i, p := range pattern {
+		if name, isParam := strings.CutPrefix(p, ":"); isParam && name != "" {
+			if params == nil {
+				params = map[string]string{}
+			}
+			params[name] = path[i]
+			continue
+		}
+		if p != path[i] {
+			return score, nil, false
+		}
+		score.literals++
+	}
+	score.segments = len(pattern)
+	score.exact = len(pattern) == len(path)
+	return score, params, true
+}
+
+// ResolveMenuPath finds the item whose URL best matches path (e.g. the page
+// the user is on): an exact match first, otherwise the item whose URL is the
+// longest segment-wise prefix of path. Item URLs may contain ":name" segments
+// ("/orders/:id") that match any single segment. Ties go to the item that
+// comes first in the tree. Items with absolute URLs are ignored.
+func ResolveMenuPath(ctx context.Context, path string) (_ *MenuMatch, err error) {
+	ctx, span := tracing.Start(ctx, "services.ResolveMenuPath")
+	defer func() { tracing.End(span, err) }()
+
+	want, ok := splitPath(path)
+	if !ok {
+		return nil, fmt.Errorf("%w: path must start with /", ErrInvalidQuery)
+	}
+	tree, err := GetMenuTreeFn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		best      *MenuMatch
+		bestScore menuPathScore
+		chain     []*models.MenuNode
+	)
+	var walk func(level []*models.MenuNode)
+	walk = func(level []*models.MenuNode) {
+		for _, n := range level {
+			if n.URL != nil {
+				if pattern, ok := splitPath(*n.URL); ok {
+					score, params, ok := matchMenuPath(pattern, want)
+					if ok && (best == nil || score.beats(bestScore)) {
+						ancestors := make([]*models.MenuNode, len(chain))
+						for i, a := range chain {
+							ancestors[i] = leafCopy(a)
+						}
+						best = &MenuMatch{Item: leafCopy(n), Ancestors: ancestors, Exact: score.exact, Params: params}
+						bestScore = score
+					}
+				}
+			}
+			chain = append(chain, n)
+			walk(n.Children)
+			chain = chain[:len(chain)-1]
+		}
+	}
+	walk(tree)
+	if best == nil {
+		return nil, ErrNoMenuMatch
+	}
+	span.SetAttributes(attribute.Int("menu.id", int(best.Item.ID)), attribute.Bool("menu.exact", best.Exact))
+	return best, nil
+}
+
+// leafCopy copies n without its children (cached nodes are shared).
+func leafCopy(n *models.MenuNode) *models.MenuNode {
+	c := *n
+	c.Children = nil
+	return &c
+}
+
+// GetMenuChain returns the item id preceded by its ancestors, root first
+// (nodes without children). A missing item is gorm.ErrRecordNotFound.
+func GetMenuChain(ctx context.Context, id uint) (_ []*models.MenuNode, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetMenuChain")
+	defer func() { tracing.End(span, err) }()
+
+	tree, err := GetMenuTreeFn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var chain []*models.MenuNode
+	var find func(level []*models.MenuNode) bool
+	find = func(level []*models.MenuNode) bool {
+		for _, n := range level {
+			chain = append(chain, n)
+			if n.ID == id || find(n.Children) {
+				return true
+			}
+			chain = chain[:len(chain)-1]
+		}
+		return false
+	}
+	if !find(tree) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	out := make([]*models.MenuNode, len(chain))
+	for i, n := range chain {
+		out[i] = leafCopy(n)
+	}
+	return out, nil
+}
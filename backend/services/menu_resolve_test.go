@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestResolveMenuPath(t *testing.T) {
+	u := func(s string) *string { return &s }
+	p := func(id uint) *uint { return &id }
+	flat := []models.Menu{
+		{ID: 1, Title: "Home", URL: u("/")},
+		{ID: 2, Title: "Settings", URL: u("/settings")},
+		{ID: 3, Title: "Billing", URL: u("/settings/billing"), ParentID: p(2)},
+		{ID: 4, Title: "Cards", URL: u("/settings/billing/cards?tab=all"), ParentID: p(3)},
+		{ID: 5, Title: "Orders", URL: u("/orders")},
+		{ID: 6, Title: "Order", URL: u("/orders/:id"), ParentID: p(5)},
+		{ID: 7, Title: "New order", URL: u("/orders/new"), ParentID: p(5)},
+		{ID: 8, Title: "Docs", URL: u("https://example.com/settings")},
+	}
+	tree, err := BuildTree(flat)
+	require.NoError(t, err)
+	orig := GetMenuTreeFn
+	GetMenuTreeFn = func(context.Context) ([]*models.MenuNode, error) { return tree, nil }
+	defer func() { GetMenuTreeFn = orig }()
+	ctx := context.Background()
+
+	m, err := ResolveMenuPath(ctx, "/settings/billing/")
+	require.NoError(t, err)
+	require.Equal(t, uint(3), m.Item.ID)
+	require.True(t, m.Exact)
+	require.Len(t, m.Ancestors, 1)
+	require.Equal(t, uint(2), m.Ancestors[0].ID)
+	require.Nil(t, m.Item.Children, "nodes come without children")
+	require.Len(t, tree[1].Children, 1, "the cached tree is untouched")
+
+	m, err = ResolveMenuPath(ctx, "/settings/billing/cards?tab=visa#top")
+	require.NoError(t, err)
+	require.Equal(t, uint(4), m.Item.ID)
+	require.Equal(t, []uint{2, 3}, []uint{m.Ancestors[0].ID, m.Ancestors[1].ID})
+
+	m, err = ResolveMenuPath(ctx, "/settings/profile")
+	require.NoError(t, err)
+	require.Equal(t, uint(2), m.Item.ID, "longest prefix")
+	require.False(t, m.Exact)
+
+	m, err = ResolveMenuPath(ctx, "/orders/42")
+	require.NoError(t, err)
+	require.Equal(t, uint(6), m.Item.ID)
+	require.Equal(t, map[string]string{"id": "42"}, m.Params)
+
+	m, err = ResolveMenuPath(ctx, "/orders/new")
+	require.NoError(t, err)
+	require.Equal(t, uint(7), m.Item.ID, "literal segments beat patterns")
+
+	m, err = ResolveMenuPath(ctx, "/orders/42/items")
+	require.NoError(t, err)
+	require.Equal(t, uint(6), m.Item.ID)
+	require.False(t, m.Exact)
+
+	m, err = ResolveMenuPath(ctx, "/elsewhere")
+	require.NoError(t, err)
+	require.Equal(t, uint(1), m.Item.ID, "/ is the prefix of everything")
+
+	_, err = ResolveMenuPath(ctx, "settings")
+	require.ErrorIs(t, err, ErrInvalidQuery)
+	_, err = ResolveMenuPath(ctx, "https://example.com/settings")
+	require.ErrorIs(t, err, ErrInvalidQuery)
+
+	GetMenuTreeFn = func(context.Context) ([]*models.MenuNode, error) { return tree[1:], nil }
+	_, err = ResolveMenuPath(ctx, "/elsewhere")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
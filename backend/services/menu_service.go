@@ -3,17 +3,34 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/galpt/sotekre/backend/config"
 	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// orderColumn is the reserved-word "order" column, quoted by the dialect
+// (`order` on MySQL, where a double-quoted "order" is a string constant).
+var orderColumn = clause.Column{Name: "order"}
+
+// byOrder sorts siblings by orderColumn.
+var byOrder = clause.OrderByColumn{Column: orderColumn}
+
 // GetAllMenus returns all menus ordered by `order` ASC.
-func GetAllMenus(ctx context.Context) ([]models.Menu, error) {
+func GetAllMenus(ctx context.Context) (_ []models.Menu, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetAllMenus")
+	defer func() { tracing.End(span, err) }()
+
 	var menus []models.Menu
-	if err := config.DB.Order("\"order\" asc").Find(&menus).Error; err != nil {
+	if err := config.DB.WithContext(ctx).Order(byOrder).Find(&menus).Error; err != nil {
 		return nil, err
 	}
 	return menus, nil
@@ -61,28 +78,297 @@ func BuildTree(flat []models.Menu) ([]*models.MenuNode, error) {
 	return roots, nil
 }
 
-// CreateMenu inserts a new Menu row.
-func CreateMenu(ctx context.Context, m *models.Menu) error {
-	if m.Title == "" {
-		return errors.New("title is required")
+// CreateMenu validates (see ValidateMenu) and inserts a new Menu row. A
+// ParentID that does not exist is ErrParentNotFound. Without a Key one is
+// generated from the title; a Key already in use is ErrKeyTaken.
+func CreateMenu(ctx context.Context, m *models.Menu) (err error) {
+	ctx, span := tracing.Start(ctx, "services.CreateMenu")
+	defer func() { tracing.End(span, err) }()
+
+	if err := ValidateMenu(m); err != nil {
+		return err
+	}
+	var changes changeLog
+	defer changes.done(ctx)
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if m.ParentID != nil {
+			if err := checkNewParent(tx, 0, *m.ParentID); err != nil {
+				return err
+			}
+		}
+		if err := checkKeyFree(tx, m.Key, 0); err != nil {
+			return err
+		}
+		if err := tx.Create(m).Error; err != nil {
+			return err
+		}
+		return changes.record(tx, EventCreated, []uint{m.ID})
+	})
+	if err != nil {
+		return keyConflict(err)
+	}
+	afterCommit(ctx, EventCreated, []uint{m.ID})
+	return nil
+}
+
+// GetMenu returns a single menu item (gorm.ErrRecordNotFound when missing).
+func GetMenu(ctx context.Context, id uint) (_ *models.Menu, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetMenu", trace.WithAttributes(attribute.Int64("menu.id", int64(id))))
+	defer func() { tracing.End(span, err) }()
+
+	var m models.Menu
+	if err := config.DB.WithContext(ctx).First(&m, id).Error; err != nil {
+		return nil, err
 	}
-	return config.DB.Create(m).Error
+	return &m, nil
 }
 
-// UpdateMenu updates allowed fields for a menu item.
-func UpdateMenu(ctx context.Context, id uint, upd map[string]interface{}) error {
+// UpdateMenu updates allowed fields for a menu item. Values are checked and
+// normalized by ValidateMenuUpdate; a new parent_id must exist and must not be
+// the item itself or one of its descendants, and a new key must be free
+// (ErrKeyTaken). A parent or order change is a move (see updateInTx); the
+// siblings it renumbered are returned like by MoveMenu. A missing item is
+// gorm.ErrRecordNotFound.
+func UpdateMenu(ctx context.Context, id uint, upd map[string]interface{}) (_ []SiblingOrder, err error) {
+	ctx, span := tracing.Start(ctx, "services.UpdateMenu", trace.WithAttributes(attribute.Int64("menu.id", int64(id))))
+	defer func() { tracing.End(span, err) }()
+
 	if len(upd) == 0 {
-		return errors.New("no fields to update")
+		return nil, errors.New("no fields to update")
+	}
+	if upd, err = ValidateMenuUpdate(upd); err != nil {
+		return nil, err
+	}
+	var siblings []SiblingOrder
+	var changes changeLog
+	defer changes.done(ctx)
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cur models.Menu
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cur, id).Error; err != nil {
+			return err
+		}
+		var err error
+		siblings, err = updateInTx(tx, &changes, &cur, upd)
+		return err
+	})
+	if err != nil {
+		return nil, keyConflict(err)
+	}
+	afterCommit(ctx, EventUpdated, []uint{id})
+	afterCommit(ctx, EventReordered, writtenIDs(siblings))
+	return siblings, nil
+}
+
+// PatchMenu reads item id, passes it to patch and applies the update patch
+// returns, in one transaction with the item locked: a patch computed from
+// (or testing) the current state cannot overwrite a concurrent change. The
+// update is validated like UpdateMenu's and returns the renumbered siblings
+// the same way; an empty one leaves the item unchanged (changed is false).
+// Errors from patch are returned as they are.
+func PatchMenu(ctx context.Context, id uint, patch func(cur *models.Menu) (map[string]interface{}, error)) (_ []SiblingOrder, changed bool, err error) {
+	ctx, span := tracing.Start(ctx, "services.PatchMenu", trace.WithAttributes(attribute.Int64("menu.id", int64(id))))
+	defer func() { tracing.End(span, err) }()
+
+	var siblings []SiblingOrder
+	var changes changeLog
+	defer changes.done(ctx)
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cur models.Menu
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cur, id).Error; err != nil {
+			return err
+		}
+		upd, err := patch(&cur)
+		if err != nil {
+			return err
+		}
+		if changed = len(upd) > 0; !changed {
+			return nil
+		}
+		if upd, err = ValidateMenuUpdate(upd); err != nil {
+			return err
+		}
+		siblings, err = updateInTx(tx, &changes, &cur, upd)
+		return err
+	})
+	if err != nil || !changed {
+		return nil, false, keyConflict(err)
+	}
+	afterCommit(ctx, EventUpdated, []uint{id})
+	afterCommit(ctx, EventReordered, writtenIDs(siblings))
+	return siblings, true, nil
+}
+
+// updateInTx applies a validated update to cur inside tx and logs it. A new
+// parent or an order goes through moveInTx, so the item's old and new
+// siblings are renumbered like for MoveMenu (order is the index among the
+// siblings; a new parent without one appends). It returns the siblings the
+// move renumbered.
+func updateInTx(tx *gorm.DB, changes *changeLog, cur *models.Menu, upd map[string]interface{}) ([]SiblingOrder, error) {
+	parentID := cur.ParentID
+	raw, parentSet := upd["parent_id"]
+	if parentSet {
+		switch p := raw.(type) {
+		case *uint:
+			parentID = p
+		case uint:
+			parentID = &p
+		default:
+			parentID = nil
+		}
+		if parentID != nil {
+			if err := checkNewParent(tx, cur.ID, *parentID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	order, orderSet := upd["order"].(int)
+	delete(upd, "parent_id")
+	delete(upd, "order")
+
+	if k, ok := upd["key"].(string); ok {
+		if err := checkKeyFree(tx, k, cur.ID); err != nil {
+			return nil, err
+		}
+		delete(upd, "key")
+		upd["menu_key"] = k
+	}
+	if len(upd) > 0 {
+		if err := tx.Model(&models.Menu{}).Where("id = ?", cur.ID).Updates(upd).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var siblings []SiblingOrder
+	moved := (parentID == nil) != (cur.ParentID == nil) || (parentID != nil && *parentID != *cur.ParentID)
+	if moved || orderSet {
+		var at *int
+		if orderSet {
+			at = &order
+		}
+		written, err := moveInTx(tx, cur.ID, parentID, at)
+		if err != nil {
+			return nil, err
+		}
+		siblings = siblingOrders(written, cur.ID)
+	}
+	if err := changes.record(tx, EventUpdated, []uint{cur.ID}); err != nil {
+		return nil, err
+	}
+	return siblings, changes.record(tx, EventReordered, writtenIDs(siblings))
+}
+
+// checkKeyFree returns ErrKeyTaken when key belongs to an item other than id.
+func checkKeyFree(tx *gorm.DB, key string, id uint) error {
+	if key == "" {
+		return nil
+	}
+	var n int64
+	if err := tx.Unscoped().Model(&models.Menu{}).Where("menu_key = ? AND id <> ?", key, id).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrKeyTaken
+	}
+	return nil
+}
+
+// GetMenuIDByKey resolves a menu key to its id (gorm.ErrRecordNotFound when
+// no item has it).
+func GetMenuIDByKey(ctx context.Context, key string) (uint, error) {
+	var m models.Menu
+	if err := config.DB.WithContext(ctx).Select("id").Where("menu_key = ?", key).First(&m).Error; err != nil {
+		return 0, err
+	}
+	return m.ID, nil
+}
+
+// MenuKeyPrefix marks a menu reference that is a menu key rather than a
+// numeric id, e.g. /api/menus/key:orders.
+const MenuKeyPrefix = "key:"
+
+// ErrInvalidMenuID is returned by LookupMenuID for a malformed id.
+var ErrInvalidMenuID = errors.New("id must be a positive integer or key:<menu key>")
+
+// LookupMenuID resolves a menu reference, a numeric id or "key:<menu key>",
+// as accepted by every API.
+func LookupMenuID(ctx context.Context, raw string) (uint, error) {
+	if key, ok := strings.CutPrefix(raw, MenuKeyPrefix); ok {
+		return GetMenuIDByKeyFn(ctx, key)
+	}
+	id64, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || id64 == 0 {
+		return 0, ErrInvalidMenuID
 	}
-	return config.DB.Model(&models.Menu{}).Where("id = ?", id).Updates(upd).Error
+	return uint(id64), nil
+}
+
+// BackfillMenuKeys gives every item without a key (rows from before keys
+// existed, or inserted by SQL imports) one generated from its title.
+func BackfillMenuKeys(ctx context.Context) (n int, err error) {
+	ctx, span := tracing.Start(ctx, "services.BackfillMenuKeys")
+	defer func() { tracing.End(span, err) }()
+
+	var missing []models.Menu
+	db := config.DB.WithContext(ctx)
+	if err := db.Unscoped().Select("id", "title").Where("menu_key IS NULL OR menu_key = ''").Order("id").Find(&missing).Error; err != nil {
+		return 0, err
+	}
+	for _, m := range missing {
+		key, err := models.UniqueKey(db, models.Slugify(m.Title))
+		if err != nil {
+			return n, err
+		}
+		if err := db.Unscoped().Model(&models.Menu{}).Where("id = ?", m.ID).UpdateColumn("menu_key", key).Error; err != nil {
+			return n, err
+		}
+		n++
+	}
+	if n > 0 {
+		InvalidateMenuTree()
+	}
+	return n, nil
+}
+
+// checkNewParent verifies that parentID exists and is neither id nor one of
+// its descendants.
+func checkNewParent(tx *gorm.DB, id, parentID uint) error {
+	cur := &parentID
+	for depth := 0; cur != nil; depth++ {
+		if *cur == id {
+			return ErrCycle
+		}
+		var p models.Menu
+		if err := tx.Select("parent_id").Where("id = ?", *cur).First(&p).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if depth == 0 {
+				return ErrParentNotFound
+			}
+			return nil // dangling ancestor: the chain ends here
+		}
+		cur = p.ParentID
+	}
+	return nil
 }
 
 // DeleteMenuRecursive deletes a menu and all its children (transactional).
 // Uses HARD DELETE (Unscoped) to permanently remove from database.
-func DeleteMenuRecursive(ctx context.Context, id uint) error {
-	return config.DB.Transaction(func(tx *gorm.DB) error {
+func DeleteMenuRecursive(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.DeleteMenuRecursive", trace.WithAttributes(attribute.Int64("menu.id", int64(id))))
+	defer func() { tracing.End(span, err) }()
+
+	var toDelete []uint
+	var changes changeLog
+	defer changes.done(ctx)
+	err = changes.runTx(ctx, "delete", func(tx *gorm.DB) error {
+		// a missing item is a 404, not an empty delete that still publishes
+		var root models.Menu
+		if err := tx.Select("id").First(&root, id).Error; err != nil {
+			return err
+		}
 		// Find children recursively and delete permanently
-		var toDelete []uint
+		toDelete = toDelete[:0]
 		var stack = []uint{id}
 		for len(stack) > 0 {
 			cur := stack[len(stack)-1]
@@ -101,186 +387,505 @@ func DeleteMenuRecursive(ctx context.Context, id uint) error {
 		if err := tx.Unscoped().Where("id IN (?)", toDelete).Delete(&models.Menu{}).Error; err != nil {
 			return err
 		}
-		return nil
+		return changes.record(tx, EventDeleted, toDelete)
 	})
+	if err != nil {
+		return err
+	}
+	afterCommit(ctx, EventDeleted, toDelete)
+	return nil
 }
 
-// ReorderMenu reorders an item within its current parent to the specified index.
-func ReorderMenu(ctx context.Context, id uint, newOrder int) error {
+// SiblingOrder is the position a move gave to a row it renumbered.
+type SiblingOrder struct {
+	ID       uint  `json:"id" example:"3"`
+	ParentID *uint `json:"parent_id" example:"1"`
+	Order    int   `json:"order" example:"2"`
+}
+
+// ReorderMenu reorders an item within its current parent to the specified
+// index. It returns the new order of every sibling it renumbered (not the item
+// itself; empty when the position did not change).
+func ReorderMenu(ctx context.Context, id uint, newOrder int) (_ []SiblingOrder, err error) {
+	ctx, span := tracing.Start(ctx, "services.ReorderMenu", trace.WithAttributes(attribute.Int64("menu.id", int64(id))))
+	defer func() { tracing.End(span, err) }()
+
 	// fetch item's current parent and delegate to MoveMenu
 	var item models.Menu
-	if err := config.DB.First(&item, id).Error; err != nil {
-		return err
+	if err := config.DB.WithContext(ctx).First(&item, id).Error; err != nil {
+		return nil, err
 	}
-	return MoveMenu(ctx, id, item.ParentID, &newOrder)
+	written, err := moveMenu(ctx, EventReordered, id, item.ParentID, &newOrder)
+	if err != nil {
+		return nil, err
+	}
+	afterCommit(ctx, EventReordered, writtenIDs(written))
+	return siblingOrders(written, id), nil
 }
 
 // MoveMenu moves an item to a (possibly different) parent and inserts it at newOrder.
 // If newOrder is nil the item will be appended to the destination's children.
-func MoveMenu(ctx context.Context, id uint, newParentID *uint, newOrder *int) error {
-	return config.DB.Transaction(func(tx *gorm.DB) error {
-		// load the item
-		var item models.Menu
-		if err := tx.Clauses().First(&item, id).Error; err != nil {
+// It returns the new order of every sibling it renumbered in the source and
+// destination parents (not the item itself).
+func MoveMenu(ctx context.Context, id uint, newParentID *uint, newOrder *int) (_ []SiblingOrder, err error) {
+	ctx, span := tracing.Start(ctx, "services.MoveMenu", trace.WithAttributes(attribute.Int64("menu.id", int64(id))))
+	defer func() { tracing.End(span, err) }()
+
+	written, err := moveMenu(ctx, EventMoved, id, newParentID, newOrder)
+	if err != nil {
+		return nil, err
+	}
+	afterCommit(ctx, EventMoved, writtenIDs(written))
+	return siblingOrders(written, id), nil
+}
+
+// moveMenu runs the move transaction shared by MoveMenu and ReorderMenu,
+// logging the rows it wrote as typ, and returns them (empty for a no-op).
+func moveMenu(ctx context.Context, typ EventType, id uint, newParentID *uint, newOrder *int) ([]SiblingOrder, error) {
+	var written []SiblingOrder
+	var changes changeLog
+	defer changes.done(ctx)
+	err := changes.runTx(ctx, "move", func(tx *gorm.DB) error {
+		var err error
+		if written, err = moveInTx(tx, id, newParentID, newOrder); err != nil {
 			return err
 		}
+		return changes.record(tx, typ, writtenIDs(written))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return written, nil
+}
 
-		oldParent := item.ParentID
+// writtenIDs returns the ids of the rows a move wrote, for its change event.
+func writtenIDs(written []SiblingOrder) []uint {
+	ids := make([]uint, len(written))
+	for i, w := range written {
+		ids[i] = w.ID
+	}
+	return ids
+}
 
-		// prevent moving item into its own descendant (walk up from destination)
-		if newParentID != nil {
-			cur := newParentID
-			for cur != nil {
-				if *cur == id {
-					return errors.New("cannot move item into its own descendant")
-				}
-				var p models.Menu
-				if err := tx.Select("parent_id").Where("id = ?", *cur).First(&p).Error; err != nil {
-					if errors.Is(err, gorm.ErrRecordNotFound) {
-						break
-					}
-					return err
+// siblingOrders returns the rows a move wrote other than the moved item id.
+func siblingOrders(written []SiblingOrder, id uint) []SiblingOrder {
+	out := make([]SiblingOrder, 0, len(written))
+	for _, w := range written {
+		if w.ID != id {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// forRow tags the statements run through the returned tx with the menu row
+// they target (span attribute menu.id), so the per-sibling UPDATEs of a move
+// can be told apart in a trace.
+func forRow(tx *gorm.DB, id uint) *gorm.DB {
+	return tx.WithContext(tracing.WithStatementAttributes(tx.Statement.Context, attribute.Int64("menu.id", int64(id))))
+}
+
+// moveInTx places item id under newParentID at index newOrder (append when
+// nil), renumbering source and destination siblings inside tx. It returns
+// every row it wrote with its new parent and order.
+func moveInTx(tx *gorm.DB, id uint, newParentID *uint, newOrder *int) ([]SiblingOrder, error) {
+	var written []SiblingOrder
+	// load the item
+	var item models.Menu
+	if err := tx.Clauses().First(&item, id).Error; err != nil {
+		return nil, err
+	}
+
+	oldParent := item.ParentID
+
+	// prevent moving item into its own descendant (walk up from destination)
+	if newParentID != nil {
+		cur := newParentID
+		for cur != nil {
+			if *cur == id {
+				return nil, ErrCycle
+			}
+			var p models.Menu
+			if err := tx.Select("parent_id").Where("id = ?", *cur).First(&p).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					break
 				}
-				cur = p.ParentID
+				return nil, err
 			}
+			cur = p.ParentID
 		}
+	}
 
-		// fetch destination siblings (excluding the item)
-		var destSibs []models.Menu
-		q := tx.Model(&models.Menu{})
-		if newParentID == nil {
-			q = q.Where("parent_id IS NULL")
+	// fetch destination siblings (excluding the item)
+	var destSibs []models.Menu
+	q := tx.Model(&models.Menu{})
+	if newParentID == nil {
+		q = q.Where("parent_id IS NULL")
+	} else {
+		q = q.Where("parent_id = ?", *newParentID)
+	}
+	if err := q.Order(byOrder).Find(&destSibs).Error; err != nil {
+		return nil, err
+	}
+	// filter out the moving item if present (same-parent move)
+	tabledest := make([]models.Menu, 0, len(destSibs))
+	for _, s := range destSibs {
+		if s.ID == id {
+			continue
+		}
+		tabledest = append(tabledest, s)
+	}
+	destSibs = tabledest
+
+	// determine insertion index
+	insertIdx := len(destSibs) // append by default
+	if newOrder != nil {
+		if *newOrder < 0 {
+			insertIdx = 0
+		} else if *newOrder > len(destSibs) {
+			insertIdx = len(destSibs)
 		} else {
-			q = q.Where("parent_id = ?", *newParentID)
+			insertIdx = *newOrder
 		}
-		if err := q.Order("\"order\" asc").Find(&destSibs).Error; err != nil {
-			return err
+	}
+
+	// if moving within same parent and position unchanged -> no-op
+	if (oldParent == nil && newParentID == nil) || (oldParent != nil && newParentID != nil && *oldParent == *newParentID) {
+		// same parent: check index
+		// build current order slice (excluding item)
+		var srcSibs []models.Menu
+		srcQ := tx.Model(&models.Menu{})
+		if oldParent == nil {
+			srcQ = srcQ.Where("parent_id IS NULL")
+		} else {
+			srcQ = srcQ.Where("parent_id = ?", *oldParent)
 		}
-		// filter out the moving item if present (same-parent move)
-		tabledest := make([]models.Menu, 0, len(destSibs))
-		for _, s := range destSibs {
+		if err := srcQ.Order(byOrder).Find(&srcSibs).Error; err != nil {
+			return nil, err
+		}
+		// find current index of item among siblings
+		curIdx := -1
+		for i, s := range srcSibs {
 			if s.ID == id {
-				continue
+				curIdx = i
+				break
 			}
-			tabledest = append(tabledest, s)
 		}
-		destSibs = tabledest
-
-		// determine insertion index
-		insertIdx := len(destSibs) // append by default
-		if newOrder != nil {
-			if *newOrder < 0 {
-				insertIdx = 0
-			} else if *newOrder > len(destSibs) {
-				insertIdx = len(destSibs)
-			} else {
-				insertIdx = *newOrder
+		if curIdx == -1 {
+			// item might be missing from list (shouldn't happen) — continue to generic path
+		} else {
+			// compute target index after removing the item
+			if newParentID == nil && oldParent == nil || (oldParent != nil && newParentID != nil && *oldParent == *newParentID) {
+				// remove current
+				// If inserting after the current index, decrement to account for removal —
+				// but do NOT decrement when the target is an append (insertIdx == len(destSibs)),
+				// because appending should place the item after all other siblings.
+				if insertIdx > curIdx && insertIdx != len(destSibs) {
+					insertIdx-- // account for removal earlier in the list (skip when appending)
+				}
+				if insertIdx == curIdx {
+					return nil, nil // nothing to do
+				}
 			}
 		}
+	}
+
+	// Build final destination ID order (slice of IDs) by inserting item ID at insertIdx
+	finalIDs := make([]uint, 0, len(destSibs)+1)
+	for i, s := range destSibs {
+		if i == insertIdx {
+			finalIDs = append(finalIDs, id)
+		}
+		finalIDs = append(finalIDs, s.ID)
+	}
+	if insertIdx == len(destSibs) {
+		finalIDs = append(finalIDs, id)
+	}
 
-		// if moving within same parent and position unchanged -> no-op
-		if (oldParent == nil && newParentID == nil) || (oldParent != nil && newParentID != nil && *oldParent == *newParentID) {
-			// same parent: check index
-			// build current order slice (excluding item)
-			var srcSibs []models.Menu
+	// If moving between different parents, compact the source parent's orders (remove the item)
+	if !(oldParent == nil && newParentID == nil) {
+		sameParent := oldParent != nil && newParentID != nil && *oldParent == *newParentID
+		if !sameParent {
+			var srcRem []models.Menu
 			srcQ := tx.Model(&models.Menu{})
 			if oldParent == nil {
 				srcQ = srcQ.Where("parent_id IS NULL")
 			} else {
 				srcQ = srcQ.Where("parent_id = ?", *oldParent)
 			}
-			if err := srcQ.Order("\"order\" asc").Find(&srcSibs).Error; err != nil {
-				return err
-			}
-			// find current index of item among siblings
-			curIdx := -1
-			for i, s := range srcSibs {
+			srcQ.Order(byOrder).Find(&srcRem)
+			// renumber srcRem excluding item
+			idx := 0
+			for _, s := range srcRem {
 				if s.ID == id {
-					curIdx = i
-					break
+					continue
 				}
-			}
-			if curIdx == -1 {
-				// item might be missing from list (shouldn't happen) — continue to generic path
-			} else {
-				// compute target index after removing the item
-				if newParentID == nil && oldParent == nil || (oldParent != nil && newParentID != nil && *oldParent == *newParentID) {
-					// remove current
-					// If inserting after the current index, decrement to account for removal —
-					// but do NOT decrement when the target is an append (insertIdx == len(destSibs)),
-					// because appending should place the item after all other siblings.
-					if insertIdx > curIdx && insertIdx != len(destSibs) {
-						insertIdx-- // account for removal earlier in the list (skip when appending)
-					}
-					if insertIdx == curIdx {
-						return nil // nothing to do
+				if s.Order != idx {
+					if err := forRow(tx, s.ID).Model(&models.Menu{}).Where("id = ?", s.ID).Update("order", idx).Error; err != nil {
+						return nil, err
 					}
+					written = append(written, SiblingOrder{ID: s.ID, ParentID: oldParent, Order: idx})
 				}
+				idx++
 			}
 		}
+	}
 
-		// Build final destination ID order (slice of IDs) by inserting item ID at insertIdx
-		finalIDs := make([]uint, 0, len(destSibs)+1)
-		for i, s := range destSibs {
-			if i == insertIdx {
-				finalIDs = append(finalIDs, id)
-			}
-			finalIDs = append(finalIDs, s.ID)
+	// write back destination ordering and update parent for the moved item
+	for idx, idv := range finalIDs {
+		upd := map[string]interface{}{"order": idx}
+		// for the moved item, ensure parent_id is set to newParentID
+		if idv == id {
+			upd["parent_id"] = newParentID
 		}
-		if insertIdx == len(destSibs) {
-			finalIDs = append(finalIDs, id)
+		if err := forRow(tx, idv).Model(&models.Menu{}).Where("id = ?", idv).Updates(upd).Error; err != nil {
+			return nil, err
 		}
+		written = append(written, SiblingOrder{ID: idv, ParentID: newParentID, Order: idx})
+	}
+
+	return written, nil
+}
 
-		// If moving between different parents, compact the source parent's orders (remove the item)
-		if !(oldParent == nil && newParentID == nil) {
-			sameParent := oldParent != nil && newParentID != nil && *oldParent == *newParentID
-			if !sameParent {
-				var srcRem []models.Menu
-				srcQ := tx.Model(&models.Menu{})
-				if oldParent == nil {
-					srcQ = srcQ.Where("parent_id IS NULL")
-				} else {
-					srcQ = srcQ.Where("parent_id = ?", *oldParent)
+// DuplicateOptions controls where DuplicateMenu places the copy.
+type DuplicateOptions struct {
+	// NextToSource places the copy right after the original (same parent);
+	// ParentID and Position are then ignored.
+	NextToSource bool
+	// ParentID is the destination parent; nil places the copy at the root.
+	ParentID *uint
+	// Position is the index among the destination's children; nil appends.
+	Position *int
+	// TitleSuffix is appended to the copied root's title (e.g. " (copy)").
+	TitleSuffix string
+}
+
+// DuplicateMenu deep-copies item id and all its descendants in one transaction.
+// Copies get new ids and keep their relative order; the copied root is placed
+// among the destination siblings with the same logic as MoveMenu.
+func DuplicateMenu(ctx context.Context, id uint, opts DuplicateOptions) (_ *models.Menu, err error) {
+	ctx, span := tracing.Start(ctx, "services.DuplicateMenu", trace.WithAttributes(attribute.Int64("menu.id", int64(id))))
+	defer func() { tracing.End(span, err) }()
+
+	var root models.Menu
+	var written, shifted []uint
+	var changes changeLog
+	defer changes.done(ctx)
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		written, shifted = written[:0], shifted[:0]
+		var src models.Menu
+		if err := tx.First(&src, id).Error; err != nil {
+			return err
+		}
+		if opts.NextToSource {
+			var sibs []models.Menu
+			q := tx.Model(&models.Menu{}).Select("id")
+			if src.ParentID == nil {
+				q = q.Where("parent_id IS NULL")
+			} else {
+				q = q.Where("parent_id = ?", *src.ParentID)
+			}
+			if err := q.Order(byOrder).Find(&sibs).Error; err != nil {
+				return err
+			}
+			pos := len(sibs)
+			for i, sb := range sibs {
+				if sb.ID == src.ID {
+					pos = i + 1
+					break
 				}
-				srcQ.Order("\"order\" asc").Find(&srcRem)
-				// renumber srcRem excluding item
-				idx := 0
-				for _, s := range srcRem {
-					if s.ID == id {
-						continue
-					}
-					if s.Order != idx {
-						if err := tx.Model(&models.Menu{}).Where("id = ?", s.ID).Update("order", idx).Error; err != nil {
-							return err
-						}
-					}
-					idx++
+			}
+			opts.ParentID, opts.Position = src.ParentID, &pos
+		}
+		if opts.ParentID != nil {
+			var parent models.Menu
+			if err := tx.Select("id").First(&parent, *opts.ParentID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrParentNotFound
 				}
+				return err
 			}
 		}
 
-		// write back destination ordering and update parent for the moved item
-		for idx, idv := range finalIDs {
-			upd := map[string]interface{}{"order": idx}
-			// for the moved item, ensure parent_id is set to newParentID
-			if idv == id {
-				upd["parent_id"] = newParentID
+		// snapshot the subtree (breadth-first) before writing, so copying into
+		// the source's own subtree does not pick up the copies
+		subtree := []models.Menu{src}
+		for i := 0; i < len(subtree); i++ {
+			var children []models.Menu
+			if err := tx.Where("parent_id = ?", subtree[i].ID).Order(byOrder).Find(&children).Error; err != nil {
+				return err
+			}
+			subtree = append(subtree, children...)
+		}
+
+		// start the copy after the destination's last sibling; moveInTx then
+		// renumbers it into the requested position
+		var maxOrder *int
+		q := tx.Model(&models.Menu{}).Select("MAX(?)", orderColumn)
+		if opts.ParentID == nil {
+			q = q.Where("parent_id IS NULL")
+		} else {
+			q = q.Where("parent_id = ?", *opts.ParentID)
+		}
+		if err := q.Scan(&maxOrder).Error; err != nil {
+			return err
+		}
+		root = models.Menu{
+			Title:    src.Title + opts.TitleSuffix,
+			URL:      src.URL,
+			Icon:     src.Icon,
+			ParentID: opts.ParentID,
+		}
+		if maxOrder != nil {
+			root.Order = *maxOrder + 1
+		}
+		// the suffix may push the title past its limit
+		if err := ValidateMenu(&root); err != nil {
+			return err
+		}
+		if err := tx.Create(&root).Error; err != nil {
+			return err
+		}
+		written = append(written, root.ID)
+
+		// parents precede children in subtree, so each new parent id is known
+		newIDs := map[uint]uint{src.ID: root.ID}
+		for _, m := range subtree[1:] {
+			parentID := newIDs[*m.ParentID]
+			cp := models.Menu{
+				Title:    m.Title,
+				URL:      m.URL,
+				Icon:     m.Icon,
+				ParentID: &parentID,
+				Order:    m.Order,
 			}
-			if err := tx.Model(&models.Menu{}).Where("id = ?", idv).Updates(upd).Error; err != nil {
+			if err := tx.Create(&cp).Error; err != nil {
 				return err
 			}
+			newIDs[m.ID] = cp.ID
+			written = append(written, cp.ID)
 		}
 
-		return nil
+		moved, err := moveInTx(tx, root.ID, opts.ParentID, opts.Position)
+		if err != nil {
+			return err
+		}
+		shifted = writtenIDs(siblingOrders(moved, root.ID))
+		if err := tx.First(&root, root.ID).Error; err != nil {
+			return err
+		}
+		if err := changes.record(tx, EventCreated, written); err != nil {
+			return err
+		}
+		return changes.record(tx, EventReordered, shifted)
 	})
+	if err != nil {
+		return nil, keyConflict(err)
+	}
+	// the copies are new; the siblings that made room for them only moved
+	afterCommit(ctx, EventCreated, written)
+	afterCommit(ctx, EventReordered, shifted)
+	return &root, nil
+}
+
+// ErrCycle is returned when a move would place an item under itself or one
+// of its descendants.
+var ErrCycle = errors.New("cannot move item into its own descendant")
+
+// ErrParentNotFound is returned by CreateMenu, UpdateMenu and DuplicateMenu
+// when the destination parent does not exist. It wraps
+// gorm.ErrRecordNotFound.
+var ErrParentNotFound = fmt.Errorf("destination parent: %w", gorm.ErrRecordNotFound)
+
+// ErrKeyTaken is returned when a menu key is already used by another item,
+// also when a concurrent writer took it between the check and the write.
+var ErrKeyTaken = errors.New("key is already used by another menu item")
+
+// ErrChildSetMismatch is returned by SetChildOrder when the ids are not
+// exactly the parent's current children (the client's view is stale).
+var ErrChildSetMismatch = errors.New("ids must list exactly the current children")
+
+// ErrDuplicateIDs is returned when an id list contains the same id twice.
+var ErrDuplicateIDs = errors.New("ids must not contain duplicates")
+
+// SetChildOrder rewrites the order of all children of parentID (nil = roots)
+// in one transaction. ids must be a permutation of the current children.
+func SetChildOrder(ctx context.Context, parentID *uint, ids []uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.SetChildOrder")
+	defer func() { tracing.End(span, err) }()
+
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return ErrDuplicateIDs
+		}
+		seen[id] = true
+	}
+
+	var written []uint
+	var changes changeLog
+	defer changes.done(ctx)
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		written = written[:0]
+		if parentID != nil {
+			var parent models.Menu
+			if err := tx.Select("id").First(&parent, *parentID).Error; err != nil {
+				return err
+			}
+		}
+		var current []models.Menu
+		q := tx.Model(&models.Menu{}).Select("id", "order")
+		if parentID == nil {
+			q = q.Where("parent_id IS NULL")
+		} else {
+			q = q.Where("parent_id = ?", *parentID)
+		}
+		if err := q.Find(&current).Error; err != nil {
+			return err
+		}
+		if len(current) != len(ids) {
+			return ErrChildSetMismatch
+		}
+		orders := make(map[uint]int, len(current))
+		for _, m := range current {
+			if !seen[m.ID] {
+				return ErrChildSetMismatch
+			}
+			orders[m.ID] = m.Order
+		}
+		for idx, id := range ids {
+			if orders[id] == idx {
+				continue
+			}
+			if err := forRow(tx, id).Model(&models.Menu{}).Where("id = ?", id).Update("order", idx).Error; err != nil {
+				return err
+			}
+			written = append(written, id)
+		}
+		return changes.record(tx, EventReordered, written)
+	})
+	if err != nil {
+		return err
+	}
+	afterCommit(ctx, EventReordered, written)
+	return nil
 }
 
 // Test hooks — allow handlers to stub behavior in tests.
 var (
 	CreateMenuFn          = CreateMenu
+	GetMenuFn             = GetMenu
+	GetMenuIDByKeyFn      = GetMenuIDByKey
 	UpdateMenuFn          = UpdateMenu
+	PatchMenuFn           = PatchMenu
 	ReorderMenuFn         = ReorderMenu
 	MoveMenuFn            = MoveMenu
 	DeleteMenuRecursiveFn = DeleteMenuRecursive
 	GetAllMenusFn         = GetAllMenus
+	GetMenuTreeFn         = GetMenuTree
+	ResolveMenuPathFn     = ResolveMenuPath
+	GetMenuChainFn        = GetMenuChain
+	SearchMenusFn         = SearchMenus
+	DuplicateMenuFn       = DuplicateMenu
+	SetChildOrderFn       = SetChildOrder
 )
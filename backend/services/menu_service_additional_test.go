@@ -36,14 +36,14 @@ func TestCreateMenu_emptyTitle_errors(t *testing.T) {
 func TestReorderMenu_nonExistentID_returnsError(t *testing.T) {
 	setupInMemoryDBForServicesTest(t)
 	defer config.CloseDB()
-	if err := ReorderMenu(context.Background(), 99999, 1); err == nil {
+	if _, err := ReorderMenu(context.Background(), 99999, 1); err == nil {
 		t.Fatalf("expected error when reordering non-existent id")
 	}
 }
 
 func TestUpdateMenu_noFields_returnsError(t *testing.T) {
 	// no DB required — fast path
-	if err := UpdateMenu(context.Background(), 1, map[string]interface{}{}); err == nil {
+	if _, err := UpdateMenu(context.Background(), 1, map[string]interface{}{}); err == nil {
 		t.Fatalf("expected error for empty update map")
 	}
 }
@@ -55,18 +55,92 @@ func TestUpdateMenu_updatesFields(t *testing.T) {
 	if err := config.DB.Create(&m).Error; err != nil {
 		t.Fatalf("create failed: %v", err)
 	}
-	if err := UpdateMenu(context.Background(), m.ID, map[string]interface{}{"title": "new", "order": 5}); err != nil {
+	if _, err := UpdateMenu(context.Background(), m.ID, map[string]interface{}{"title": "new", "order": 5}); err != nil {
 		t.Fatalf("UpdateMenu failed: %v", err)
 	}
 	var got models.Menu
 	if err := config.DB.First(&got, m.ID).Error; err != nil {
 		t.Fatalf("read back failed: %v", err)
 	}
-	if got.Title != "new" || got.Order != 5 {
+	// order is a position among the siblings: a lone item is already in place
+	if got.Title != "new" || got.Order != 2 {
 		t.Fatalf("unexpected row after update: %+v", got)
 	}
 }
 
+func TestUpdateMenu_parentAndOrderRenumberSiblings(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	a := models.Menu{Title: "A", Order: 0}
+	b := models.Menu{Title: "B", Order: 1}
+	c := models.Menu{Title: "C", Order: 2}
+	for _, m := range []*models.Menu{&a, &b, &c} {
+		require.NoError(t, config.DB.Create(m).Error)
+	}
+	x := models.Menu{Title: "X", ParentID: &a.ID, Order: 0}
+	require.NoError(t, config.DB.Create(&x).Error)
+
+	orders := func() map[string]int {
+		var all []models.Menu
+		require.NoError(t, config.DB.Find(&all).Error)
+		out := map[string]int{}
+		for _, m := range all {
+			out[m.Title] = m.Order
+		}
+		return out
+	}
+
+	// B moves under A in front of X: the roots close the gap, X shifts
+	require.NoError(t, updateErr(ctx, b.ID, map[string]interface{}{"parent_id": a.ID, "order": 0}))
+	require.Equal(t, map[string]int{"A": 0, "C": 1, "B": 0, "X": 1}, orders())
+
+	// an order alone reorders within the parent
+	require.NoError(t, updateErr(ctx, c.ID, map[string]interface{}{"order": 0}))
+	require.Equal(t, map[string]int{"C": 0, "A": 1, "B": 0, "X": 1}, orders())
+
+	require.ErrorIs(t, updateErr(ctx, a.ID, map[string]interface{}{"parent_id": b.ID}), ErrCycle)
+	require.ErrorIs(t, updateErr(ctx, a.ID, map[string]interface{}{"parent_id": uint(999)}), ErrParentNotFound)
+}
+
+func TestPatchMenu_appliesPatchToCurrentRow(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	m := models.Menu{Title: "old"}
+	require.NoError(t, config.DB.Create(&m).Error)
+
+	_, changed, err := PatchMenu(ctx, m.ID, func(cur *models.Menu) (map[string]interface{}, error) {
+		require.Equal(t, "old", cur.Title)
+		return map[string]interface{}{"title": " new "}, nil
+	})
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	_, changed, err = PatchMenu(ctx, m.ID, func(cur *models.Menu) (map[string]interface{}, error) {
+		require.Equal(t, "new", cur.Title, "the patch sees the committed, normalized row")
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	boom := fmt.Errorf("test failed")
+	_, _, err = PatchMenu(ctx, m.ID, func(*models.Menu) (map[string]interface{}, error) { return nil, boom })
+	require.ErrorIs(t, err, boom)
+
+	_, _, err = PatchMenu(ctx, m.ID, func(*models.Menu) (map[string]interface{}, error) {
+		return map[string]interface{}{"title": ""}, nil
+	})
+	require.ErrorIs(t, err, ErrValidation)
+
+	_, _, err = PatchMenu(ctx, 999, func(*models.Menu) (map[string]interface{}, error) { return nil, nil })
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	var got models.Menu
+	require.NoError(t, config.DB.First(&got, m.ID).Error)
+	require.Equal(t, "new", got.Title)
+}
+
 func TestDeleteMenuRecursive_deletesSubtree(t *testing.T) {
 	setupInMemoryDBForServicesTest(t)
 	defer config.CloseDB()
@@ -95,6 +169,18 @@ func TestDeleteMenuRecursive_deletesSubtree(t *testing.T) {
 	}
 }
 
+func TestDeleteMenuRecursive_missingItem(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	rev := CurrentRevision()
+
+	require.ErrorIs(t, DeleteMenuRecursive(context.Background(), 999), gorm.ErrRecordNotFound)
+	var n int64
+	require.NoError(t, config.DB.Model(&models.MenuChange{}).Count(&n).Error)
+	require.Zero(t, n, "no tombstone for an id that never existed")
+	require.Equal(t, rev, CurrentRevision(), "nothing is published")
+}
+
 func TestDeleteMenuRecursive_txRollback_onDeleteError_sqlmock(t *testing.T) {
 	// use sqlmock to force the DELETE to fail and assert the transaction is rolled back
 	sqlDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
@@ -110,7 +196,9 @@ func TestDeleteMenuRecursive_txRollback_onDeleteError_sqlmock(t *testing.T) {
 	config.DB = gdb
 
 	mock.ExpectBegin()
-	// the service will query children by parent_id (return empty rows here)
+	// the service loads the item first
+	mock.ExpectQuery("SELECT .*FROM .*menus.*id").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	// then queries children by parent_id (return empty rows here)
 	mock.ExpectQuery("SELECT .*FROM .*menus.*parent_id").WillReturnRows(sqlmock.NewRows([]string{"id", "title", "parent_id", "order"}))
 	// force the hard-delete (DELETE ... WHERE id IN) to fail
 	// Note: We use Unscoped().Delete() for hard delete, not soft delete UPDATE
@@ -146,7 +234,7 @@ func TestMoveMenu_cannotMoveIntoOwnDescendant(t *testing.T) {
 	var c models.Menu
 	config.DB.First(&c, "title = ?", "c")
 
-	err := MoveMenu(context.Background(), a.ID, &c.ID, nil)
+	_, err := MoveMenu(context.Background(), a.ID, &c.ID, nil)
 	if err == nil {
 		t.Fatalf("expected error when moving parent into descendant")
 	}
@@ -188,7 +276,7 @@ func TestMoveMenu_sameParent_noop(t *testing.T) {
 		t.Fatalf("could not find B in roots; roots=%+v", roots)
 	}
 
-	if err := MoveMenu(context.Background(), idB, nil, &curIdx); err != nil {
+	if _, err := MoveMenu(context.Background(), idB, nil, &curIdx); err != nil {
 		t.Fatalf("MoveMenu same-parent noop failed: %v", err)
 	}
 	// verify order unchanged
@@ -207,7 +295,7 @@ func TestMoveMenu_newParentNotFound_setsParent(t *testing.T) {
 		t.Fatalf("create loner: %v", err)
 	}
 	nonEx := uint(99999)
-	if err := MoveMenu(context.Background(), m.ID, &nonEx, nil); err != nil {
+	if _, err := MoveMenu(context.Background(), m.ID, &nonEx, nil); err != nil {
 		t.Fatalf("expected MoveMenu to allow non-existent parent (current behavior): %v", err)
 	}
 	var got models.Menu
@@ -233,7 +321,8 @@ func TestMoveMenu_insertIndexBounds(t *testing.T) {
 
 	// move C to newOrder -1 => should clamp to 0
 	neg := -1
-	require.NoError(t, MoveMenu(context.Background(), c.ID, nil, &neg))
+	_, err := MoveMenu(context.Background(), c.ID, nil, &neg)
+	require.NoError(t, err)
 	var roots []models.Menu
 	config.DB.Where("parent_id IS NULL").Order("\"order\" asc").Find(&roots)
 	require.Equal(t, 3, len(roots))
@@ -241,7 +330,8 @@ func TestMoveMenu_insertIndexBounds(t *testing.T) {
 
 	// move C to very large index -> append
 	big := 100
-	require.NoError(t, MoveMenu(context.Background(), c.ID, nil, &big))
+	_, err = MoveMenu(context.Background(), c.ID, nil, &big)
+	require.NoError(t, err)
 	config.DB.Where("parent_id IS NULL").Order("\"order\" asc").Find(&roots)
 	require.Equal(t, c.ID, roots[2].ID)
 }
@@ -264,7 +354,8 @@ func TestMoveMenu_betweenParents_compactSourceOrders(t *testing.T) {
 	var s1 models.Menu
 	config.DB.First(&s1, "title = ?", "s1")
 	idx := 1
-	require.NoError(t, MoveMenu(context.Background(), s1.ID, &p2.ID, &idx))
+	_, err := MoveMenu(context.Background(), s1.ID, &p2.ID, &idx)
+	require.NoError(t, err)
 
 	// verify source compacted (s0,s2) = orders 0,1
 	var src []models.Menu
@@ -346,7 +437,7 @@ func TestMoveMenu_txRollback_onExecError_sqlmock(t *testing.T) {
 	mock.ExpectExec("UPDATE .*menus.*").WillReturnError(fmt.Errorf("boom"))
 	mock.ExpectRollback()
 
-	err = MoveMenu(context.Background(), 1, nil, nil)
+	_, err = MoveMenu(context.Background(), 1, nil, nil)
 	if err == nil {
 		t.Fatalf("expected error from MoveMenu when UPDATE fails")
 	}
@@ -354,3 +445,69 @@ func TestMoveMenu_txRollback_onExecError_sqlmock(t *testing.T) {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
+
+func TestSetChildOrder_permutesSiblings(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	p := models.Menu{Title: "p"}
+	require.NoError(t, CreateMenu(ctx, &p))
+	var ids []uint
+	for i, title := range []string{"a", "b", "c"} {
+		m := models.Menu{Title: title, ParentID: &p.ID, Order: i}
+		require.NoError(t, CreateMenu(ctx, &m))
+		ids = append(ids, m.ID)
+	}
+
+	require.NoError(t, SetChildOrder(ctx, &p.ID, []uint{ids[2], ids[0], ids[1]}))
+	tree, err := GetMenuTree(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"c", "a", "b"}, titles(tree[0].Children))
+
+	// roots: only p
+	require.NoError(t, SetChildOrder(ctx, nil, []uint{p.ID}))
+
+	require.ErrorIs(t, SetChildOrder(ctx, &p.ID, []uint{ids[0], ids[1]}), ErrChildSetMismatch)
+	require.ErrorIs(t, SetChildOrder(ctx, &p.ID, []uint{ids[0], ids[1], p.ID}), ErrChildSetMismatch)
+	require.ErrorIs(t, SetChildOrder(ctx, &p.ID, []uint{ids[0], ids[0], ids[1]}), ErrDuplicateIDs)
+	require.ErrorIs(t, SetChildOrder(ctx, ptrUint(9999), nil), gorm.ErrRecordNotFound)
+}
+
+func TestMoveMenu_returnsSiblingOrders(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	// roots a, b, c; x, y under a
+	var roots []models.Menu
+	for _, title := range []string{"a", "b", "c"} {
+		m := models.Menu{Title: title}
+		require.NoError(t, CreateMenu(ctx, &m))
+		roots = append(roots, m)
+	}
+	a, b, c := roots[0], roots[1], roots[2]
+	x := models.Menu{Title: "x", ParentID: &a.ID}
+	require.NoError(t, CreateMenu(ctx, &x))
+	y := models.Menu{Title: "y", ParentID: &a.ID}
+	require.NoError(t, CreateMenu(ctx, &y))
+
+	// b leaves the root (c renumbered) for the slot between x and y
+	sibs, err := MoveMenu(ctx, b.ID, &a.ID, ptrInt(1))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []SiblingOrder{
+		{ID: c.ID, ParentID: nil, Order: 1},
+		{ID: x.ID, ParentID: &a.ID, Order: 0},
+		{ID: y.ID, ParentID: &a.ID, Order: 2},
+	}, sibs)
+
+	sibs, err = ReorderMenu(ctx, y.ID, 0)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []SiblingOrder{
+		{ID: x.ID, ParentID: &a.ID, Order: 1},
+		{ID: b.ID, ParentID: &a.ID, Order: 2},
+	}, sibs)
+
+	// no-op: nothing renumbered
+	sibs, err = ReorderMenu(ctx, y.ID, 0)
+	require.NoError(t, err)
+	require.Empty(t, sibs)
+}
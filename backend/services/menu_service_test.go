@@ -45,13 +45,19 @@ func setupInMemoryDBForServicesTest(t *testing.T) {
 		t.Fatalf("open sqlite failed: %v", err)
 	}
 	config.DB = db
-	if err := config.DB.AutoMigrate(&models.Menu{}); err != nil {
+	if err := config.DB.AutoMigrate(&models.Menu{}, &models.MenuChange{}); err != nil {
 		t.Fatalf("migrate failed: %v", err)
 	}
 }
 
 func ptrInt(v int) *int { return &v }
 
+// updateErr calls UpdateMenu and returns only its error.
+func updateErr(ctx context.Context, id uint, upd map[string]interface{}) error {
+	_, err := UpdateMenu(ctx, id, upd)
+	return err
+}
+
 func TestReorderMenu_service(t *testing.T) {
 	setupInMemoryDBForServicesTest(t)
 	// create three roots A(0), B(1), C(2)
@@ -69,7 +75,7 @@ func TestReorderMenu_service(t *testing.T) {
 	}
 
 	// move C to index 1 -> expected order A, C, B
-	if err := ReorderMenu(context.Background(), c.ID, 1); err != nil {
+	if _, err := ReorderMenu(context.Background(), c.ID, 1); err != nil {
 		t.Fatalf("ReorderMenu failed: %v", err)
 	}
 
@@ -97,7 +103,7 @@ func TestMoveMenu_service_between_parents(t *testing.T) {
 	config.DB.Create(&c)
 
 	// move B to be the first child of A
-	if err := MoveMenu(context.Background(), b.ID, &a.ID, ptrInt(0)); err != nil {
+	if _, err := MoveMenu(context.Background(), b.ID, &a.ID, ptrInt(0)); err != nil {
 		t.Fatalf("MoveMenu failed: %v", err)
 	}
 
@@ -127,7 +133,7 @@ func TestMoveMenu_service_between_parents(t *testing.T) {
 	}
 
 	// cannot move A under its descendant B (should error)
-	if err := MoveMenu(context.Background(), a.ID, &b.ID, ptrInt(0)); err == nil {
+	if _, err := MoveMenu(context.Background(), a.ID, &b.ID, ptrInt(0)); err == nil {
 		t.Fatalf("expected error when moving parent under descendant, got nil")
 	}
 }
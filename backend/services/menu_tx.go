@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/metrics"
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// maxTxAttempts bounds how often a transaction is re-run after a lock conflict.
+const maxTxAttempts = 3
+
+// MySQL error numbers worth retrying: the transaction was rolled back by the
+// server and re-running it from scratch is safe.
+const (
+	mysqlLockWaitTimeout = 1205
+	mysqlDeadlock        = 1213
+)
+
+// mysqlDuplicateEntry is MySQL's unique index violation.
+const mysqlDuplicateEntry = 1062
+
+func retryableTxError(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && (me.Number == mysqlDeadlock || me.Number == mysqlLockWaitTimeout)
+}
+
+// keyConflict turns a unique index violation (MySQL 1062, SQLite "UNIQUE
+// constraint failed") into ErrKeyTaken. menu_key is the only unique column
+// written, and checkKeyFree / models.UniqueKey only look before inserting: a
+// concurrent writer can still take the key first.
+func keyConflict(err error) error {
+	var me *mysql.MySQLError
+	if (errors.As(err, &me) && me.Number == mysqlDuplicateEntry) ||
+		(err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")) {
+		return ErrKeyTaken
+	}
+	return err
+}
+
+// runTx runs fn in a transaction, re-running it after deadlocks and lock wait
+// timeouts. fn must reset any state it accumulates, since it may run more
+// than once. Rollbacks and retries are counted per op in metrics.
+func runTx(ctx context.Context, op string, fn func(tx *gorm.DB) error) error {
+	for attempt := 1; ; attempt++ {
+		err := config.DB.WithContext(ctx).Transaction(fn)
+		if err == nil {
+			return nil
+		}
+		metrics.TxRollbacks.WithLabelValues(op).Inc()
+		if attempt >= maxTxAttempts || !retryableTxError(err) {
+			return err
+		}
+		metrics.TxRetries.WithLabelValues(op).Inc()
+		trace.SpanFromContext(ctx).AddEvent("transaction retry", trace.WithAttributes(
+			attribute.String("tx.op", op), attribute.Int("tx.attempt", attempt), attribute.String("error", err.Error())))
+		config.Logger(ctx).Warn("menu transaction conflict, retrying", "op", op, "attempt", attempt, "error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/metrics"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/galpt/sotekre/backend/tracing"
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+func TestRunTx_retriesLockConflictsAndCountsRollbacks(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	retries := testutil.ToFloat64(metrics.TxRetries.WithLabelValues("test"))
+	rollbacks := testutil.ToFloat64(metrics.TxRollbacks.WithLabelValues("test"))
+
+	calls := 0
+	err := runTx(ctx, "test", func(tx *gorm.DB) error {
+		calls++
+		if calls == 1 {
+			return &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+		}
+		return tx.Create(&models.Menu{Title: "A"}).Error
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, retries+1, testutil.ToFloat64(metrics.TxRetries.WithLabelValues("test")))
+	require.Equal(t, rollbacks+1, testutil.ToFloat64(metrics.TxRollbacks.WithLabelValues("test")))
+
+	// other errors roll back without a retry; conflicts give up after maxTxAttempts
+	calls = 0
+	boom := errors.New("boom")
+	require.ErrorIs(t, runTx(ctx, "test", func(*gorm.DB) error { calls++; return boom }), boom)
+	require.Equal(t, 1, calls)
+	calls = 0
+	err = runTx(ctx, "test", func(*gorm.DB) error { calls++; return &mysql.MySQLError{Number: 1205} })
+	require.Error(t, err)
+	require.Equal(t, maxTxAttempts, calls)
+	require.Equal(t, rollbacks+1+1+3, testutil.ToFloat64(metrics.TxRollbacks.WithLabelValues("test")))
+}
+
+func TestMenuTreeStats(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	s, err := MenuTreeStats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, metrics.TreeStats{}, s)
+
+	seedProducts(t) // Products -> [Phones -> [Android], Laptops], Support
+	before := TreeCacheStats()
+	hits := testutil.ToFloat64(metrics.TreeCacheLookups.WithLabelValues("hit"))
+	s, err = MenuTreeStats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, metrics.TreeStats{Items: 5, MaxDepth: 3, MaxFanOut: 2}, s)
+	_, err = MenuTreeStats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, TreeCacheStats(), "scrapes are not cache lookups")
+	require.Equal(t, hits, testutil.ToFloat64(metrics.TreeCacheLookups.WithLabelValues("hit")))
+}
+
+func TestMoveMenu_tracesServiceAndStatements(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	require.NoError(t, config.DB.Use(tracing.GormPlugin()))
+	products, support, _ := seedProducts(t)
+
+	ctx, root := tracing.Start(context.Background(), "test")
+	_, err := MoveMenu(ctx, support.ID, &products.ID, ptrInt(0))
+	require.NoError(t, err)
+	root.End()
+
+	var move sdktrace.ReadOnlySpan
+	updates := 0
+	for _, s := range rec.Ended() {
+		if s.Name() == "services.MoveMenu" {
+			move = s
+		}
+	}
+	require.NotNil(t, move)
+	rows := map[int64]bool{}
+	for _, s := range rec.Ended() {
+		if s.Name() == "UPDATE menus" && s.Parent().SpanID() == move.SpanContext().SpanID() {
+			updates++
+			for _, kv := range s.Attributes() {
+				if kv.Key == "menu.id" {
+					rows[kv.Value.AsInt64()] = true
+				}
+			}
+		}
+	}
+	require.GreaterOrEqual(t, updates, 3, "every sibling renumbering UPDATE is its own span")
+	require.Len(t, rows, updates, "each UPDATE span names the row it wrote")
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+)
+
+// Column limits from models.Menu (characters, as MySQL VARCHAR counts them).
+const (
+	MaxTitleLen = 255
+	MaxURLLen   = 1024
+	MaxIconLen  = 255
+)
+
+// defaultURLSchemes are the absolute URL schemes menu items may link to.
+// Override with MENU_URL_SCHEMES (comma-separated).
+const defaultURLSchemes = "http,https,mailto,tel"
+
+// ErrValidation matches every *ValidationError via errors.Is.
+var ErrValidation = errors.New("validation failed")
+
+// FieldError describes why one input field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of an input, not just the first.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return ErrValidation.Error() + ": " + strings.Join(parts, "; ")
+}
+
+// Is makes errors.Is(err, ErrValidation) true.
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+// Add records a field error.
+func (e *ValidationError) Add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns e when it holds field errors, nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func hasControl(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
+
+func allowedURLSchemes() map[string]bool {
+	out := map[string]bool{}
+	for _, s := range strings.Split(config.EnvOr("MENU_URL_SCHEMES", defaultURLSchemes), ",") {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			out[s] = true
+		}
+	}
+	return out
+}
+
+// normalizeTitle trims title and checks it is present and fits the column.
+func normalizeTitle(title string, v *ValidationError) string {
+	title = strings.TrimSpace(title)
+	switch {
+	case title == "":
+		v.Add("title", "is required")
+	case utf8.RuneCountInString(title) > MaxTitleLen:
+		v.Add("title", "must be at most %d characters", MaxTitleLen)
+	case hasControl(title):
+		v.Add("title", "must not contain control characters")
+	}
+	return title
+}
+
+// normalizeURL trims raw; empty means "no link" (nil). Relative references
+// ("/orders", "docs/faq", "#top") are accepted; absolute URLs must use an
+// allowed scheme and name a host where the scheme needs one. Protocol-relative
+// "//host" links are rejected since their scheme is not under our control.
+func normalizeURL(raw *string, v *ValidationError) *string {
+	if raw == nil {
+		return nil
+	}
+	s := strings.TrimSpace(*raw)
+	if s == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(s) > MaxURLLen {
+		v.Add("url", "must be at most %d characters", MaxURLLen)
+		return &s
+	}
+	u, err := url.Parse(s)
+	if err != nil || hasControl(s) {
+		v.Add("url", "is not a valid URL")
+		return &s
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch {
+	case scheme == "" && strings.HasPrefix(s, "//"):
+		v.Add("url", "protocol-relative URLs are not allowed")
+	case scheme == "":
+		// relative path, query or fragment
+	case !allowedURLSchemes()[scheme]:
+		v.Add("url", "scheme %q is not allowed", scheme)
+	case (scheme == "http" || scheme == "https") && u.Host == "":
+		v.Add("url", "must include a host")
+	}
+	return &s
+}
+
+// normalizeIcon trims raw; empty means "no icon" (nil).
+func normalizeIcon(raw *string, v *ValidationError) *string {
+	if raw == nil {
+		return nil
+	}
+	s := strings.TrimSpace(*raw)
+	switch {
+	case s == "":
+		return nil
+	case utf8.RuneCountInString(s) > MaxIconLen:
+		v.Add("icon", "must be at most %d characters", MaxIconLen)
+	case hasControl(s):
+		v.Add("icon", "must not contain control characters")
+	}
+	return &s
+}
+
+// normalizeKey trims key and checks its shape; empty means "generate one".
+func normalizeKey(key string, v *ValidationError) string {
+	key = strings.TrimSpace(key)
+	if key != "" && !models.ValidKey(key) {
+		v.Add("key", "must be at most %d lower-case letters, digits and single dashes", models.MaxKeyLen)
+	}
+	return key
+}
+
+// ValidateMenu normalizes m in place (trimmed title, url and icon; blank url
+// and icon become nil) and checks it. Create and duplicate both go through
+// it. The error is a *ValidationError listing every invalid field.
+func ValidateMenu(m *models.Menu) error {
+	v := &ValidationError{}
+	m.Title = normalizeTitle(m.Title, v)
+	m.Key = normalizeKey(m.Key, v)
+	m.URL = normalizeURL(m.URL, v)
+	m.Icon = normalizeIcon(m.Icon, v)
+	if m.Order < 0 {
+		v.Add("order", "must be >= 0")
+	}
+	if m.ParentID != nil && *m.ParentID == 0 {
+		v.Add("parent_id", "must be a menu id or null")
+	}
+	return v.Err()
+}
+
+// updateFields are the keys ValidateMenuUpdate accepts, in the order their
+// field errors are reported.
+var updateFields = []string{"title", "key", "url", "icon", "parent_id", "order"}
+
+// updateKeys returns the keys of upd in updateFields order, followed by any
+// unknown keys sorted, so field errors come out in a stable order.
+func updateKeys(upd map[string]interface{}) []string {
+	keys := make([]string, 0, len(upd))
+	known := make(map[string]bool, len(updateFields))
+	for _, k := range updateFields {
+		known[k] = true
+		if _, ok := upd[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	var unknown []string
+	for k := range upd {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return append(keys, unknown...)
+}
+
+// ValidateMenuUpdate checks and normalizes a partial update. Accepted keys and
+// value types: title and key (string), url and icon (*string or string; nil/blank
+// clears), parent_id (*uint or uint; nil moves to the root) and order (int >= 0).
+// Unknown keys and wrong types are field errors.
+func ValidateMenuUpdate(upd map[string]interface{}) (map[string]interface{}, error) {
+	v := &ValidationError{}
+	out := make(map[string]interface{}, len(upd))
+	optString := func(field string, val interface{}) (*string, bool) {
+		switch s := val.(type) {
+		case nil:
+			return nil, true
+		case string:
+			return &s, true
+		case *string:
+			return s, true
+		}
+		v.Add(field, "must be a string or null")
+		return nil, false
+	}
+	for _, k := range updateKeys(upd) {
+		val := upd[k]
+		switch k {
+		case "title":
+			s, ok := val.(string)
+			if !ok {
+				v.Add(k, "must be a string")
+				continue
+			}
+			out[k] = normalizeTitle(s, v)
+		case "key":
+			s, ok := val.(string)
+			if !ok {
+				v.Add(k, "must be a string")
+				continue
+			}
+			if s = normalizeKey(s, v); s == "" {
+				v.Add(k, "cannot be empty")
+			}
+			out[k] = s
+		case "url":
+			if s, ok := optString(k, val); ok {
+				out[k] = normalizeURL(s, v)
+			}
+		case "icon":
+			if s, ok := optString(k, val); ok {
+				out[k] = normalizeIcon(s, v)
+			}
+		case "parent_id":
+			switch p := val.(type) {
+			case nil:
+				out[k] = nil
+			case *uint:
+				if p != nil && *p == 0 {
+					v.Add(k, "must be a menu id or null")
+				}
+				out[k] = p
+			case uint:
+				if p == 0 {
+					v.Add(k, "must be a menu id or null")
+				}
+				out[k] = p
+			default:
+				v.Add(k, "must be a menu id or null")
+			}
+		case "order":
+			n, ok := val.(int)
+			if !ok {
+				v.Add(k, "must be an integer")
+			} else if n < 0 {
+				v.Add(k, "must be >= 0")
+			}
+			out[k] = n
+		default:
+			v.Add(k, "is not an updatable field")
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/stretchr/testify/require"
+)
+
+func strp(s string) *string { return &s }
+
+func fieldNames(err error) []string {
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		return nil
+	}
+	out := make([]string, 0, len(ve.Fields))
+	for _, f := range ve.Fields {
+		out = append(out, f.Field)
+	}
+	return out
+}
+
+func TestValidateMenu_trimsAndChecksURLs(t *testing.T) {
+	m := &models.Menu{Title: "  Orders \t", URL: strp(" /orders/1 "), Icon: strp("  ")}
+	require.NoError(t, ValidateMenu(m))
+	require.Equal(t, "Orders", m.Title)
+	require.Equal(t, "/orders/1", *m.URL)
+	require.Nil(t, m.Icon, "blank icon is cleared")
+
+	for _, ok := range []string{"docs/faq", "#top", "?tab=2", "https://example.com/x", "mailto:a@b.c", "tel:+1555"} {
+		require.NoError(t, ValidateMenu(&models.Menu{Title: "x", URL: strp(ok)}), ok)
+	}
+	for _, bad := range []string{"javascript:alert(1)", " JavaScript:alert(1)", "data:text/html,x", "//evil.example", "https:///nohost", "/" + strings.Repeat("a", MaxURLLen)} {
+		err := ValidateMenu(&models.Menu{Title: "x", URL: strp(bad)})
+		require.ErrorIs(t, err, ErrValidation, bad)
+		require.Equal(t, []string{"url"}, fieldNames(err), bad)
+	}
+
+	t.Setenv("MENU_URL_SCHEMES", "https")
+	require.Error(t, ValidateMenu(&models.Menu{Title: "x", URL: strp("mailto:a@b.c")}))
+}
+
+func TestValidateMenu_reportsEveryField(t *testing.T) {
+	err := ValidateMenu(&models.Menu{
+		Title: strings.Repeat("é", MaxTitleLen+1),
+		Icon:  strp(strings.Repeat("i", MaxIconLen+1)),
+		Order: -1,
+	})
+	require.ErrorIs(t, err, ErrValidation)
+	require.Equal(t, []string{"title", "icon", "order"}, fieldNames(err))
+
+	// limits count characters, not bytes
+	require.NoError(t, ValidateMenu(&models.Menu{Title: strings.Repeat("é", MaxTitleLen)}))
+	require.Equal(t, []string{"title"}, fieldNames(ValidateMenu(&models.Menu{Title: " \n "})))
+}
+
+func TestValidateMenuUpdate_rejectsWrongTypes(t *testing.T) {
+	_, err := ValidateMenuUpdate(map[string]interface{}{"order": "5"})
+	require.Equal(t, []string{"order"}, fieldNames(err))
+	_, err = ValidateMenuUpdate(map[string]interface{}{"parent_id": 3.0})
+	require.Equal(t, []string{"parent_id"}, fieldNames(err))
+	_, err = ValidateMenuUpdate(map[string]interface{}{"created_at": "x"})
+	require.Equal(t, []string{"created_at"}, fieldNames(err))
+
+	// field errors come out in a fixed order, unknown keys last and sorted
+	for i := 0; i < 20; i++ {
+		_, err = ValidateMenuUpdate(map[string]interface{}{"zeta": 1, "order": "x", "title": 1, "alpha": 2, "url": 3, "key": "Not A Slug"})
+		require.Equal(t, []string{"title", "key", "url", "order", "alpha", "zeta"}, fieldNames(err))
+	}
+
+	upd, err := ValidateMenuUpdate(map[string]interface{}{"title": " T ", "url": "", "parent_id": nil, "order": 2})
+	require.NoError(t, err)
+	require.Equal(t, "T", upd["title"])
+	require.Nil(t, upd["url"])
+	require.Contains(t, upd, "parent_id")
+}
+
+func TestCreateAndUpdateMenu_validate(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	require.ErrorIs(t, CreateMenu(ctx, &models.Menu{Title: "x", URL: strp("javascript:void(0)")}), ErrValidation)
+	require.ErrorIs(t, CreateMenu(ctx, &models.Menu{Title: "x", ParentID: ptrUint(999)}), ErrParentNotFound)
+	m := &models.Menu{Title: " Home ", URL: strp(" / ")}
+	require.NoError(t, CreateMenu(ctx, m))
+	require.Equal(t, "Home", m.Title)
+
+	require.ErrorIs(t, updateErr(ctx, m.ID, map[string]interface{}{"url": "javascript:x"}), ErrValidation)
+	require.NoError(t, updateErr(ctx, m.ID, map[string]interface{}{"title": "  Start  "}))
+	var got models.Menu
+	require.NoError(t, config.DB.First(&got, m.ID).Error)
+	require.Equal(t, "Start", got.Title)
+	require.Equal(t, "/", *got.URL)
+
+	_, err := DuplicateMenu(ctx, m.ID, DuplicateOptions{TitleSuffix: strings.Repeat("!", MaxTitleLen)})
+	require.ErrorIs(t, err, ErrValidation)
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/galpt/sotekre/backend/problem"
+	"gorm.io/gorm"
+)
+
+// ErrorProblem maps a menu service error to the problem every API reports
+// (REST, GraphQL and gRPC), so they all use the same codes. ok is false for
+// unexpected errors, which must not be shown to clients.
+func ErrorProblem(err error) (p problem.Problem, ok bool) {
+	var ve *ValidationError
+	switch {
+	case errors.As(err, &ve):
+		return ValidationProblem(ve.Fields), true
+	case errors.Is(err, ErrInvalidMenuID):
+		return problem.New(http.StatusBadRequest, problem.CodeInvalidID, err.Error()), true
+	case errors.Is(err, ErrDuplicateIDs):
+		return ValidationProblem([]FieldError{{Field: "ids", Message: err.Error()}}), true
+	case errors.Is(err, ErrInvalidQuery):
+		return problem.New(http.StatusBadRequest, problem.CodeInvalidParameter, err.Error()), true
+	case errors.Is(err, ErrCycle):
+		return problem.New(http.StatusConflict, problem.CodeCycleDetected, err.Error()), true
+	case errors.Is(err, ErrKeyTaken):
+		return problem.New(http.StatusConflict, problem.CodeKeyConflict, err.Error()), true
+	case errors.Is(err, ErrChildSetMismatch):
+		return problem.New(http.StatusConflict, problem.CodeChildSetMismatch, err.Error()), true
+	case errors.Is(err, ErrParentNotFound):
+		return problem.New(http.StatusNotFound, problem.CodeParentNotFound, "destination parent does not exist"), true
+	case errors.Is(err, ErrNoMenuMatch):
+		return problem.New(http.StatusNotFound, problem.CodeMenuNotFound, err.Error()), true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return problem.New(http.StatusNotFound, problem.CodeMenuNotFound, "menu item does not exist"), true
+	}
+	return problem.Problem{}, false
+}
+
+// ValidationProblem is the validation_failed problem for fields.
+func ValidationProblem(fields []FieldError) problem.Problem {
+	out := make([]problem.FieldError, len(fields))
+	for i, f := range fields {
+		out[i] = problem.FieldError{Field: f.Field, Message: f.Message}
+	}
+	return problem.Validation(out)
+}
@@ -0,0 +1,494 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"gorm.io/gorm"
+)
+
+// Headers sent with every webhook POST. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, body)).
+const (
+	WebhookSignatureHeader = "X-Sotekre-Signature"
+	WebhookEventHeader     = "X-Sotekre-Event"
+	WebhookDeliveryHeader  = "X-Sotekre-Delivery"
+)
+
+// ErrInvalidWebhook is returned for subscriptions that fail validation.
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// WebhookOptions tunes the dispatcher. Zero values fall back to the env
+// defaults in WebhookOptionsFromEnv.
+type WebhookOptions struct {
+	// Client sends the deliveries. The default refuses loopback, private and
+	// link-local addresses at dial time; a custom client is trusted as is.
+	Client       *http.Client
+	MaxAttempts  int
+	BaseBackoff  time.Duration // delay after the first failure, doubled per attempt
+	MaxBackoff   time.Duration
+	PollInterval time.Duration // how often due retries are picked up
+	// Concurrency is how many subscriptions are delivered to in parallel;
+	// each subscription gets its deliveries one at a time, in order.
+	Concurrency int
+}
+
+// WebhookOptionsFromEnv reads WEBHOOK_TIMEOUT_MS, WEBHOOK_MAX_ATTEMPTS,
+// WEBHOOK_BACKOFF_MS, WEBHOOK_MAX_BACKOFF_MS, WEBHOOK_POLL_MS and
+// WEBHOOK_CONCURRENCY.
+func WebhookOptionsFromEnv() WebhookOptions {
+	return WebhookOptions{
+		Client:       newWebhookClient(config.EnvDurationMSOr("WEBHOOK_TIMEOUT_MS", 10*time.Second)),
+		MaxAttempts:  config.EnvIntOr("WEBHOOK_MAX_ATTEMPTS", 6),
+		BaseBackoff:  config.EnvDurationMSOr("WEBHOOK_BACKOFF_MS", time.Second),
+		MaxBackoff:   config.EnvDurationMSOr("WEBHOOK_MAX_BACKOFF_MS", time.Hour),
+		PollInterval: config.EnvDurationMSOr("WEBHOOK_POLL_MS", time.Second),
+		Concurrency:  config.EnvIntOr("WEBHOOK_CONCURRENCY", 8),
+	}
+}
+
+type webhookDispatcher struct {
+	opts WebhookOptions
+	wake chan struct{}
+	// claimTTL is how long a claim protects a delivery; it outlives one
+	// attempt, so only claims of a crashed replica expire.
+	claimTTL time.Duration
+
+	mu      sync.Mutex
+	busy    map[uint]bool // subscriptions with a running worker
+	workers sync.WaitGroup
+}
+
+// activeWebhooks is the running dispatcher; nil means mutations enqueue nothing
+// (unit tests, tools that import services without starting the server).
+var activeWebhooks atomic.Pointer[webhookDispatcher]
+
+// StartWebhookDispatcher starts the background delivery worker. From then on
+// every committed mutation enqueues one delivery per matching subscription.
+// stop blocks until the worker has exited.
+func StartWebhookDispatcher(opts WebhookOptions) (stop func()) {
+	def := WebhookOptionsFromEnv()
+	if opts.Client == nil {
+		opts.Client = def.Client
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = def.MaxAttempts
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = def.BaseBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = def.MaxBackoff
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = def.PollInterval
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = max(def.Concurrency, 1)
+	}
+
+	d := &webhookDispatcher{opts: opts, wake: make(chan struct{}, 1), busy: map[uint]bool{}}
+	d.claimTTL = 5 * time.Minute
+	if t := opts.Client.Timeout; t > 0 {
+		d.claimTTL = t + time.Minute
+	}
+	activeWebhooks.Store(d)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.run(ctx)
+	}()
+	return func() {
+		activeWebhooks.CompareAndSwap(d, nil)
+		cancel()
+		<-done
+	}
+}
+
+func (d *webhookDispatcher) run(ctx context.Context) {
+	t := time.NewTicker(d.opts.PollInterval)
+	defer t.Stop()
+	defer d.workers.Wait()
+	for {
+		d.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *webhookDispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// notifyWebhooks wakes the running dispatcher, if any, so deliveries a
+// mutation queued are sent without waiting for the next poll.
+func notifyWebhooks() {
+	if d := activeWebhooks.Load(); d != nil {
+		d.notify()
+	}
+}
+
+// enqueueDeliveries queues ev for every active subscription that wants it.
+// changeLog.record calls it inside the mutation's transaction.
+func enqueueDeliveries(db *gorm.DB, ev ChangeEvent) error {
+	var subs []models.WebhookSubscription
+	if err := db.Where("active = ?", true).Find(&subs).Error; err != nil {
+		return err
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, s := range subs {
+		if !s.Wants(string(ev.Type)) {
+			continue
+		}
+		del := models.WebhookDelivery{
+			SubscriptionID: s.ID,
+			EventType:      string(ev.Type),
+			Revision:       ev.Revision,
+			Payload:        string(payload),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  &now,
+		}
+		if err := db.Create(&del).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dueDeliveries narrows q to pending deliveries that are due and not claimed
+// by a live dispatcher.
+func (d *webhookDispatcher) dueDeliveries(q *gorm.DB, now time.Time) *gorm.DB {
+	return q.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Where("claimed_at IS NULL OR claimed_at < ?", now.Add(-d.claimTTL))
+}
+
+// deliverDue starts a worker for every subscription with due deliveries that
+// does not have one yet, up to Concurrency workers, so a slow receiver only
+// delays its own deliveries.
+func (d *webhookDispatcher) deliverDue(ctx context.Context) {
+	d.mu.Lock()
+	busy := make([]uint, 0, len(d.busy))
+	for id := range d.busy {
+		busy = append(busy, id)
+	}
+	free := d.opts.Concurrency - len(busy)
+	d.mu.Unlock()
+	if free <= 0 {
+		return
+	}
+
+	q := d.dueDeliveries(config.DB.WithContext(ctx).Model(&models.WebhookDelivery{}), time.Now())
+	if len(busy) > 0 {
+		q = q.Where("subscription_id NOT IN ?", busy)
+	}
+	var subIDs []uint
+	if err := q.Distinct("subscription_id").Limit(free).Pluck("subscription_id", &subIDs).Error; err != nil {
+		if ctx.Err() == nil {
+			config.Logger(ctx).Error("webhooks: load due deliveries failed", "error", err)
+		}
+		return
+	}
+	for _, id := range subIDs {
+		d.mu.Lock()
+		if d.busy[id] {
+			d.mu.Unlock()
+			continue
+		}
+		d.busy[id] = true
+		d.mu.Unlock()
+		d.workers.Add(1)
+		go d.work(ctx, id)
+	}
+}
+
+// work sends the due deliveries of one subscription, oldest first, until none
+// is left.
+func (d *webhookDispatcher) work(ctx context.Context, subID uint) {
+	defer d.workers.Done()
+	defer func() {
+		d.mu.Lock()
+		delete(d.busy, subID)
+		d.mu.Unlock()
+		d.notify() // other subscriptions may have waited for a free worker
+	}()
+	for ctx.Err() == nil {
+		del, err := d.claimNext(ctx, subID)
+		if err != nil {
+			if ctx.Err() == nil {
+				config.Logger(ctx).Error("webhooks: claim delivery failed", "subscription_id", subID, "error", err)
+			}
+			return
+		}
+		if del == nil {
+			return
+		}
+		d.attempt(ctx, del)
+	}
+}
+
+// claimNext claims the oldest due delivery of subID and returns it, or nil
+// when there is none. The conditional UPDATE makes the claim exclusive across
+// replicas: only one of them sees a row affected.
+func (d *webhookDispatcher) claimNext(ctx context.Context, subID uint) (*models.WebhookDelivery, error) {
+	for {
+		now := time.Now()
+		var del models.WebhookDelivery
+		err := d.dueDeliveries(config.DB.WithContext(ctx), now).Where("subscription_id = ?", subID).
+			Order("id asc").First(&del).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		res := d.dueDeliveries(config.DB.WithContext(ctx).Model(&models.WebhookDelivery{}), now).
+			Where("id = ?", del.ID).Update("claimed_at", now)
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		if res.RowsAffected == 1 {
+			del.ClaimedAt = &now
+			return &del, nil
+		}
+		// another dispatcher claimed it first; try the next one
+	}
+}
+
+func (d *webhookDispatcher) attempt(ctx context.Context, del *models.WebhookDelivery) {
+	var sub models.WebhookSubscription
+	err := config.DB.First(&sub, del.SubscriptionID).Error
+	var code int
+	if err == nil {
+		code, err = d.post(ctx, &sub, del)
+	}
+	if ctx.Err() != nil {
+		// shutting down: release the claim and leave the delivery pending
+		if err := config.DB.Model(del).Update("claimed_at", nil).Error; err != nil {
+			config.Logger(ctx).Error("webhooks: release delivery failed", "delivery_id", del.ID, "error", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	del.ClaimedAt = nil
+	del.Attempts++
+	del.LastStatusCode = code
+	if err == nil {
+		del.Status = models.DeliverySucceeded
+		del.DeliveredAt = &now
+		del.NextAttemptAt = nil
+		del.LastError = ""
+	} else {
+		del.LastError = truncate(err.Error(), 1024)
+		if del.Attempts >= d.opts.MaxAttempts || errors.Is(err, gorm.ErrRecordNotFound) {
+			del.Status = models.DeliveryFailed
+			del.NextAttemptAt = nil
+		} else {
+			next := now.Add(webhookBackoff(d.opts.BaseBackoff, d.opts.MaxBackoff, del.Attempts))
+			del.NextAttemptAt = &next
+		}
+	}
+	if err := config.DB.Save(del).Error; err != nil {
+		config.Logger(ctx).Error("webhooks: save delivery failed", "delivery_id", del.ID, "error", err)
+	}
+}
+
+// webhookBackoff returns base * 2^(attempts-1), capped at max.
+func webhookBackoff(base, max time.Duration, attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := base
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= max || d <= 0 {
+			return max
+		}
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
+func (d *webhookDispatcher) post(ctx context.Context, sub *models.WebhookSubscription, del *models.WebhookDelivery) (int, error) {
+	body := []byte(del.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, strings.NewReader(del.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sotekre-webhooks/1")
+	req.Header.Set(WebhookEventHeader, del.EventType)
+	req.Header.Set(WebhookDeliveryHeader, fmt.Sprint(del.ID))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(sub.Secret, body))
+
+	res, err := d.opts.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver responded %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// SignWebhookPayload returns the signature header value for body.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a received signature header in constant time.
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, body)), []byte(signature))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// --- subscription management ---
+
+var webhookEventTypes = map[string]bool{
+	string(EventCreated): true, string(EventUpdated): true, string(EventMoved): true,
+	string(EventReordered): true, string(EventDeleted): true,
+}
+
+func validateWebhook(ctx context.Context, s *models.WebhookSubscription) error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	if err := checkWebhookHost(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf("%w: url must point at a public address (%v)", ErrInvalidWebhook, err)
+	}
+	if s.Secret == "" {
+		return fmt.Errorf("%w: secret is required", ErrInvalidWebhook)
+	}
+	var events []string
+	for _, e := range strings.Split(s.Events, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !webhookEventTypes[e] {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, e)
+		}
+		events = append(events, e)
+	}
+	s.Events = strings.Join(events, ",")
+	return nil
+}
+
+// CreateWebhook stores a new subscription.
+func CreateWebhook(ctx context.Context, s *models.WebhookSubscription) error {
+	if err := validateWebhook(ctx, s); err != nil {
+		return err
+	}
+	return config.DB.WithContext(ctx).Create(s).Error
+}
+
+// ListWebhooks returns all subscriptions.
+func ListWebhooks(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	if err := config.DB.WithContext(ctx).Order("id asc").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// DeleteWebhook removes a subscription and its delivery log.
+func DeleteWebhook(ctx context.Context, id uint) error {
+	return config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.WebhookSubscription{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error
+	})
+}
+
+// ListWebhookDeliveries returns the newest deliveries of a subscription.
+func ListWebhookDeliveries(ctx context.Context, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	var sub models.WebhookSubscription
+	if err := config.DB.WithContext(ctx).First(&sub, subscriptionID).Error; err != nil {
+		return nil, err
+	}
+	var dels []models.WebhookDelivery
+	if err := config.DB.WithContext(ctx).Where("subscription_id = ?", subscriptionID).Order("id desc").Limit(limit).Find(&dels).Error; err != nil {
+		return nil, err
+	}
+	return dels, nil
+}
+
+// RedeliverWebhook queues a fresh copy of a past delivery (same payload and
+// subscription, attempts reset). The original row is kept in the log.
+func RedeliverWebhook(ctx context.Context, deliveryID uint) (*models.WebhookDelivery, error) {
+	var orig models.WebhookDelivery
+	if err := config.DB.WithContext(ctx).First(&orig, deliveryID).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	del := models.WebhookDelivery{
+		SubscriptionID: orig.SubscriptionID,
+		EventType:      orig.EventType,
+		Revision:       orig.Revision,
+		Payload:        orig.Payload,
+		Status:         models.DeliveryPending,
+		NextAttemptAt:  &now,
+		RedeliveryOf:   &orig.ID,
+	}
+	if err := config.DB.WithContext(ctx).Create(&del).Error; err != nil {
+		return nil, err
+	}
+	notifyWebhooks()
+	return &del, nil
+}
+
+// Test hooks — allow handlers to stub behavior in tests.
+var (
+	CreateWebhookFn         = CreateWebhook
+	ListWebhooksFn          = ListWebhooks
+	DeleteWebhookFn         = DeleteWebhook
+	ListWebhookDeliveriesFn = ListWebhookDeliveries
+	RedeliverWebhookFn      = RedeliverWebhook
+)
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+	"github.com/galpt/sotekre/backend/models"
+	"github.com/stretchr/testify/require"
+)
+
+// setupWebhookDB also allows private targets: the receivers are httptest
+// servers on 127.0.0.1. One connection serializes the concurrent workers'
+// writes, which shared-cache sqlite would otherwise fail with SQLITE_LOCKED.
+func setupWebhookDB(t *testing.T) {
+	setupInMemoryDBForServicesTest(t)
+	require.NoError(t, config.DB.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{}))
+	sqlDB, err := config.DB.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	old := SetAllowPrivateWebhookTargets(true)
+	t.Cleanup(func() { SetAllowPrivateWebhookTargets(old) })
+}
+
+// receiver records deliveries and fails the first `failFirst` requests.
+type receiver struct {
+	mu        sync.Mutex
+	failFirst int
+	calls     int
+	bodies    [][]byte
+	headers   []http.Header
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.calls++
+	if rc.calls <= rc.failFirst {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	rc.bodies = append(rc.bodies, body)
+	rc.headers = append(rc.headers, r.Header.Clone())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func waitForDelivery(t *testing.T, id uint, status string) models.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		var d models.WebhookDelivery
+		require.NoError(t, config.DB.First(&d, id).Error)
+		if d.Status == status {
+			return d
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivery %d stuck in %q (want %q): %+v", id, d.Status, status, d)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func fastWebhookOptions() WebhookOptions {
+	return WebhookOptions{
+		MaxAttempts:  3,
+		BaseBackoff:  5 * time.Millisecond,
+		MaxBackoff:   20 * time.Millisecond,
+		PollInterval: 5 * time.Millisecond,
+	}
+}
+
+func TestWebhooks_signedDeliveryWithRetry(t *testing.T) {
+	setupWebhookDB(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	rc := &receiver{failFirst: 1}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	sub := models.WebhookSubscription{URL: srv.URL, Secret: "s3cret", Active: true}
+	require.NoError(t, CreateWebhook(ctx, &sub))
+
+	stop := StartWebhookDispatcher(fastWebhookOptions())
+	defer stop()
+
+	m := models.Menu{Title: "Products"}
+	require.NoError(t, CreateMenu(ctx, &m))
+
+	dels, err := ListWebhookDeliveries(ctx, sub.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, dels, 1)
+	d := waitForDelivery(t, dels[0].ID, models.DeliverySucceeded)
+	require.Equal(t, 2, d.Attempts, "first attempt fails, the retry succeeds")
+	require.Equal(t, http.StatusNoContent, d.LastStatusCode)
+	require.NotNil(t, d.DeliveredAt)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	require.Len(t, rc.bodies, 1)
+	require.Equal(t, "created", rc.headers[0].Get(WebhookEventHeader))
+	require.True(t, VerifyWebhookSignature("s3cret", rc.bodies[0], rc.headers[0].Get(WebhookSignatureHeader)))
+	require.False(t, VerifyWebhookSignature("wrong", rc.bodies[0], rc.headers[0].Get(WebhookSignatureHeader)))
+}
+
+func TestWebhooks_failsAfterMaxAttemptsAndRedeliver(t *testing.T) {
+	setupWebhookDB(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	rc := &receiver{failFirst: 3}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	sub := models.WebhookSubscription{URL: srv.URL, Secret: "k", Events: "created", Active: true}
+	require.NoError(t, CreateWebhook(ctx, &sub))
+	stop := StartWebhookDispatcher(fastWebhookOptions())
+	defer stop()
+
+	m := models.Menu{Title: "A"}
+	require.NoError(t, CreateMenu(ctx, &m))
+	// filtered out: the subscription only wants "created"
+	require.NoError(t, updateErr(ctx, m.ID, map[string]interface{}{"title": "B"}))
+
+	dels, err := ListWebhookDeliveries(ctx, sub.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, dels, 1)
+	failed := waitForDelivery(t, dels[0].ID, models.DeliveryFailed)
+	require.Equal(t, 3, failed.Attempts)
+	require.Equal(t, http.StatusServiceUnavailable, failed.LastStatusCode)
+
+	again, err := RedeliverWebhook(ctx, failed.ID)
+	require.NoError(t, err)
+	require.Equal(t, failed.ID, *again.RedeliveryOf)
+	waitForDelivery(t, again.ID, models.DeliverySucceeded)
+
+	_, err = RedeliverWebhook(ctx, 9999)
+	require.Error(t, err)
+}
+
+func TestWebhooks_enqueuedInMutationTransaction(t *testing.T) {
+	setupWebhookDB(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	sub := models.WebhookSubscription{URL: "http://example.invalid/hook", Secret: "k", Active: true}
+	require.NoError(t, CreateWebhook(ctx, &sub))
+	// a dispatcher that never polls: the rows are only inspected
+	stop := StartWebhookDispatcher(WebhookOptions{PollInterval: time.Hour})
+	defer stop()
+
+	m := models.Menu{Title: "A"}
+	require.NoError(t, CreateMenu(ctx, &m))
+	var del models.WebhookDelivery
+	require.NoError(t, config.DB.First(&del).Error)
+	var change models.MenuChange
+	require.NoError(t, config.DB.Last(&change).Error)
+	require.Equal(t, change.Seq, del.Revision, "the revision is the change log sequence")
+
+	// a delivery that cannot be queued fails the mutation instead of being lost
+	require.NoError(t, config.DB.Migrator().DropTable(&models.WebhookDelivery{}))
+	require.Error(t, CreateMenu(ctx, &models.Menu{Title: "B"}))
+	var n int64
+	require.NoError(t, config.DB.Model(&models.Menu{}).Count(&n).Error)
+	require.EqualValues(t, 1, n, "the mutation rolled back")
+}
+
+func TestWebhooks_noDispatcher_enqueuesNothing(t *testing.T) {
+	setupWebhookDB(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	sub := models.WebhookSubscription{URL: "http://example.invalid/hook", Secret: "k", Active: true}
+	require.NoError(t, CreateWebhook(ctx, &sub))
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "A"}))
+	var n int64
+	config.DB.Model(&models.WebhookDelivery{}).Count(&n)
+	require.Zero(t, n)
+}
+
+func TestWebhooks_inactive_enqueuesNothing(t *testing.T) {
+	setupWebhookDB(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	sub := models.WebhookSubscription{URL: "http://example.invalid/hook", Secret: "k", Active: false}
+	require.NoError(t, CreateWebhook(ctx, &sub))
+	var stored models.WebhookSubscription
+	require.NoError(t, config.DB.First(&stored, sub.ID).Error)
+	require.False(t, stored.Active)
+
+	stop := StartWebhookDispatcher(fastWebhookOptions())
+	defer stop()
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "A"}))
+	var n int64
+	config.DB.Model(&models.WebhookDelivery{}).Count(&n)
+	require.Zero(t, n)
+}
+
+func TestCreateWebhook_validation(t *testing.T) {
+	ctx := context.Background()
+	for _, s := range []models.WebhookSubscription{
+		{URL: "ftp://x", Secret: "k"},
+		{URL: "/relative", Secret: "k"},
+		{URL: "https://x.example", Secret: ""},
+		{URL: "https://x.example", Secret: "k", Events: "created,exploded"},
+		{URL: "http://127.0.0.1:8080/hook", Secret: "k"},
+		{URL: "http://localhost/hook", Secret: "k"},
+		{URL: "http://169.254.169.254/latest/meta-data", Secret: "k"},
+		{URL: "http://10.1.2.3/hook", Secret: "k"},
+		{URL: "http://[::1]/hook", Secret: "k"},
+		{URL: "http://[::ffff:192.168.0.1]/hook", Secret: "k"},
+	} {
+		err := CreateWebhook(ctx, &s)
+		require.True(t, errors.Is(err, ErrInvalidWebhook), "%+v: %v", s, err)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	require.Equal(t, time.Second, webhookBackoff(time.Second, time.Minute, 1))
+	require.Equal(t, 4*time.Second, webhookBackoff(time.Second, time.Minute, 3))
+	require.Equal(t, time.Minute, webhookBackoff(time.Second, time.Minute, 40))
+}
+
+func TestForbiddenWebhookIP(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1": true, "10.0.0.1": true, "172.16.5.4": true, "192.168.1.1": true,
+		"169.254.169.254": true, "0.0.0.0": true, "100.64.0.1": true, "224.0.0.1": true,
+		"::1": true, "fe80::1": true, "fd00::1": true, "::ffff:127.0.0.1": true,
+		"93.184.216.34": false, "2606:2800:220:1::1": false,
+	} {
+		require.Equal(t, want, forbiddenWebhookIP(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+// TestWebhooks_dialRefusesPrivateAddress covers a target that became private
+// after it was registered (e.g. DNS rebinding): the dialer refuses it.
+func TestWebhooks_dialRefusesPrivateAddress(t *testing.T) {
+	setupWebhookDB(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	sub := models.WebhookSubscription{URL: srv.URL, Secret: "k", Active: true}
+	require.NoError(t, CreateWebhook(ctx, &sub))
+
+	SetAllowPrivateWebhookTargets(false)
+	stop := StartWebhookDispatcher(fastWebhookOptions())
+	defer stop()
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "A"}))
+
+	dels, err := ListWebhookDeliveries(ctx, sub.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, dels, 1)
+	failed := waitForDelivery(t, dels[0].ID, models.DeliveryFailed)
+	require.Contains(t, failed.LastError, ErrWebhookTargetForbidden.Error())
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	require.Zero(t, rc.calls)
+}
+
+// TestWebhooks_claimsAcrossDispatchers runs two dispatchers, as two replicas
+// would: every delivery is sent exactly once.
+func TestWebhooks_claimsAcrossDispatchers(t *testing.T) {
+	setupWebhookDB(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	seen := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Header.Get(WebhookDeliveryHeader)]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, CreateWebhook(ctx, &models.WebhookSubscription{URL: srv.URL, Secret: "k", Active: true}))
+	}
+
+	stop1 := StartWebhookDispatcher(fastWebhookOptions())
+	defer stop1()
+	stop2 := StartWebhookDispatcher(fastWebhookOptions())
+	defer stop2()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, CreateMenu(ctx, &models.Menu{Title: fmt.Sprint("M", i)}))
+	}
+
+	var dels []models.WebhookDelivery
+	require.NoError(t, config.DB.Find(&dels).Error)
+	require.Len(t, dels, 15)
+	for _, d := range dels {
+		waitForDelivery(t, d.ID, models.DeliverySucceeded)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, seen, 15)
+	for id, n := range seen {
+		require.Equal(t, 1, n, "delivery %s", id)
+	}
+}
+
+// TestWebhooks_slowReceiverDoesNotBlockOthers holds one receiver's response
+// until another subscription has been delivered to.
+func TestWebhooks_slowReceiverDoesNotBlockOthers(t *testing.T) {
+	setupWebhookDB(t)
+	defer config.CloseDB()
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast := httptest.NewServer(&receiver{})
+	defer fast.Close()
+
+	slowSub := models.WebhookSubscription{URL: slow.URL, Secret: "k", Active: true}
+	require.NoError(t, CreateWebhook(ctx, &slowSub))
+	fastSub := models.WebhookSubscription{URL: fast.URL, Secret: "k", Active: true}
+	require.NoError(t, CreateWebhook(ctx, &fastSub))
+
+	stop := StartWebhookDispatcher(fastWebhookOptions())
+	defer stop()
+	require.NoError(t, CreateMenu(ctx, &models.Menu{Title: "A"}))
+
+	dels, err := ListWebhookDeliveries(ctx, fastSub.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, dels, 1)
+	waitForDelivery(t, dels[0].ID, models.DeliverySucceeded)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/galpt/sotekre/backend/config"
+)
+
+// allowPrivateWebhookTargets turns off the address checks below. It is set
+// from WEBHOOK_ALLOW_PRIVATE_TARGETS=true for local development, where
+// receivers run on localhost; tests use SetAllowPrivateWebhookTargets.
+var allowPrivateWebhookTargets atomic.Bool
+
+func init() {
+	allowPrivateWebhookTargets.Store(config.EnvOr("WEBHOOK_ALLOW_PRIVATE_TARGETS", "") == "true")
+}
+
+// SetAllowPrivateWebhookTargets allows (or forbids) webhook URLs that point
+// at loopback, private or link-local addresses and returns the old setting.
+func SetAllowPrivateWebhookTargets(allow bool) (old bool) {
+	return allowPrivateWebhookTargets.Swap(allow)
+}
+
+// ErrWebhookTargetForbidden is returned when a webhook URL resolves to an
+// address the server must not call (loopback, private, link-local, ...).
+var ErrWebhookTargetForbidden = errors.New("webhook target address is not allowed")
+
+// nonPublicPrefixes are ranges netip does not classify as private or local
+// but that still reach internal infrastructure.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64 (embeds IPv4 addresses)
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, incl. broadcast
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4-translated
+}
+
+// forbiddenWebhookIP reports whether deliveries must not be sent to ip.
+func forbiddenWebhookIP(ip netip.Addr) bool {
+	if allowPrivateWebhookTargets.Load() {
+		return false
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return true // loopback, link-local, multicast, unspecified, RFC 1918, ULA
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWebhookHost rejects a webhook host that is, or resolves to, a
+// forbidden address. A name that does not resolve yet is accepted: the dialer
+// checks the address again on every delivery.
+func checkWebhookHost(ctx context.Context, host string) error {
+	if allowPrivateWebhookTargets.Load() {
+		return nil
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrWebhookTargetForbidden, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if forbiddenWebhookIP(ip) {
+			return fmt.Errorf("%w: %s", ErrWebhookTargetForbidden, host)
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, ip := range ips {
+		if forbiddenWebhookIP(ip) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrWebhookTargetForbidden, host, ip)
+		}
+	}
+	return nil
+}
+
+// webhookDialControl runs after name resolution, for every connection
+// (including redirects), so a DNS answer that changes after the subscription
+// was created cannot point deliveries at internal services.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if forbiddenWebhookIP(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookTargetForbidden, ip)
+	}
+	return nil
+}
+
+// newWebhookClient returns the delivery HTTP client: no proxy from the
+// environment (it would be dialled instead of the receiver) and a dialer that
+// refuses forbidden addresses.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second, Control: webhookDialControl}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
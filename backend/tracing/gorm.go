@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "otel:span"
+
+type statementAttrsKey struct{}
+
+// WithStatementAttributes returns a ctx whose GORM statement spans carry
+// attrs as well, e.g. the row a per-row UPDATE targets: the recorded SQL has
+// placeholders, so identical statements are otherwise indistinguishable.
+func WithStatementAttributes(ctx context.Context, attrs ...attribute.KeyValue) context.Context {
+	prev, _ := ctx.Value(statementAttrsKey{}).([]attribute.KeyValue)
+	return context.WithValue(ctx, statementAttrsKey{}, append(append([]attribute.KeyValue(nil), prev...), attrs...))
+}
+
+// gormPlugin opens a span before every GORM operation and closes it with the
+// final SQL, table and affected rows. Queries only join a trace when they run
+// with DB.WithContext(ctx).
+type gormPlugin struct{}
+
+// GormPlugin returns the plugin; install it with db.Use(tracing.GormPlugin()).
+func GormPlugin() gorm.Plugin { return gormPlugin{} }
+
+func (gormPlugin) Name() string { return "otel-tracing" }
+
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		op     string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		op := h.op
+		if err := h.before("otel:before_"+op, func(tx *gorm.DB) { startGormSpan(tx, op) }); err != nil {
+			return err
+		}
+		if err := h.after("otel:after_"+op, endGormSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startGormSpan(tx *gorm.DB, op string) {
+	ctx := tx.Statement.Context
+	if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return // not part of a trace: don't start root spans for every query
+	}
+	attrs, _ := ctx.Value(statementAttrsKey{}).([]attribute.KeyValue)
+	ctx, span := Tracer().Start(ctx, "gorm."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	tx.Statement.Context = ctx
+	tx.InstanceSet(gormSpanKey, span)
+}
+
+func endGormSpan(tx *gorm.DB) {
+	v, ok := tx.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	stmt := tx.Statement
+	attrs := []attribute.KeyValue{
+		semconv.DBQueryText(stmt.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.RowsAffected),
+	}
+	if stmt.Table != "" {
+		attrs = append(attrs, semconv.DBCollectionName(stmt.Table))
+		// "UPDATE menus" etc. makes slow statements easy to spot in a trace
+		span.SetName(strings.ToUpper(firstWord(stmt.SQL.String())) + " " + stmt.Table)
+	}
+	if tx.Dialector != nil {
+		attrs = append(attrs, semconv.DBSystemKey.String(tx.Dialector.Name()))
+	}
+	span.SetAttributes(attrs...)
+	if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+func firstWord(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if i := strings.IndexAny(sql, " \n\t"); i > 0 {
+		return sql[:i]
+	}
+	return sql
+}
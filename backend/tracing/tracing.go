@@ -0,0 +1,92 @@
+// Package tracing wires OpenTelemetry: the tracer provider and exporter,
+// W3C trace context propagation, span helpers for services and a GORM plugin
+// that records one span per SQL statement.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the default service.name resource attribute
+// (OTEL_SERVICE_NAME overrides it).
+const ServiceName = "sotekre-backend"
+
+const instrumentationName = "github.com/galpt/sotekre/backend"
+
+// Init configures the global tracer provider from OTEL_TRACES_EXPORTER:
+//   - "otlp": OTLP over HTTP, configured by the standard OTEL_EXPORTER_OTLP_*
+//     variables (endpoint defaults to localhost:4318);
+//   - "stdout": pretty-printed spans on stdout, for local use;
+//   - "none" or unset: no export (spans are not recorded).
+//
+// W3C traceparent/baggage propagation is installed in every case. The
+// returned shutdown flushes pending spans.
+func Init(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	switch name := strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER")); name {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q (want otlp, stdout or none)", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME / OTEL_RESOURCE_ATTRIBUTES win over the default name
+	if envRes, rerr := resource.New(ctx, resource.WithFromEnv()); rerr == nil {
+		if merged, merr := resource.Merge(res, envRes); merr == nil {
+			res = merged
+		}
+	}
+
+	// the sampler honours OTEL_TRACES_SAMPLER(_ARG); default is parentbased_always_on
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the application tracer from the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start opens a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End records err (if any) on span and ends it. Use with a named error
+// return: defer func() { tracing.End(span, err) }().
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type widget struct {
+	ID    uint
+	Title string
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func TestInit_exporterSelection(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "")
+	shutdown, err := Init(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	_, err = Init(context.Background())
+	require.Error(t, err)
+
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+	t.Setenv("OTEL_TRACES_EXPORTER", "stdout")
+	shutdown, err = Init(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+}
+
+func TestGormPlugin_spanPerStatement(t *testing.T) {
+	rec := recordSpans(t)
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:tracing_%d?mode=memory&cache=shared", time.Now().UnixNano())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&widget{}))
+	require.NoError(t, db.Use(GormPlugin()))
+
+	// untraced queries create no spans
+	require.NoError(t, db.Create(&widget{Title: "a"}).Error)
+	require.Empty(t, rec.Ended())
+
+	ctx, parent := Start(context.Background(), "services.Test")
+	require.NoError(t, db.WithContext(ctx).Model(&widget{}).Where("id = ?", 1).Update("title", "b").Error)
+	var w widget
+	require.Error(t, db.WithContext(ctx).First(&w, 99).Error)
+	End(parent, nil)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 3)
+	update, query := spans[0], spans[1]
+	require.Equal(t, "UPDATE widgets", update.Name())
+	require.Equal(t, parent.SpanContext().SpanID(), update.Parent().SpanID())
+	attrs := map[string]string{}
+	for _, kv := range update.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	require.Contains(t, attrs["db.query.text"], "UPDATE `widgets` SET `title`=")
+	require.Equal(t, "1", attrs["db.rows_affected"])
+	require.Equal(t, "SELECT widgets", query.Name())
+	require.Empty(t, query.Events(), "record not found is not an error")
+}
+
+func TestGormPlugin_statementAttributes(t *testing.T) {
+	rec := recordSpans(t)
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:tracing_%d?mode=memory&cache=shared", time.Now().UnixNano())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&widget{}))
+	require.NoError(t, db.Use(GormPlugin()))
+
+	ctx, parent := Start(context.Background(), "services.Test")
+	for _, id := range []int64{1, 2} {
+		rowCtx := WithStatementAttributes(ctx, attribute.Int64("widget.id", id))
+		require.NoError(t, db.WithContext(rowCtx).Model(&widget{}).Where("id = ?", id).Update("title", "b").Error)
+	}
+	End(parent, nil)
+
+	spans := rec.Ended()
+	require.Len(t, spans, 3)
+	for i, want := range []string{"1", "2"} {
+		require.Equal(t, "UPDATE widgets", spans[i].Name())
+		attrs := map[string]string{}
+		for _, kv := range spans[i].Attributes() {
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+		require.Equal(t, want, attrs["widget.id"], "the row tells identical statements apart")
+	}
+}
@@ -1,9 +1,9 @@
-import React, { useState, useEffect } from 'react'
+import React, { useState, useEffect, useRef } from 'react'
 import Sidebar from '../components/Sidebar'
 import MenuTree from '../components/MenuTree'
 import DetailsPanel from '../components/DetailsPanel'
 import Logo from '../components/Logo'
-import { menuService, MenuNode as APIMenuNode } from '../services/menuService'
+import { menuService, MenuNode as APIMenuNode, MenuMutation, MenuChangeEvent } from '../services/menuService'
 
 type MenuItem = {
     id: string
@@ -11,6 +11,7 @@ type MenuItem = {
     depth: number
     parentData?: string
     url?: string
+    order?: number
     children?: MenuItem[]
 }
 
@@ -20,6 +21,7 @@ const convertAPIToUI = (node: APIMenuNode, depth: number = 1, parentTitle?: stri
         id: node.id.toString(),
         name: node.title,
         url: node.url,
+        order: node.order,
         depth,
         parentData: parentTitle,
         children: node.children?.map(child => convertAPIToUI(child, depth + 1, node.title)),
@@ -38,6 +40,72 @@ const findParentTitle = (nodes: APIMenuNode[], parentId: number): string | undef
     return undefined
 }
 
+// Applies the result of an update, move or reorder to the local tree so it
+// does not have to be refetched: the item takes its new title, url and parent,
+// and it and the renumbered siblings their new order. Returns null when the
+// item or its new parent is not in the tree (then reload instead).
+const applyMutation = (tree: MenuItem[], { item, siblings }: MenuMutation): MenuItem[] | null => {
+    const id = item.id.toString()
+    const parentId = item.parent_id != null ? item.parent_id.toString() : null
+    const orders = new Map<string, number>(siblings.map(s => [s.id.toString(), s.order]))
+    orders.set(id, item.order)
+
+    let moved: MenuItem | undefined
+    const detach = (nodes: MenuItem[]): MenuItem[] =>
+        nodes
+            .filter(n => {
+                if (n.id !== id) return true
+                moved = n
+                return false
+            })
+            .map(n => (n.children ? { ...n, children: detach(n.children) } : n))
+    const rest = detach(tree)
+    if (!moved) return null
+
+    const updated: MenuItem = { ...moved, name: item.title, url: item.url }
+    let attached = parentId === null
+    const attach = (nodes: MenuItem[]): MenuItem[] =>
+        nodes.map(n => {
+            if (n.id === parentId) {
+                attached = true
+                return { ...n, children: [...(n.children || []), updated] }
+            }
+            return n.children ? { ...n, children: attach(n.children) } : n
+        })
+    const placed = parentId === null ? [...rest, updated] : attach(rest)
+    if (!attached) return null
+
+    // renumber, sort each level by order and recompute depth / parent title
+    const relink = (nodes: MenuItem[], depth: number, parentTitle?: string): MenuItem[] =>
+        nodes
+            .map(n => ({ ...n, order: orders.get(n.id) ?? n.order }))
+            .sort((a, b) => (a.order ?? 0) - (b.order ?? 0))
+            .map(n => ({
+                ...n,
+                depth,
+                parentData: parentTitle,
+                children: n.children && relink(n.children, depth + 1, n.name),
+            }))
+    return relink(placed, 1)
+}
+
+// How long a locally applied mutation waits for its own change events
+const OWN_CHANGE_TTL_MS = 5000
+
+// Rows written by a mutation this client already applied locally; its change
+// events consume them instead of triggering a refetch
+type OwnChange = { ids: Set<string>; expires: number }
+
+// Consumes the ids of ev from the own change that wrote all of them. Returns
+// false when ev is a change made elsewhere.
+const consumeOwnChange = (own: OwnChange[], ev: MenuChangeEvent): boolean => {
+    const ids = ev.ids.map(id => id.toString())
+    const match = own.find(c => ids.every(id => c.ids.has(id)))
+    if (!match) return false
+    ids.forEach(id => match.ids.delete(id))
+    return true
+}
+
 export default function Home() {
     const [menuData, setMenuData] = useState<MenuItem[]>([])
     const [loading, setLoading] = useState(true)
@@ -58,9 +126,49 @@ export default function Home() {
         loadMenus()
     }, [])
 
-    const loadMenus = async () => {
+    const ownChanges = useRef<OwnChange[]>([])
+
+    // Keep in sync with edits from other open UIs (coalesce bursts of events).
+    // Events of this client's own updates and moves were already applied by
+    // applyResult; the delay also lets their HTTP response arrive first.
+    useEffect(() => {
+        let timer: ReturnType<typeof setTimeout> | undefined
+        let events: MenuChangeEvent[] = []
+        let reset = false
+        const flush = () => {
+            const now = Date.now()
+            ownChanges.current = ownChanges.current.filter(c => c.expires > now && c.ids.size > 0)
+            let foreign = reset
+            for (const ev of events) {
+                if (!consumeOwnChange(ownChanges.current, ev)) foreign = true
+            }
+            events = []
+            reset = false
+            if (foreign) loadMenus(false)
+        }
+        const schedule = () => {
+            clearTimeout(timer)
+            timer = setTimeout(flush, 200)
+        }
+        const unsubscribe = menuService.subscribeToChanges(
+            ev => {
+                events.push(ev)
+                schedule()
+            },
+            () => {
+                reset = true
+                schedule()
+            },
+        )
+        return () => {
+            clearTimeout(timer)
+            unsubscribe()
+        }
+    }, [])
+
+    const loadMenus = async (showSpinner: boolean = true) => {
         try {
-            setLoading(true)
+            if (showSpinner) setLoading(true)
             setError(null)
             const data = await menuService.getMenus()
             const converted = data.map(node => convertAPIToUI(node))
@@ -73,6 +181,19 @@ export default function Home() {
         }
     }
 
+    // Patches the tree with a mutation result, falling back to a reload when
+    // the local tree is out of date
+    const applyResult = (result: MenuMutation) => {
+        const next = applyMutation(menuData, result)
+        if (next) {
+            const ids = new Set([result.item.id, ...result.siblings.map(s => s.id)].map(id => id.toString()))
+            ownChanges.current.push({ ids, expires: Date.now() + OWN_CHANGE_TTL_MS })
+            setMenuData(next)
+        } else {
+            loadMenus(false)
+        }
+    }
+
     const handleExpandAll = () => {
         setExpandedAll(true)
     }
@@ -84,16 +205,14 @@ export default function Home() {
     const handleSave = async (item: MenuItem) => {
         try {
             console.log('Saving item:', item)
-            // Convert empty string url to undefined to properly handle NULL in database
-            const updateData: { title: string; url?: string } = {
+            // An empty url is sent as null, which clears it (merge patch)
+            const updateData = {
                 title: item.name,
-            }
-            if (item.url && item.url.trim()) {
-                updateData.url = item.url.trim()
+                url: item.url && item.url.trim() ? item.url.trim() : null,
             }
 
-            await menuService.updateMenu(parseInt(item.id), updateData)
-            await loadMenus() // Reload after save
+            const result = await menuService.updateMenu(parseInt(item.id), updateData)
+            applyResult(result)
         } catch (err: any) {
             console.error('Failed to save:', err)
             alert('Failed to save: ' + err.message)
@@ -119,7 +238,7 @@ export default function Home() {
             console.log(`[DELETE] Reload complete`)
         } catch (err: any) {
             console.error('[DELETE] Failed to delete:', err)
-            alert('Failed to delete: ' + (err.response?.data?.error || err.message))
+            alert('Failed to delete: ' + (err.response?.data?.detail || err.message))
         }
     }
 
@@ -129,13 +248,12 @@ export default function Home() {
             const numericId = parseInt(itemId)
             const numericParentId = newParentId ? parseInt(newParentId) : null
 
-            await menuService.moveMenu(numericId, numericParentId, newOrder)
-            console.log(`[MOVE] Move successful, reloading...`)
-            await loadMenus()
-            console.log(`[MOVE] Reload complete`)
+            const result = await menuService.moveMenu(numericId, numericParentId, newOrder)
+            console.log(`[MOVE] Move successful, ${result.siblings.length} siblings renumbered`)
+            applyResult(result)
         } catch (err: any) {
             console.error('[MOVE] Failed to move item:', err)
-            alert('Failed to move item: ' + (err.response?.data?.error || err.message))
+            alert('Failed to move item: ' + (err.response?.data?.detail || err.message))
             // Reload to revert UI to actual state
             await loadMenus()
         }
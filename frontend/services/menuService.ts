@@ -5,6 +5,7 @@ const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'
 export interface MenuNode {
     id: number
     title: string
+    key?: string
     url?: string
     parent_id?: number
     order: number
@@ -15,6 +16,43 @@ export interface MenuResponse {
     data: MenuNode[]
 }
 
+// New position of an item that a move or reorder renumbered
+export interface SiblingOrder {
+    id: number
+    parent_id: number | null
+    order: number
+}
+
+// Result of an update, move or reorder: the item as it is now (without
+// children) and the siblings in the old and new parent whose order changed
+export interface MenuMutation {
+    item: MenuNode
+    siblings: SiblingOrder[]
+}
+
+interface MenuMutationResponse {
+    data: MenuNode
+    meta: { siblings: SiblingOrder[] }
+}
+
+// Result of resolving a page path: the best-matching item and its ancestors
+// from the root down (nodes without children)
+export interface MenuMatch {
+    item: MenuNode
+    ancestors: MenuNode[]
+    exact: boolean
+    params?: Record<string, string>
+}
+
+export type MenuEventType = 'created' | 'updated' | 'moved' | 'reordered' | 'deleted'
+
+export interface MenuChangeEvent {
+    revision: number
+    type: MenuEventType
+    ids: number[]
+    at: string
+}
+
 export interface CreateMenuInput {
     title: string
     url?: string
@@ -22,13 +60,21 @@ export interface CreateMenuInput {
     order?: number
 }
 
+// JSON Merge Patch: omitted fields are kept, null clears url / moves to root
 export interface UpdateMenuInput {
     title?: string
-    url?: string
-    parent_id?: number
+    url?: string | null
+    parent_id?: number | null
     order?: number
 }
 
+const MENUS = '/api/v1/menus'
+
+const toMutation = (body: MenuMutationResponse): MenuMutation => ({
+    item: body.data,
+    siblings: body.meta?.siblings || [],
+})
+
 const api = axios.create({
     baseURL: API_BASE_URL,
     headers: {
@@ -36,42 +82,89 @@ const api = axios.create({
     },
 })
 
+// Sends a mutating request with an Idempotency-Key and retries it once with
+// the same key when no response arrived (network error or timeout), so the
+// server replays the first result instead of applying the change twice.
+async function idempotent<T>(send: (headers: Record<string, string>) => Promise<T>): Promise<T> {
+    const headers = { 'Idempotency-Key': crypto.randomUUID() }
+    try {
+        return await send(headers)
+    } catch (err: any) {
+        if (err.response) throw err
+        return send(headers)
+    }
+}
+
 export const menuService = {
     // Get all menus
     async getMenus(): Promise<MenuNode[]> {
         // Add cache-busting parameter to ensure fresh data
         const timestamp = new Date().getTime()
-        const response = await api.get<MenuResponse>(`/api/menus?_t=${timestamp}`)
+        const response = await api.get<MenuResponse>(`${MENUS}?_t=${timestamp}`)
         return response.data.data || []
     },
 
+    // Find the item for a page path (null when nothing matches)
+    async resolvePath(path: string): Promise<MenuMatch | null> {
+        try {
+            const response = await api.get<{ data: MenuMatch }>(`${MENUS}/resolve`, { params: { path } })
+            return response.data.data
+        } catch (err: any) {
+            if (err.response?.status === 404) return null
+            throw err
+        }
+    },
+
     // Create menu
     async createMenu(input: CreateMenuInput): Promise<MenuNode> {
-        const response = await api.post<MenuNode>('/api/menus', input)
-        return response.data
+        const response = await idempotent((headers) => api.post<{ data: MenuNode }>(MENUS, input, { headers }))
+        return response.data.data
     },
 
     // Update menu
-    async updateMenu(id: number, input: UpdateMenuInput): Promise<MenuNode> {
-        const response = await api.put<MenuNode>(`/api/menus/${id}`, input)
-        return response.data
+    async updateMenu(id: number, input: UpdateMenuInput): Promise<MenuMutation> {
+        const response = await api.patch<MenuMutationResponse>(`${MENUS}/${id}`, input, {
+            headers: { 'Content-Type': 'application/merge-patch+json' },
+        })
+        return toMutation(response.data)
     },
 
     // Delete menu
     async deleteMenu(id: number): Promise<void> {
-        await api.delete(`/api/menus/${id}`)
+        await api.delete(`${MENUS}/${id}`)
     },
 
     // Reorder menu
-    async reorderMenu(id: number, newOrder: number): Promise<void> {
-        await api.patch(`/api/menus/${id}/reorder`, { new_order: newOrder })
+    async reorderMenu(id: number, newOrder: number): Promise<MenuMutation> {
+        const response = await api.patch<MenuMutationResponse>(`${MENUS}/${id}/reorder`, { new_order: newOrder })
+        return toMutation(response.data)
     },
 
     // Move menu
-    async moveMenu(id: number, newParentId: number | null, newOrder?: number): Promise<void> {
-        await api.patch(`/api/menus/${id}/move`, {
-            new_parent_id: newParentId,
-            new_order: newOrder,
+    async moveMenu(id: number, newParentId: number | null, newOrder?: number): Promise<MenuMutation> {
+        const body = { new_parent_id: newParentId, new_order: newOrder }
+        const response = await idempotent((headers) => api.patch<MenuMutationResponse>(`${MENUS}/${id}/move`, body, { headers }))
+        return toMutation(response.data)
+    },
+
+    // Set the complete child order of a parent (null = root items) in one call
+    async setChildOrder(parentId: number | null, ids: number[]): Promise<void> {
+        const parent = parentId === null ? 'root' : parentId
+        await api.put(`${MENUS}/${parent}/children/order`, { ids })
+    },
+
+    // Subscribe to server-sent change events. `onReset` fires when the server
+    // could not resume from the browser's Last-Event-ID (full refetch needed).
+    // Returns an unsubscribe function.
+    subscribeToChanges(onChange: (event: MenuChangeEvent) => void, onReset?: () => void): () => void {
+        const source = new EventSource(`${API_BASE_URL}${MENUS}/events`)
+        const types: MenuEventType[] = ['created', 'updated', 'moved', 'reordered', 'deleted']
+        types.forEach(type => {
+            source.addEventListener(type, (e) => {
+                onChange(JSON.parse((e as MessageEvent).data) as MenuChangeEvent)
+            })
         })
+        source.addEventListener('reset', () => onReset?.())
+        return () => source.close()
     },
 }